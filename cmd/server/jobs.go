@@ -0,0 +1,105 @@
+// cmd/server/jobs.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"chi-mongo-backend/internal/config"
+	"chi-mongo-backend/internal/scheduler"
+	"chi-mongo-backend/internal/services"
+)
+
+// staleReservationAge is how long a promo redemption may stay in flight before its slots
+// are released; it is far longer than any request
+const staleReservationAge = 15 * time.Minute
+
+// registerJobs adds the server's periodic background jobs to the scheduler
+func registerJobs(
+	s *scheduler.Scheduler,
+	cfg config.SchedulerConfig,
+	tokenService services.CreditTokenService,
+	creditsService services.CreditsService,
+	apiKeyService services.APIKeyService,
+	usageService services.UsageService,
+	resultStoreService services.ResultStoreService,
+) error {
+	jobs := []scheduler.Job{
+		{
+			Name:    "expired-token-cleanup",
+			Spec:    cfg.TokenCleanupSpec,
+			Timeout: 5 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := tokenService.CleanupExpiredTokens(ctx)
+				return fmt.Sprintf("expired %d tokens", count), err
+			},
+		},
+		{
+			Name:    "api-key-expiry-notices",
+			Spec:    cfg.APIKeyExpirySpec,
+			Timeout: 5 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				window := time.Duration(cfg.APIKeyExpiryNoticeDays) * 24 * time.Hour
+				count, err := apiKeyService.NotifyExpiringKeys(ctx, window)
+				return fmt.Sprintf("notified %d API key owners", count), err
+			},
+		},
+		{
+			Name:    "usage-rollups",
+			Spec:    cfg.UsageRollupSpec,
+			Timeout: 10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				from, to, err := usageService.CompactRollups(ctx)
+				if from.IsZero() {
+					return "no usage to compact", err
+				}
+				return fmt.Sprintf("compacted usage from %s to %s", from.Format(time.RFC3339), to.Format(time.RFC3339)), err
+			},
+		},
+		{
+			Name:    "user-last-active-sync",
+			Spec:    cfg.LastActiveSyncSpec,
+			Timeout: 10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				if err := usageService.SyncUserActivity(ctx); err != nil {
+					return "", err
+				}
+				if err := creditsService.SyncUserSearchFields(ctx); err != nil {
+					return "", err
+				}
+				return "synced last activity, call totals and balances onto users", nil
+			},
+		},
+		{
+			Name:    "stale-reservation-release",
+			Spec:    cfg.StaleReservationSpec,
+			Timeout: 5 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := tokenService.ReleaseStaleRedemptions(ctx, staleReservationAge)
+				return fmt.Sprintf("released %d stale promo redemptions", count), err
+			},
+		},
+		{
+			Name:    "expired-result-purge",
+			Spec:    cfg.ResultPurgeSpec,
+			Timeout: 10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := resultStoreService.PurgeExpired(ctx)
+				return fmt.Sprintf("purged %d expired results", count), err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if job.Spec == "off" {
+			log.Printf("⏸️  Job %s disabled", job.Name)
+			continue
+		}
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,9 +14,13 @@ import (
 	"chi-mongo-backend/internal/config"
 	"chi-mongo-backend/internal/database"
 	"chi-mongo-backend/internal/handlers"
+	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/repository"
 	"chi-mongo-backend/internal/routes"
+	"chi-mongo-backend/internal/scheduler"
 	"chi-mongo-backend/internal/services"
+	"chi-mongo-backend/pkg/blobstore"
+	"chi-mongo-backend/pkg/imageprep"
 )
 
 func main() {
@@ -26,6 +30,11 @@ func main() {
 		log.Fatalf("❌ Failed to load configuration: %v", err)
 	}
 
+	// Configure the credit token code format
+	if err := models.SetTokenCodeFormat(cfg.Tokens.CodeFormat); err != nil {
+		log.Fatalf("❌ Invalid TOKEN_CODE_FORMAT: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.NewMongoDB(cfg)
 	if err != nil {
@@ -43,18 +52,71 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.GetCollection("users"))
-	creditsRepo := repository.NewCreditsRepository(db.GetCollection("credits"))
+	creditsRepo := repository.NewCreditsRepository(db.GetCollection("credits"), db.GetCollection("users"))
 	tokenRepo := repository.NewTokenRepository(db.GetCollection("tokens"))
 	apiKeyRepo := repository.NewAPIKeyRepository(db.GetCollection("api_keys"))
 	activityRepo := repository.NewActivityRepository(db.GetCollection("activities"))
 	usageRepo := repository.NewUsageRepository(db.GetCollection("usage")) // Add usage repository
+	creditAlertRepo := repository.NewCreditAlertRepository(db.GetCollection("credit_alerts"))
+	campaignRepo := repository.NewCampaignRepository(db.GetCollection("campaigns"))
+	redemptionRepo := repository.NewRedemptionRepository(db.GetCollection("token_redemptions"))
+	tokenAuditRepo := repository.NewTokenAuditRepository(db.GetCollection("token_audit"))
+	usageRollupRepo := repository.NewUsageRollupRepository(db.GetCollection("usage"), db.GetCollection("usage_hourly"), db.GetCollection("usage_daily"))
+	jobLockRepo := repository.NewJobLockRepository(db.GetCollection("job_locks"))
+	jobRunRepo := repository.NewJobRunRepository(db.GetCollection("job_runs"))
+	adminAuditRepo := repository.NewAdminAuditRepository(db.GetCollection("admin_audit"))
+	adminRepo := repository.NewAdminRepository(db.GetCollection("admins"))
+	idempotencyRepo := repository.NewIdempotencyRepository(db.GetCollection("idempotency_keys"))
+	resultRepo := repository.NewProcessingResultRepository(db.GetCollection("processing_results"))
+	retainedResultRepo := repository.NewRetainedResultRepository(db.GetCollection("retained_results"))
+	resultRetentionRepo := repository.NewResultRetentionRepository(db.GetCollection("result_retention"))
+
+	// Initialize the result store; results are only kept for users who opt in
+	var resultBlobStore blobstore.BlobStore
+	switch cfg.ResultStore.Backend {
+	case "local":
+		resultBlobStore, err = blobstore.NewLocalStore(cfg.ResultStore.LocalDir)
+	case "s3":
+		resultBlobStore, err = blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        cfg.ResultStore.S3Endpoint,
+			Region:          cfg.ResultStore.S3Region,
+			Bucket:          cfg.ResultStore.S3Bucket,
+			AccessKeyID:     cfg.ResultStore.S3AccessKeyID,
+			SecretAccessKey: cfg.ResultStore.S3SecretKey,
+			PathStyle:       cfg.ResultStore.S3PathStyle,
+		})
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize result store: %v", err)
+	}
 
 	// Initialize services
-	userService := services.NewUserService(userRepo, creditsRepo, activityRepo)
-	creditsService := services.NewCreditsService(creditsRepo, userRepo)
-	tokenService := services.NewCreditTokenService(tokenRepo, creditsRepo)
-	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
-	usageService := services.NewUsageService(usageRepo) // Add usage service
+	activityEmitter := services.NewActivityEmitter(activityRepo)
+	userService := services.NewUserService(userRepo, creditsRepo, activityRepo, activityEmitter)
+	creditsService := services.NewCreditsService(creditsRepo, userRepo, usageRepo, activityEmitter)
+	adminDirectory := services.NewAdminDirectoryService(adminRepo)
+	tokenService := services.NewCreditTokenService(tokenRepo, creditsRepo, redemptionRepo, userRepo, tokenAuditRepo, adminDirectory, activityEmitter)
+	campaignService := services.NewCampaignService(campaignRepo, tokenRepo, tokenAuditRepo)
+	notificationService := services.NewNotificationService()
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, notificationService, activityEmitter)
+	usageService := services.NewUsageService(usageRepo, usageRollupRepo, userRepo) // Add usage service
+	paymentService := services.NewPaymentService()
+	creditAlertService := services.NewCreditAlertService(creditAlertRepo, creditsRepo, notificationService, paymentService, activityEmitter)
+	adminAuditService := services.NewAdminAuditService(adminAuditRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, time.Duration(cfg.Idempotency.TTLHours)*time.Hour)
+	resultService := services.NewProcessingResultService(resultRepo, resultRetentionRepo, time.Duration(cfg.Results.DedupWindowHours)*time.Hour)
+	resultStoreService := services.NewResultStoreService(resultBlobStore, retainedResultRepo, resultRetentionRepo, usageRepo, cfg.ResultStore.MaxRetentionDays)
+	imagePreprocessor := services.NewImagePreprocessor(imageprep.Limits{
+		MaxBytes:     cfg.Images.MaxBytes,
+		MaxDimension: cfg.Images.MaxDimension,
+		MinDimension: cfg.Images.MinDimension,
+		MaxPixels:    cfg.Images.MaxPixels,
+		DownscaleTo:  cfg.Images.DownscaleMaxSide,
+		JPEGQuality:  cfg.Images.JPEGQuality,
+	}, cfg.Images.PDFMaxPages)
+
+	// Low-balance alerts run whenever the credits repository changes a balance
+	creditsRepo.SetBalanceListener(creditAlertService)
 	
 	// Initialize API services
 	qrAPIService := services.NewQRMaskingAPIService()
@@ -88,24 +150,45 @@ func main() {
 
 	log.Println("✅ All services initialized successfully")
 
+	// Users stored before the admin search fields existed get them before the first search
+	backfillCtx, cancelBackfill := context.WithTimeout(context.Background(), 2*time.Minute)
+	if err := userRepo.BackfillSearchFields(backfillCtx); err != nil {
+		log.Printf("❌ Failed to backfill user search fields: %v", err)
+	}
+	cancelBackfill()
+
+	// Start background jobs; every replica runs the scheduler and a Mongo lock picks one per run
+	jobScheduler := scheduler.New(jobLockRepo, jobRunRepo)
+	if cfg.Scheduler.Enabled {
+		if err := registerJobs(jobScheduler, cfg.Scheduler, tokenService, creditsService, apiKeyService, usageService, resultStoreService); err != nil {
+			log.Fatalf("❌ Failed to register background jobs: %v", err)
+		}
+		jobScheduler.Start()
+		log.Println("✅ Background job scheduler started")
+	}
+
 	// Initialize handlers (only SignatureVerification has usage tracking implemented)
 	handlers := &routes.Handlers{
 		Health:                handlers.NewHealthHandler(),
-		User:                  handlers.NewUserHandler(userService),
-		Credits:               handlers.NewCreditsHandler(creditsService, userService),
-		Token:                 handlers.NewTokenHandler(tokenService, creditsService, userService),
+		User:                  handlers.NewUserHandler(userService, adminAuditService),
+		Credits:               handlers.NewCreditsHandler(creditsService, userService, adminAuditService),
+		CreditAlert:           handlers.NewCreditAlertHandler(creditAlertService, userService),
+		Token:                 handlers.NewTokenHandler(tokenService, creditsService, userService, adminAuditService),
+		Campaign:              handlers.NewCampaignHandler(campaignService, adminAuditService),
 		APIKey:                handlers.NewAPIKeyHandler(apiKeyService, userService),
+		AdminAudit:            handlers.NewAdminAuditHandler(adminAuditService),
+		Result:                handlers.NewResultHandler(resultService, resultStoreService, userService),
 		// These handlers don't have usage tracking yet - using original constructors
-		QRMasking:             handlers.NewQRMaskingHandler(creditsService, userService, qrAPIService, usageService),
-		QRExtraction:          handlers.NewQRExtractionHandler(creditsService, userService, qrExtractionAPIService, usageService),
-		IDCropping:            handlers.NewIDCroppingHandler(creditsService, userService, idCroppingAPIService, usageService),
+		QRMasking:             handlers.NewQRMaskingHandler(creditsService, userService, qrAPIService, usageService, resultService, resultStoreService, imagePreprocessor),
+		QRExtraction:          handlers.NewQRExtractionHandler(creditsService, userService, qrExtractionAPIService, usageService, resultService, imagePreprocessor),
+		IDCropping:            handlers.NewIDCroppingHandler(creditsService, userService, idCroppingAPIService, usageService, resultService, resultStoreService, imagePreprocessor),
 		// SignatureVerification has usage tracking implemented
-		SignatureVerification: handlers.NewSignatureVerificationHandler(creditsService, userService, signatureAPIService, usageService),
+		SignatureVerification: handlers.NewSignatureVerificationHandler(creditsService, userService, signatureAPIService, usageService, resultService, imagePreprocessor),
 		// These handlers don't have usage tracking yet - using original constructors
-		FaceDetect:            handlers.NewFaceDetectionHandler(creditsService, userService, faceDetectionAPIService, usageService),
-		FaceVerify:            handlers.NewFaceVerificationHandler(creditsService, userService, faceVerificationAPIService, usageService),
+		FaceDetect:            handlers.NewFaceDetectionHandler(creditsService, userService, faceDetectionAPIService, usageService, resultService, resultStoreService, imagePreprocessor),
+		FaceVerify:            handlers.NewFaceVerificationHandler(creditsService, userService, faceVerificationAPIService, usageService, resultService, imagePreprocessor),
 		Debug:                 handlers.NewDebugHandler(),
-		Usage:                 handlers.NewUsageHandler(usageService), // Usage handler for admin endpoints
+		Usage:                 handlers.NewUsageHandler(usageService, userService, adminAuditService), // Usage handler for admin endpoints
 	}
 
 	// Verify handlers are initialized
@@ -127,9 +210,11 @@ func main() {
 	services := &routes.Services{
         APIKeyService: apiKeyService,
 		UsageService:  usageService, // Add usage service to routes
+		IdempotencyService: idempotencyService,
+		AdminDirectory:     adminDirectory,
     }
 	// Setup routes
-	router := routes.SetupRoutes(handlers, services)
+	router := routes.SetupRoutes(handlers, services, cfg.BodyLimits)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -148,12 +233,23 @@ func main() {
 		log.Println("  GET  /health - Health check")
 		log.Println("  GET  /debug/token - Debug token data (NO AUTH REQUIRED)")
 		log.Println("  POST /api/v1/register - Register new user")
-		log.Println("  POST /api/v1/credits/deduct - Deduct credits from user")
+		log.Println("  POST /api/v1/credits/deduct - Charge credits, self or admin, with optional idempotency key (requires Bearer token)")
 		log.Println("  POST /api/v1/credits/add - Add credits to user")
 		log.Println("  GET  /api/v1/credits/balance - Get user's credit balance (requires Bearer token)")
+		log.Println("  GET  /api/v1/credits/alerts - Get low-balance alert settings (requires Bearer token)")
+		log.Println("  PUT  /api/v1/credits/alerts - Update low-balance alert and auto top-up settings (requires Bearer token)")
 		log.Println("  POST /api/v1/tokens/generate - Generate credit tokens (requires Bearer token)")
 		log.Println("  POST /api/v1/tokens/redeem - Redeem credit tokens (requires Bearer token)")
 		log.Println("  GET  /api/v1/tokens/my-tokens - Get user's generated tokens (requires Bearer token)")
+		log.Println("  POST /api/v1/tokens/promo - Create a multi-use promo code (Admin only)")
+		log.Println("  GET  /api/v1/tokens/{tokenId}/redemptions - List token redemptions (Admin only)")
+		log.Println("  POST /api/v1/tokens/{tokenId}/revoke - Revoke a token with a reason (Admin only)")
+		log.Println("  POST /api/v1/tokens/{tokenId}/transfer - Transfer token ownership to another admin (Admin only)")
+		log.Println("  GET  /api/v1/tokens/{tokenId}/audit - Get token lifecycle audit trail (Admin only)")
+		log.Println("  POST /api/v1/tokens/batch - Generate a campaign batch of credit tokens (Admin only)")
+		log.Println("  GET  /api/v1/tokens/campaigns - List token campaigns (Admin only)")
+		log.Println("  GET  /api/v1/tokens/campaigns/{campaignId}/analytics - Get campaign redemption analytics (Admin only)")
+		log.Println("  GET  /api/v1/tokens/campaigns/{campaignId}/export - Export campaign token codes as CSV (Admin only)")
 		
 		// API Key endpoints
 		log.Println("  POST /api/v1/api-keys - Create new API key (requires Bearer token)")
@@ -162,12 +258,21 @@ func main() {
 		log.Println("  DELETE /api/v1/api-keys/{keyId} - Revoke API key (requires Bearer token)")
 		log.Println("  GET  /api/v1/api-keys/stats - Get API key statistics (requires Bearer token)")
 		
+		// Self-service usage endpoints
+		log.Println("  GET  /api/v1/usage/me - Get your usage totals per service")
+		log.Println("  GET  /api/v1/usage/me/history - Get your usage history")
+		log.Println("  GET  /api/v1/usage/me/timeseries - Get your bucketed usage time series")
 		// Usage tracking endpoints (Admin only)
 		log.Println("  GET  /api/v1/admin/usage/global - Get global usage statistics (Admin only)")
 		log.Println("  GET  /api/v1/admin/usage/users - Get per-user usage statistics (Admin only)")
 		log.Println("  GET  /api/v1/admin/usage/services - Get service-user usage statistics (Admin only)")
+		log.Println("  GET  /api/v1/admin/usage/timeseries - Get bucketed usage time series (Admin only)")
+		log.Println("  GET  /api/v1/admin/usage/performance - Get latency percentiles and error codes per service (Admin only)")
+		log.Println("  GET  /api/v1/admin/usage/export - Stream raw usage records as CSV or NDJSON (Admin only)")
 		log.Println("  GET  /api/v1/admin/usage/user/{userId}/history - Get user usage history (Admin only)")
 		log.Println("  GET  /api/v1/admin/usage/service/{serviceName}/history - Get service usage history (Admin only)")
+		log.Println("  GET  /api/v1/admin/audit - List the admin audit log (Admin only)")
+		log.Println("  GET  /api/v1/admin/audit/verify - Verify the admin audit hash chain (Admin only)")
 		
 		log.Println("  POST /api/v1/qr-masking - Process QR masking (requires Bearer token or API key) [NO USAGE TRACKING]")
 		log.Println("  POST /api/v1/qr-extraction - Process QR extraction (requires Bearer token or API key) [NO USAGE TRACKING]")
@@ -175,6 +280,12 @@ func main() {
 		log.Println("  POST /api/v1/signature-verification - Process signature verification (requires Bearer token or API key) [WITH USAGE TRACKING]")
 		log.Println("  POST /api/v1/face-detect - Process face detection (requires Bearer token or API key) [NO USAGE TRACKING]")
 		log.Println("  POST /api/v1/face-verification - Process face verification (requires Bearer token or API key) [NO USAGE TRACKING]")
+		log.Println("  Processing endpoints take JSON or multipart/form-data file uploads; Accept: image/* returns masked, cropped and face images as binary")
+		log.Println("  ID cropping, QR extraction and signature verification also take PDFs; pages are selected with \"pages\" and charged one by one")
+		log.Println("  GET  /api/v1/results/{service}/{req_id} - Get the stored result of a processing call (requires Bearer token or API key)")
+		log.Println("  GET  /api/v1/results/retained - Get a retained result by usage_id or service and req_id (requires Bearer token or API key)")
+		log.Println("  GET  /api/v1/results/retention - Get result retention settings (requires Bearer token)")
+		log.Println("  PUT  /api/v1/results/retention - Opt in to or out of result retention (requires Bearer token)")
 		log.Println("✅ CORS enabled for all origins")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -197,5 +308,9 @@ func main() {
 		log.Fatalf("❌ Server forced to shutdown: %v", err)
 	}
 
+	if err := jobScheduler.Stop(ctx); err != nil {
+		log.Printf("❌ Background jobs did not stop in time: %v", err)
+	}
+
 	log.Println("✅ Server exited")
 }
\ No newline at end of file
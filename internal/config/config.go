@@ -13,6 +13,13 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Auth     AuthConfig
+	Tokens    TokenConfig
+	Scheduler SchedulerConfig
+	Idempotency IdempotencyConfig
+	Results     ResultsConfig
+	ResultStore ResultStoreConfig
+	Images      ImageConfig
+	BodyLimits  BodyLimitConfig
 }
 
 type ServerConfig struct {
@@ -29,6 +36,70 @@ type AuthConfig struct {
 	KindeIssuerURL string
 }
 
+type TokenConfig struct {
+	// CodeFormat is a pattern like "XXXX-XXXX-XXXX", or "hex" for legacy 32-char tokens
+	CodeFormat string
+}
+
+// SchedulerConfig holds the cron specs of the background jobs; the spec "off" disables a job
+type SchedulerConfig struct {
+	Enabled              bool
+	TokenCleanupSpec     string
+	APIKeyExpirySpec     string
+	UsageRollupSpec      string
+	StaleReservationSpec string
+	LastActiveSyncSpec   string
+	ResultPurgeSpec      string
+	// APIKeyExpiryNoticeDays is how far ahead of expiry key owners are notified
+	APIKeyExpiryNoticeDays int
+}
+
+// IdempotencyConfig controls how long responses to Idempotency-Key requests are replayed
+type IdempotencyConfig struct {
+	TTLHours int
+}
+
+// ResultsConfig controls how long processing results are kept for req_id deduplication,
+// which users opt in to in their result retention settings; zero turns it off for everyone
+type ResultsConfig struct {
+	DedupWindowHours int
+}
+
+// ResultStoreConfig selects where results are kept for users who opt in to result retention.
+// Backend is "off", "local" (files under LocalDir) or "s3" (any S3-compatible service).
+type ResultStoreConfig struct {
+	Backend          string
+	LocalDir         string
+	MaxRetentionDays int
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKeyID    string
+	S3SecretKey      string
+	S3PathStyle      bool
+}
+
+// ImageConfig bounds the images accepted by the processing endpoints. DownscaleMaxSide
+// shrinks larger images before they are sent upstream; zero leaves them as they are.
+// PDFMaxPages caps how many pages of a PDF one request may process.
+type ImageConfig struct {
+	MaxBytes         int
+	MaxDimension     int
+	MinDimension     int
+	MaxPixels        int
+	DownscaleMaxSide int
+	JPEGQuality      int
+	PDFMaxPages      int
+}
+
+// BodyLimitConfig caps request bodies, in bytes. Image applies to the single-image
+// processing routes, MultiImage to those that take several images and Default to the rest.
+type BodyLimitConfig struct {
+	Default    int64
+	Image      int64
+	MultiImage int64
+}
+
 func Load() (*Config, error) {
 	// Load .env file if it exists (for local development)
 	_ = godotenv.Load()
@@ -45,6 +116,50 @@ func Load() (*Config, error) {
 		Auth: AuthConfig{
 			KindeIssuerURL: os.Getenv("KINDE_ISSUER_URL"),
 		},
+		Tokens: TokenConfig{
+			CodeFormat: getEnvOrDefault("TOKEN_CODE_FORMAT", "XXXX-XXXX-XXXX"),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                getEnvOrDefault("SCHEDULER_ENABLED", "true") != "false",
+			TokenCleanupSpec:       getEnvOrDefault("SCHEDULER_TOKEN_CLEANUP_SPEC", "@hourly"),
+			APIKeyExpirySpec:       getEnvOrDefault("SCHEDULER_API_KEY_EXPIRY_SPEC", "0 9 * * *"),
+			UsageRollupSpec:        getEnvOrDefault("SCHEDULER_USAGE_ROLLUP_SPEC", "*/15 * * * *"),
+			StaleReservationSpec:   getEnvOrDefault("SCHEDULER_STALE_RESERVATION_SPEC", "*/10 * * * *"),
+			LastActiveSyncSpec:     getEnvOrDefault("SCHEDULER_LAST_ACTIVE_SYNC_SPEC", "30 3 * * *"),
+			ResultPurgeSpec:        getEnvOrDefault("SCHEDULER_RESULT_PURGE_SPEC", "@hourly"),
+			APIKeyExpiryNoticeDays: getEnvAsInt("API_KEY_EXPIRY_NOTICE_DAYS", 7),
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours: getEnvAsInt("IDEMPOTENCY_TTL_HOURS", 24),
+		},
+		Results: ResultsConfig{
+			DedupWindowHours: getEnvAsInt("RESULT_DEDUP_WINDOW_HOURS", 24),
+		},
+		ResultStore: ResultStoreConfig{
+			Backend:          getEnvOrDefault("RESULT_STORE_BACKEND", "off"),
+			LocalDir:         getEnvOrDefault("RESULT_STORE_DIR", "./data/results"),
+			MaxRetentionDays: getEnvAsInt("RESULT_RETENTION_MAX_DAYS", 30),
+			S3Endpoint:       os.Getenv("RESULT_STORE_S3_ENDPOINT"),
+			S3Region:         getEnvOrDefault("RESULT_STORE_S3_REGION", "us-east-1"),
+			S3Bucket:         os.Getenv("RESULT_STORE_S3_BUCKET"),
+			S3AccessKeyID:    os.Getenv("RESULT_STORE_S3_ACCESS_KEY_ID"),
+			S3SecretKey:      os.Getenv("RESULT_STORE_S3_SECRET_ACCESS_KEY"),
+			S3PathStyle:      getEnvOrDefault("RESULT_STORE_S3_PATH_STYLE", "false") == "true",
+		},
+		Images: ImageConfig{
+			MaxBytes:         getEnvAsInt("IMAGE_MAX_BYTES", 10<<20),
+			MaxDimension:     getEnvAsInt("IMAGE_MAX_DIMENSION", 10000),
+			MinDimension:     getEnvAsInt("IMAGE_MIN_DIMENSION", 32),
+			MaxPixels:        getEnvAsInt("IMAGE_MAX_PIXELS", 40_000_000),
+			DownscaleMaxSide: getEnvAsInt("IMAGE_DOWNSCALE_MAX_SIDE", 0),
+			JPEGQuality:      getEnvAsInt("IMAGE_JPEG_QUALITY", 90),
+			PDFMaxPages:      getEnvAsInt("PDF_MAX_PAGES", 10),
+		},
+		BodyLimits: BodyLimitConfig{
+			Default:    int64(getEnvAsInt("BODY_LIMIT_DEFAULT", 1<<20)),
+			Image:      int64(getEnvAsInt("BODY_LIMIT_IMAGE", 16<<20)),
+			MultiImage: int64(getEnvAsInt("BODY_LIMIT_MULTI_IMAGE", 32<<20)),
+		},
 	}
 
 	if err := config.validate(); err != nil {
@@ -61,6 +176,23 @@ func (c *Config) validate() error {
 	if c.Auth.KindeIssuerURL == "" {
 		return fmt.Errorf("KINDE_ISSUER_URL is required")
 	}
+	switch c.ResultStore.Backend {
+	case "off", "local", "s3":
+	default:
+		return fmt.Errorf("RESULT_STORE_BACKEND must be off, local or s3")
+	}
+	if c.ResultStore.MaxRetentionDays <= 0 {
+		return fmt.Errorf("RESULT_RETENTION_MAX_DAYS must be positive")
+	}
+	if c.Images.JPEGQuality < 1 || c.Images.JPEGQuality > 100 {
+		return fmt.Errorf("IMAGE_JPEG_QUALITY must be between 1 and 100")
+	}
+	if c.Images.PDFMaxPages <= 0 {
+		return fmt.Errorf("PDF_MAX_PAGES must be positive")
+	}
+	if c.BodyLimits.Default <= 0 || c.BodyLimits.Image <= 0 || c.BodyLimits.MultiImage <= 0 {
+		return fmt.Errorf("BODY_LIMIT_DEFAULT, BODY_LIMIT_IMAGE and BODY_LIMIT_MULTI_IMAGE must be positive")
+	}
 	return nil
 }
 
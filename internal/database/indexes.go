@@ -4,6 +4,7 @@ package database
 import (
 	"context"
 	"log"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -25,6 +26,103 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return err
 	}
 
+	// Credit alerts collection indexes
+	creditAlertsCollection := m.GetCollection("credit_alerts")
+	if err := m.createCreditAlertsIndexes(ctx, creditAlertsCollection); err != nil {
+		return err
+	}
+
+	// Tokens collection indexes
+	tokensCollection := m.GetCollection("tokens")
+	if err := m.createTokensIndexes(ctx, tokensCollection); err != nil {
+		return err
+	}
+
+	// API keys collection indexes
+	apiKeysCollection := m.GetCollection("api_keys")
+	if err := m.createAPIKeysIndexes(ctx, apiKeysCollection); err != nil {
+		return err
+	}
+
+	// Activity log collection indexes
+	activitiesCollection := m.GetCollection("activities")
+	if err := m.createActivitiesIndexes(ctx, activitiesCollection); err != nil {
+		return err
+	}
+
+	// Campaigns collection indexes
+	campaignsCollection := m.GetCollection("campaigns")
+	if err := m.createCampaignsIndexes(ctx, campaignsCollection); err != nil {
+		return err
+	}
+
+	// Token redemptions collection indexes
+	redemptionsCollection := m.GetCollection("token_redemptions")
+	if err := m.createTokenRedemptionsIndexes(ctx, redemptionsCollection); err != nil {
+		return err
+	}
+
+	// Token audit collection indexes
+	tokenAuditCollection := m.GetCollection("token_audit")
+	if err := m.createTokenAuditIndexes(ctx, tokenAuditCollection); err != nil {
+		return err
+	}
+
+	// Admin directory collection indexes
+	adminsCollection := m.GetCollection("admins")
+	if err := m.createAdminsIndexes(ctx, adminsCollection); err != nil {
+		return err
+	}
+
+	// Admin audit collection indexes
+	adminAuditCollection := m.GetCollection("admin_audit")
+	if err := m.createAdminAuditIndexes(ctx, adminAuditCollection); err != nil {
+		return err
+	}
+
+	// Idempotency key collection indexes
+	idempotencyCollection := m.GetCollection("idempotency_keys")
+	if err := m.createIdempotencyIndexes(ctx, idempotencyCollection); err != nil {
+		return err
+	}
+
+	// Processing result collection indexes
+	processingResultsCollection := m.GetCollection("processing_results")
+	if err := m.createProcessingResultsIndexes(ctx, processingResultsCollection); err != nil {
+		return err
+	}
+
+	// Retained result collection indexes
+	retainedResultsCollection := m.GetCollection("retained_results")
+	if err := m.createRetainedResultsIndexes(ctx, retainedResultsCollection); err != nil {
+		return err
+	}
+
+	// Result retention settings collection indexes
+	resultRetentionCollection := m.GetCollection("result_retention")
+	if err := m.createResultRetentionIndexes(ctx, resultRetentionCollection); err != nil {
+		return err
+	}
+
+	// Raw usage collection indexes
+	usageCollection := m.GetCollection("usage")
+	if err := m.createUsageIndexes(ctx, usageCollection); err != nil {
+		return err
+	}
+
+	// Usage rollup collections are keyed by their compound _id; index the bucket for range reads
+	for _, name := range []string{"usage_hourly", "usage_daily"} {
+		if err := m.createUsageRollupIndexes(ctx, m.GetCollection(name)); err != nil {
+			return err
+		}
+	}
+
+	// Job history collection indexes
+	jobRunsCollection := m.GetCollection("job_runs")
+	if err := m.createJobRunsIndexes(ctx, jobRunsCollection); err != nil {
+		return err
+	}
+
 	log.Println("✅ Database indexes created successfully")
 	return nil
 }
@@ -32,13 +130,32 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 func (m *MongoDB) createUsersIndexes(ctx context.Context, collection *mongo.Collection) error {
 	indexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"userId", 1}},
+			Keys:    bson.D{{Key: "userId", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 		{
-			Keys:    bson.D{{"email", 1}},
+			Keys:    bson.D{{Key: "email", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
+		// Admin user search: default order, created-at range and last-active filters
+		{
+			Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		// Email prefix search runs an anchored regex on the lowercased copy
+		{
+			Keys: bson.D{{Key: "emailLower", Value: 1}},
+		},
+		// Sorting by balance or usage reads the copies kept on the user document
+		{
+			Keys: bson.D{{Key: "credits", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "totalCalls", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "lastActiveAt", Value: -1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
@@ -53,7 +170,7 @@ func (m *MongoDB) createUsersIndexes(ctx context.Context, collection *mongo.Coll
 func (m *MongoDB) createCreditsIndexes(ctx context.Context, collection *mongo.Collection) error {
 	indexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"userId", 1}},
+			Keys:    bson.D{{Key: "userId", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 	}
@@ -65,4 +182,346 @@ func (m *MongoDB) createCreditsIndexes(ctx context.Context, collection *mongo.Co
 
 	log.Println("✅ Credits collection indexes created")
 	return nil
-}
\ No newline at end of file
+}
+
+func (m *MongoDB) createCreditAlertsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Credit alerts collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createTokensIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "campaignId", Value: 1}},
+		},
+		// Paginated token lists are ordered by (createdAt, _id), newest first
+		{
+			Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "createdBy", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "isUsed", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Tokens collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createAPIKeysIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ API keys collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createActivitiesIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Activities collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createCampaignsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Campaigns collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createTokenRedemptionsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			// Enforces the per-user redemption limit under concurrency
+			Keys:    bson.D{{Key: "tokenId", Value: 1}, {Key: "userId", Value: 1}, {Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "tokenId", Value: 1}, {Key: "redeemedAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Only in-flight redemptions carry a status, so the stale-reservation scan stays small
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "redeemedAt", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Token redemptions collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createTokenAuditIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "tokenId", Value: 1}, {Key: "timestamp", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "event", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Token audit collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createAdminsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Admins collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createAdminAuditIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		// One entry per sequence keeps the hash chain from forking under concurrent writers
+		{
+			Keys:    bson.D{{Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Paginated audit lists are ordered by (timestamp, _id), newest first
+		{
+			Keys: bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "actor", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "targetId", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Admin audit collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createIdempotencyIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		// Stored responses are replayed until expiresAt and then removed by Mongo
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Idempotency keys collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createProcessingResultsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		// One result per user, service and req_id; claims rely on this to detect a repeat
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "service", Value: 1}, {Key: "reqId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Results are kept for the dedup window and then removed by Mongo
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Processing results collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createRetainedResultsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		// Lookup of a user's newest result for a req_id
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "service", Value: 1}, {Key: "reqId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		// The purge job deletes blobs before records, so expiry is not left to a TTL index
+		{
+			Keys: bson.D{{Key: "expiresAt", Value: 1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Retained results collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createResultRetentionIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Result retention collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createUsageIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "service_name", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		// A direct charge is recorded at most once per idempotency key and user
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Usage collection indexes created")
+	return nil
+}
+
+func (m *MongoDB) createUsageRollupIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "_id.bucket", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "_id.user_id", Value: 1}, {Key: "_id.bucket", Value: 1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ %s collection indexes created", collection.Name())
+	return nil
+}
+
+// jobRunRetention is how long job history is kept before the TTL index removes it
+const jobRunRetention = 90 * 24 * time.Hour
+
+func (m *MongoDB) createJobRunsIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "jobName", Value: 1}, {Key: "startedAt", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "startedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(jobRunRetention.Seconds())),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("✅ Job runs collection indexes created")
+	return nil
+}
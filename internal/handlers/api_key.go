@@ -264,12 +264,7 @@ func (h *APIKeyHandler) ValidateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := req.Validate(); err != nil {
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed",
-			err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
 		return
 	}
 
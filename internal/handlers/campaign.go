@@ -0,0 +1,154 @@
+// internal/handlers/campaign.go
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"chi-mongo-backend/internal/middleware"
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+	"chi-mongo-backend/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// unsafeFilenameChars matches characters stripped from campaign names in export filenames
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+type CampaignHandler struct {
+	campaignService services.CampaignService
+	auditService    services.AdminAuditService
+}
+
+func NewCampaignHandler(campaignService services.CampaignService, auditService services.AdminAuditService) *CampaignHandler {
+	return &CampaignHandler{
+		campaignService: campaignService,
+		auditService:    auditService,
+	}
+}
+
+// GenerateTokenBatch - Admin only: Generate a batch of tokens grouped under a new campaign
+func (h *CampaignHandler) GenerateTokenBatch(w http.ResponseWriter, r *http.Request) {
+	// Get admin email from context
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	var req models.GenerateTokenBatchRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.campaignService.GenerateTokenBatch(r.Context(), &req, email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	recordAdminAction(r, h.auditService, models.AdminActionTokenBatch, models.AuditTargetCampaign, response.Campaign.ID.Hex(), nil, map[string]interface{}{
+		"name":    response.Campaign.Name,
+		"count":   len(response.Tokens),
+		"credits": req.Credits,
+	})
+
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+// GetCampaigns - Admin only: List all token campaigns
+func (h *CampaignHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.campaignService.GetCampaigns(r.Context(), page)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetCampaignAnalytics - Admin only: Redemption analytics for a campaign
+func (h *CampaignHandler) GetCampaignAnalytics(w http.ResponseWriter, r *http.Request) {
+	campaignID := chi.URLParam(r, "campaignId")
+	if campaignID == "" {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrBadRequest,
+			http.StatusBadRequest,
+			"campaign ID is required",
+		))
+		return
+	}
+
+	response, err := h.campaignService.GetCampaignAnalytics(r.Context(), campaignID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// ExportCampaignTokens - Admin only: Download the campaign's token codes as CSV
+func (h *CampaignHandler) ExportCampaignTokens(w http.ResponseWriter, r *http.Request) {
+	campaignID := chi.URLParam(r, "campaignId")
+	if campaignID == "" {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrBadRequest,
+			http.StatusBadRequest,
+			"campaign ID is required",
+		))
+		return
+	}
+
+	campaign, tokens, err := h.campaignService.GetCampaignTokens(r.Context(), campaignID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("campaign-%s-tokens.csv", unsafeFilenameChars.ReplaceAllString(campaign.Name, "_"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"token", "credits", "expires_at", "is_used", "used_by", "used_at"})
+	for _, token := range tokens {
+		usedAt := ""
+		if token.UsedAt != nil {
+			usedAt = token.UsedAt.Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			models.FormatTokenCode(token.Token),
+			strconv.Itoa(token.Credits),
+			token.ExpiresAt.Format(time.RFC3339),
+			strconv.FormatBool(token.IsUsed),
+			token.UsedBy,
+			usedAt,
+		})
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing campaign CSV export: %v", err)
+	}
+}
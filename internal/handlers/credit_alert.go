@@ -0,0 +1,84 @@
+// internal/handlers/credit_alert.go
+package handlers
+
+import (
+	"net/http"
+
+	"chi-mongo-backend/internal/middleware"
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+type CreditAlertHandler struct {
+	alertService services.CreditAlertService
+	userService  services.UserService
+}
+
+func NewCreditAlertHandler(alertService services.CreditAlertService, userService services.UserService) *CreditAlertHandler {
+	return &CreditAlertHandler{
+		alertService: alertService,
+		userService:  userService,
+	}
+}
+
+// GetAlertSettings returns the caller's low-balance alert settings
+func (h *CreditAlertHandler) GetAlertSettings(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.alertService.GetSettings(r.Context(), user.UserID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// UpdateAlertSettings replaces the caller's low-balance alert settings
+func (h *CreditAlertHandler) UpdateAlertSettings(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	var req models.UpdateCreditAlertRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.alertService.UpdateSettings(r.Context(), user.UserID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
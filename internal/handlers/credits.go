@@ -14,12 +14,14 @@ import (
 type CreditsHandler struct {
 	creditsService services.CreditsService
 	userService    services.UserService
+	auditService   services.AdminAuditService
 }
 
-func NewCreditsHandler(creditsService services.CreditsService, userService services.UserService) *CreditsHandler {
+func NewCreditsHandler(creditsService services.CreditsService, userService services.UserService, auditService services.AdminAuditService) *CreditsHandler {
 	return &CreditsHandler{
 		creditsService: creditsService,
 		userService:    userService,
+		auditService:   auditService,
 	}
 }
 
@@ -88,27 +90,93 @@ func (h *CreditsHandler) AddCredits(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.creditsService.AddCredits(r.Context(), &req)
+	// Get admin email from context
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	response, err := h.creditsService.AddCredits(r.Context(), &req, email)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
+	recordAdminAction(r, h.auditService, models.AdminActionCreditsAdd, models.AuditTargetUser, req.UserID,
+		map[string]interface{}{"credits": response.Credits - req.Amount},
+		map[string]interface{}{"credits": response.Credits},
+	)
+
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
+// DeductCredits charges the caller's own credits; admins may charge any user. userId
+// defaults to the caller.
 func (h *CreditsHandler) DeductCredits(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
 	var req models.DeductCreditsRequest
 	if err := utils.DecodeJSONBody(r, &req); err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
-	response, err := h.creditsService.DeductCredits(r.Context(), &req)
+	caller, err := h.userService.GetOrCreateUser(r.Context(), email)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
+	if req.UserID == "" {
+		req.UserID = caller.UserID
+	}
+
+	adminEmail := ""
+	if req.UserID != caller.UserID {
+		if !middleware.IsAdminFromContext(r.Context()) {
+			utils.SendErrorResponse(w, apperrors.NewAppError(
+				apperrors.ErrForbidden,
+				http.StatusForbidden,
+				"you can only deduct your own credits",
+			))
+			return
+		}
+		adminEmail = email
+	}
+
+	response, err := h.creditsService.ChargeCredits(r.Context(), &req, adminEmail, h.getAuthMethod(r))
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	if adminEmail != "" && !response.Replayed {
+		recordAdminAction(r, h.auditService, models.AdminActionCreditsDeduct, models.AuditTargetUser, req.UserID,
+			map[string]interface{}{"credits": response.Credits + req.Amount},
+			map[string]interface{}{"credits": response.Credits, "chargeId": response.ChargeID, "reason": req.Reason},
+		)
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// getAuthMethod reports how the caller authenticated, for the usage ledger
+func (h *CreditsHandler) getAuthMethod(r *http.Request) string {
+	if _, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context()); isAPIKeyAuth {
+		return "api_key"
+	}
+	return "bearer_token"
+}
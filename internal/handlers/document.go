@@ -0,0 +1,147 @@
+// internal/handlers/document.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+// pageProcessor sends one page upstream. It returns the upstream result and whether it
+// succeeded; an error means the upstream service did not answer and the page is not charged.
+type pageProcessor func(ctx context.Context, page *models.DocumentPage) (result interface{}, success bool, message string, err error)
+
+// processDocumentPages processes the pages of a PDF one at a time and charges price credits
+// for each page the upstream service answered, the same as for a single image. Every page
+// is paid for before the first is sent, and the pages left unanswered are refunded.
+// Each page gets pageTimeout for its upstream call, so a long document cannot run the later
+// pages out of time, and the refund is made even when ctx has since ended.
+// It returns the result with the balance left afterwards.
+func processDocumentPages(
+	ctx context.Context,
+	creditsService services.CreditsService,
+	userID, reqID string,
+	price int,
+	pageTimeout time.Duration,
+	pages []*models.DocumentPage,
+	process pageProcessor,
+) (*models.DocumentResult, int, error) {
+	// Reserve every page with one conditional deduction, so a concurrent request cannot spend
+	// the credits while pages are processed. Pages the upstream service never answers are
+	// refunded at the end.
+	required := price * len(pages)
+	balance, err := creditsService.DeductCredits(ctx, &models.DeductCreditsRequest{UserID: userID, Amount: required})
+	if err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrInsufficientCredits) {
+			return nil, 0, apperrors.NewAppError(
+				apperrors.ErrInsufficientCredits,
+				http.StatusBadRequest,
+				fmt.Sprintf("insufficient credits for %d pages (%d credits required)", len(pages), required),
+			)
+		}
+		return nil, 0, err
+	}
+
+	document := &models.DocumentResult{ReqID: reqID, Success: true}
+	remainingCredits := balance.Credits
+	refund := 0
+	for _, page := range pages {
+		pageResult := models.DocumentPageResult{Page: page.Number}
+		if ctx.Err() != nil {
+			pageResult.Error = "not processed: the request was cancelled"
+			document.Pages = append(document.Pages, pageResult)
+			document.Success = false
+			refund += price
+			continue
+		}
+
+		pageCtx, cancel := context.WithTimeout(ctx, pageTimeout)
+		result, success, message, err := process(pageCtx, page)
+		cancel()
+		if err == nil && result == nil {
+			err = errors.New("empty response from the processing service")
+		}
+		if err != nil {
+			pageResult.Error = "processing failed: " + err.Error()
+			refund += price
+		} else {
+			pageResult.Result, pageResult.Success = result, success
+			if !success {
+				pageResult.Error = message
+			}
+			pageResult.CreditsUsed = price
+			document.CreditsUsed += price
+		}
+
+		document.Success = document.Success && pageResult.Success
+		document.Pages = append(document.Pages, pageResult)
+	}
+
+	if refund > 0 {
+		if updated, err := refundDocumentPages(ctx, creditsService, userID, refund); err != nil {
+			log.Printf("Failed to refund %d credits for unprocessed pages of %s to user %s: %v", refund, reqID, userID, err)
+		} else {
+			remainingCredits = updated.Credits
+		}
+	}
+
+	return document, remainingCredits, nil
+}
+
+// extendWriteDeadline lets the response to a document outlive the server's write timeout,
+// which is sized for a single image, up to shortly after the request's own deadline
+func extendWriteDeadline(w http.ResponseWriter, r *http.Request) {
+	if deadline, ok := r.Context().Deadline(); ok {
+		http.NewResponseController(w).SetWriteDeadline(deadline.Add(10 * time.Second))
+	}
+}
+
+// refundDocumentPages hands back the reservation for pages the upstream service did not
+// answer. The credits are already taken, so the refund does not share the request's
+// cancellation.
+func refundDocumentPages(ctx context.Context, creditsService services.CreditsService, userID string, amount int) (*models.CreditsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	return creditsService.RefundCredits(ctx, userID, amount)
+}
+
+// trackDocumentUsage records each page like a single-image call; base carries the fields
+// shared by every page
+func trackDocumentUsage(ctx context.Context, track func(context.Context, *models.UsageTrackingRequest), base models.UsageTrackingRequest, document *models.DocumentResult) {
+	for _, page := range document.Pages {
+		usage := base
+		// As for a single image, a call the upstream service answered counts as a success
+		usage.Success = page.CreditsUsed > 0
+		usage.CreditsUsed = page.CreditsUsed
+		usage.ErrorMsg = ""
+		if page.Error != "" {
+			usage.ErrorMsg = fmt.Sprintf("page %d: %s", page.Page, page.Error)
+		}
+		track(ctx, &usage)
+	}
+}
+
+// sendDocumentResult sends the per-page result of a PDF: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func sendDocumentResult(w http.ResponseWriter, isAPIKeyAuth bool, userID, message string, document *models.DocumentResult, remainingCredits int) {
+	if isAPIKeyAuth {
+		utils.SendJSONResponse(w, http.StatusOK, document)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, &models.DocumentResponse{
+		Message:          message,
+		UserID:           userID,
+		RemainingCredits: remainingCredits,
+		DocumentResult:   document,
+		ProcessedAt:      time.Now(),
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"chi-mongo-backend/internal/models"
@@ -20,15 +21,21 @@ type FaceDetectionHandler struct {
 	userService    services.UserService
 	faceAPIService services.FaceDetectionAPIService
 	usageService   services.UsageService
+	resultService  services.ProcessingResultService
+	resultStore    services.ResultStoreService
+	imagePrep      services.ImagePreprocessor
 	errorMapper    *apperrors.APIErrorMapper
 }
 
-func NewFaceDetectionHandler(creditsService services.CreditsService, userService services.UserService, faceAPIService services.FaceDetectionAPIService, usageService services.UsageService) *FaceDetectionHandler {
+func NewFaceDetectionHandler(creditsService services.CreditsService, userService services.UserService, faceAPIService services.FaceDetectionAPIService, usageService services.UsageService, resultService services.ProcessingResultService, resultStore services.ResultStoreService, imagePrep services.ImagePreprocessor) *FaceDetectionHandler {
 	return &FaceDetectionHandler{
 		creditsService: creditsService,
 		userService:    userService,
 		faceAPIService: faceAPIService,
 		usageService:   usageService,
+		resultService:  resultService,
+		resultStore:    resultStore,
+		imagePrep:      imagePrep,
 		errorMapper:    apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -66,9 +73,13 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.FaceDetectionRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.Doc, err = form.File("file")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -107,11 +118,29 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them
+	if err := req.PrepareImages(h.imagePrep.Prepare); err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "face-detection",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -182,6 +211,24 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "face-detection", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.FaceDetectionResult, remainingCredits int) {
+			if !result.Success {
+				h.sendFailure(w, isAPIKeyAuth, result)
+				return
+			}
+			h.sendResult(w, r, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -262,12 +309,18 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 
 	// Check if the API returned success
 	if faceResult == nil || !faceResult.Success {
+		if faceResult == nil {
+			faceResult = &models.FaceDetectionResult{ReqID: req.ReqID, Message: "empty response from the processing service"}
+		}
+
 		// Still deduct credits for API usage even when detection fails
 		deductReq := &models.DeductCreditsRequest{
 			UserID: user.UserID,
 			Amount: 1,
 		}
 		h.creditsService.DeductCredits(ctx, deductReq)
+		// The call is charged, so a retry with the same req_id gets this answer back
+		claim.complete(faceResult, 1)
 
 		// Track API failure (but still consider it a "successful" call since API responded)
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
@@ -285,33 +338,7 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		// Create original response structure with specific field order
-		originalResponse := struct {
-			ReqID        string      `json:"req_id"`
-			Success      bool        `json:"success"`
-			ErrorMessage string      `json:"error_message"`
-			Data         interface{} `json:"data"`
-		}{
-			ReqID:        faceResult.ReqID,
-			Success:      faceResult.Success,
-			ErrorMessage: faceResult.Message,
-			Data:         faceResult.Data,
-		}
-		
-		// Ensure data is empty array if nil
-		if originalResponse.Data == nil {
-			originalResponse.Data = []interface{}{}
-		}
-		
-		// Handle error response based on authentication method
-		if isAPIKeyAuth {
-			// For API key authentication: return only original_response structure
-			utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
-		} else {
-			// For Bearer token (frontend): return full error with user-friendly message
-			apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, faceResult.Message, originalResponse)
-			utils.SendErrorResponse(w, apiError)
-		}
+		h.sendFailure(w, isAPIKeyAuth, faceResult)
 		return
 	}
 
@@ -347,6 +374,9 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 		return
 	}
 
+	claim.complete(faceResult, 1)
+	resultID := retainProcessingResult(ctx, h.resultStore, user.UserID, "face-detection", req.ReqID, faceResult)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -361,27 +391,94 @@ func (h *FaceDetectionHandler) ProcessFaceDetection(w http.ResponseWriter, r *ht
 		UserAgent:   r.UserAgent(),
 		AuthMethod:  h.getAuthMethod(r),
 		ProcessTime: time.Since(startTime).Milliseconds(),
+		ResultID:    resultID,
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, r, isAPIKeyAuth, user.UserID, faceResult, updatedBalance.Credits)
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *FaceDetectionHandler) sendResult(w http.ResponseWriter, r *http.Request, isAPIKeyAuth bool, userID string, result *models.FaceDetectionResult, remainingCredits int) {
+	// Clients that accept an image get one face crop, chosen with ?face= (default 0); the
+	// number of faces is in X-Face-Count
+	if wantsImage(r) && len(result.Data) > 0 {
+		index := 0
+		if value := r.URL.Query().Get("face"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 || parsed >= len(result.Data) {
+				utils.SendErrorResponse(w, apperrors.NewAppError(
+					apperrors.ErrValidation,
+					http.StatusBadRequest,
+					fmt.Sprintf("face must be between 0 and %d", len(result.Data)-1),
+				))
+				return
+			}
+			index = parsed
+		}
+
+		headers := imageHeaders(result.ReqID, isAPIKeyAuth, remainingCredits)
+		headers["X-Face-Count"] = strconv.Itoa(len(result.Data))
+		if sendImage(w, result.Data[index], headers) {
+			return
+		}
+	}
+
 	if isAPIKeyAuth {
-		// For API key authentication: return only faceResult
-		utils.SendJSONResponse(w, http.StatusOK, faceResult)
+		// For API key authentication: return only the result
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		// For Bearer token (frontend): return full response with credits info
 		response := &models.FaceDetectionResponse{
 			Message:          "Face detection completed successfully",
-			UserID:           user.UserID,
-			RemainingCredits: updatedBalance.Credits,
-			FaceResult:       faceResult,
+			UserID:           userID,
+			RemainingCredits: remainingCredits,
+			FaceResult:       result,
 			ProcessedAt:      time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// sendFailure answers a call the upstream service rejected: API key callers get the
+// upstream response, frontend callers a mapped error
+func (h *FaceDetectionHandler) sendFailure(w http.ResponseWriter, isAPIKeyAuth bool, result *models.FaceDetectionResult) {
+	// Create original response structure with specific field order
+	originalResponse := struct {
+		ReqID        string      `json:"req_id"`
+		Success      bool        `json:"success"`
+		ErrorMessage string      `json:"error_message"`
+		Data         interface{} `json:"data"`
+	}{
+		ReqID:        result.ReqID,
+		Success:      result.Success,
+		ErrorMessage: result.Message,
+		Data:         result.Data,
+	}
+	
+	// Ensure data is empty array if nil
+	if originalResponse.Data == nil {
+		originalResponse.Data = []interface{}{}
+	}
+	
+	// Handle error response based on authentication method
+	if isAPIKeyAuth {
+		// For API key authentication: return only original_response structure
+		utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
+	} else {
+		// For Bearer token (frontend): return full error with user-friendly message
+		apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, result.Message, originalResponse)
+		utils.SendErrorResponse(w, apiError)
+	}
+}
+
 // Helper methods for the FaceDetectionHandler
 func (h *FaceDetectionHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -20,6 +20,8 @@ type FaceVerificationHandler struct {
 	userService    services.UserService
 	faceAPIService services.FaceVerificationAPIService
 	usageService   services.UsageService
+	resultService  services.ProcessingResultService
+	imagePrep      services.ImagePreprocessor
 	errorMapper    *apperrors.APIErrorMapper
 }
 
@@ -28,12 +30,16 @@ func NewFaceVerificationHandler(
 	userService services.UserService,
 	faceAPIService services.FaceVerificationAPIService,
 	usageService services.UsageService,
+	resultService services.ProcessingResultService,
+	imagePrep services.ImagePreprocessor,
 ) *FaceVerificationHandler {
 	return &FaceVerificationHandler{
 		creditsService: creditsService,
 		userService:    userService,
 		faceAPIService: faceAPIService,
 		usageService:   usageService,
+		resultService:  resultService,
+		imagePrep:      imagePrep,
 		errorMapper:    apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -71,9 +77,17 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.FaceVerificationRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.DocType = form.Value("doc_type")
+		if req.Doc1, err = form.File("file_1"); err != nil {
+			return err
+		}
+		req.Doc2, err = form.File("file_2")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -112,11 +126,29 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them
+	if err := req.PrepareImages(h.imagePrep.Prepare); err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "face-verification",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -187,6 +219,20 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "face-verification", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.FaceVerificationResult, remainingCredits int) {
+			h.sendResult(w, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -345,6 +391,8 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 		return
 	}
 
+	claim.complete(faceResult, 2)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -361,17 +409,22 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 		ProcessTime: time.Since(startTime).Milliseconds(),
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, isAPIKeyAuth, user.UserID, faceResult, updatedBalance.Credits)
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *FaceVerificationHandler) sendResult(w http.ResponseWriter, isAPIKeyAuth bool, userID string, result *models.FaceVerificationResult, remainingCredits int) {
 	if isAPIKeyAuth {
-		// For API key authentication: return only faceResult
-		utils.SendJSONResponse(w, http.StatusOK, faceResult)
+		// For API key authentication: return only the result
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		// For Bearer token (frontend): return full response with credits info
 		response := &models.FaceVerificationResponse{
 			Message:          "Face verification completed successfully",
-			UserID:           user.UserID,
-			RemainingCredits: updatedBalance.Credits,
-			FaceResult:       faceResult,
+			UserID:           userID,
+			RemainingCredits: remainingCredits,
+			FaceResult:       result,
 			ProcessedAt:      time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
@@ -380,6 +433,11 @@ func (h *FaceVerificationHandler) ProcessFaceVerification(w http.ResponseWriter,
 
 // Helper methods for the FaceVerificationHandler
 func (h *FaceVerificationHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -20,6 +20,9 @@ type IDCroppingHandler struct {
 	userService    services.UserService
 	idAPIService   services.IDCroppingAPIService
 	usageService   services.UsageService
+	resultService  services.ProcessingResultService
+	resultStore    services.ResultStoreService
+	imagePrep      services.ImagePreprocessor
 	errorMapper    *apperrors.APIErrorMapper
 }
 
@@ -28,12 +31,18 @@ func NewIDCroppingHandler(
 	userService services.UserService,
 	idAPIService services.IDCroppingAPIService,
 	usageService services.UsageService,
+	resultService services.ProcessingResultService,
+	resultStore services.ResultStoreService,
+	imagePrep services.ImagePreprocessor,
 ) *IDCroppingHandler {
 	return &IDCroppingHandler{
 		creditsService: creditsService,
 		userService:    userService,
 		idAPIService:   idAPIService,
 		usageService:   usageService,
+		resultService:  resultService,
+		resultStore:    resultStore,
+		imagePrep:      imagePrep,
 		errorMapper:    apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -71,9 +80,14 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.IDCroppingRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.Pages = form.Value("pages")
+		req.Doc, err = form.File("file")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -112,11 +126,35 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them; a PDF is
+	// split into the images of its selected pages
+	var pages []*models.DocumentPage
+	err := req.PrepareImages(h.imagePrep.PrepareDocument)
+	if err == nil && req.Doc.IsPDF() {
+		pages, err = h.imagePrep.ExtractPages(req.Doc, req.Pages)
+	}
+	if err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "id-cropping",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -187,6 +225,86 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "id-cropping", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	// A repeated PDF is answered with its stored per-page result
+	if previous != nil && pages != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.DocumentResult, remainingCredits int) {
+			sendDocumentResult(w, isAPIKeyAuth, user.UserID, "ID cropping completed", result, remainingCredits)
+		})
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.IDCroppingResult, remainingCredits int) {
+			if !result.Success {
+				h.sendFailure(w, isAPIKeyAuth, result)
+				return
+			}
+			h.sendResult(w, r, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
+	// A PDF is processed page by page, each page charged like a single image
+	if pages != nil {
+		extendWriteDeadline(w, r)
+		document, remainingCredits, err := processDocumentPages(r.Context(), h.creditsService, user.UserID, req.ReqID, 1, 30*time.Second, pages, func(ctx context.Context, page *models.DocumentPage) (interface{}, bool, string, error) {
+			pageReq := req
+			pageReq.Doc = page.Image
+			result, err := h.idAPIService.ProcessIDCropping(ctx, &pageReq)
+			if err != nil || result == nil {
+				return nil, false, "", err
+			}
+			return result, result.Success, result.Message, nil
+		})
+		if err != nil {
+			// Track balance check failure
+			h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+				UserID:      user.UserID,
+				Email:       email,
+				ServiceName: "id-cropping",
+				Endpoint:    r.URL.Path,
+				Method:      r.Method,
+				Success:     false,
+				ErrorMsg:    err.Error(),
+				CreditsUsed: 0,
+				IPAddress:   h.getClientIP(r),
+				UserAgent:   r.UserAgent(),
+				AuthMethod:  h.getAuthMethod(r),
+				ProcessTime: time.Since(startTime).Milliseconds(),
+			})
+
+			utils.SendErrorResponse(w, err)
+			return
+		}
+
+		if document.CreditsUsed > 0 {
+			claim.complete(document, document.CreditsUsed)
+		}
+		resultID := retainProcessingResult(ctx, h.resultStore, user.UserID, "id-cropping", req.ReqID, document)
+
+		trackDocumentUsage(r.Context(), h.trackUsage, models.UsageTrackingRequest{
+			UserID:      user.UserID,
+			Email:       email,
+			ServiceName: "id-cropping",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+			ResultID:    resultID,
+		}, document)
+
+		sendDocumentResult(w, isAPIKeyAuth, user.UserID, "ID cropping completed", document, remainingCredits)
+		return
+	}
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -267,12 +385,18 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 
 	// Check if the API returned success
 	if cropResult == nil || !cropResult.Success {
+		if cropResult == nil {
+			cropResult = &models.IDCroppingResult{ReqID: req.ReqID, Message: "empty response from the processing service"}
+		}
+
 		// Still deduct credits for API usage even when cropping fails
 		deductReq := &models.DeductCreditsRequest{
 			UserID: user.UserID,
 			Amount: 1,
 		}
 		h.creditsService.DeductCredits(ctx, deductReq)
+		// The call is charged, so a retry with the same req_id gets this answer back
+		claim.complete(cropResult, 1)
 
 		// Track API failure (but still consider it a "successful" call since API responded)
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
@@ -290,33 +414,7 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		// Create original response structure with specific field order
-		originalResponse := struct {
-			ReqID        string `json:"req_id"`
-			Success      bool   `json:"success"`
-			ErrorMessage string `json:"error_message"`
-			Result       string `json:"result"`
-		}{
-			ReqID:        cropResult.ReqID,
-			Success:      cropResult.Success,
-			ErrorMessage: cropResult.Message,
-			Result:       "", // Empty result for failed requests
-		}
-
-		// If cropResult.Result exists, use its value
-		if cropResult.Result != nil {
-			originalResponse.Result = *cropResult.Result
-		}
-
-		// Handle error response based on authentication method
-		if isAPIKeyAuth {
-			// For API key authentication: return only original_response structure
-			utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
-		} else {
-			// For Bearer token (frontend): return full error with user-friendly message
-			apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, cropResult.Message, originalResponse)
-			utils.SendErrorResponse(w, apiError)
-		}
+		h.sendFailure(w, isAPIKeyAuth, cropResult)
 		return
 	}
 
@@ -352,6 +450,9 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	claim.complete(cropResult, 1)
+	resultID := retainProcessingResult(ctx, h.resultStore, user.UserID, "id-cropping", req.ReqID, cropResult)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -366,27 +467,75 @@ func (h *IDCroppingHandler) ProcessIDCropping(w http.ResponseWriter, r *http.Req
 		UserAgent:   r.UserAgent(),
 		AuthMethod:  h.getAuthMethod(r),
 		ProcessTime: time.Since(startTime).Milliseconds(),
+		ResultID:    resultID,
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, r, isAPIKeyAuth, user.UserID, cropResult, updatedBalance.Credits)
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *IDCroppingHandler) sendResult(w http.ResponseWriter, r *http.Request, isAPIKeyAuth bool, userID string, result *models.IDCroppingResult, remainingCredits int) {
+	// Clients that accept an image get the cropped ID itself
+	if wantsImage(r) && result.Result != nil && sendImage(w, *result.Result, imageHeaders(result.ReqID, isAPIKeyAuth, remainingCredits)) {
+		return
+	}
+
 	if isAPIKeyAuth {
-		// For API key authentication: return only cropResult
-		utils.SendJSONResponse(w, http.StatusOK, cropResult)
+		// For API key authentication: return only the result
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		// For Bearer token (frontend): return full response with credits info
 		response := &models.IDCroppingResponse{
 			Message:          "ID cropping completed successfully",
-			UserID:           user.UserID,
-			RemainingCredits: updatedBalance.Credits,
-			CropResult:       cropResult,
+			UserID:           userID,
+			RemainingCredits: remainingCredits,
+			CropResult:       result,
 			ProcessedAt:      time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// sendFailure answers a call the upstream service rejected: API key callers get the
+// upstream response, frontend callers a mapped error
+func (h *IDCroppingHandler) sendFailure(w http.ResponseWriter, isAPIKeyAuth bool, result *models.IDCroppingResult) {
+	// Create original response structure with specific field order
+	originalResponse := struct {
+		ReqID        string `json:"req_id"`
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"error_message"`
+		Result       string `json:"result"`
+	}{
+		ReqID:        result.ReqID,
+		Success:      result.Success,
+		ErrorMessage: result.Message,
+		Result:       "", // Empty result for failed requests
+	}
+
+	// If result.Result exists, use its value
+	if result.Result != nil {
+		originalResponse.Result = *result.Result
+	}
+
+	// Handle error response based on authentication method
+	if isAPIKeyAuth {
+		// For API key authentication: return only original_response structure
+		utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
+	} else {
+		// For Bearer token (frontend): return full error with user-friendly message
+		apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, result.Message, originalResponse)
+		utils.SendErrorResponse(w, apiError)
+	}
+}
+
 // Helper methods for the IDCroppingHandler
 func (h *IDCroppingHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,251 @@
+// internal/handlers/processing_result.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"chi-mongo-backend/internal/middleware"
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ResultHandler struct {
+	resultService services.ProcessingResultService
+	resultStore   services.ResultStoreService
+	userService   services.UserService
+}
+
+func NewResultHandler(resultService services.ProcessingResultService, resultStore services.ResultStoreService, userService services.UserService) *ResultHandler {
+	return &ResultHandler{
+		resultService: resultService,
+		resultStore:   resultStore,
+		userService:   userService,
+	}
+}
+
+// GetResult returns the caller's stored result of a processing call by its req_id
+func (h *ResultHandler) GetResult(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.resultService.GetResult(r.Context(), user.UserID, chi.URLParam(r, "service"), chi.URLParam(r, "req_id"))
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetRetainedResult returns a result kept in the result store, looked up by usage_id or by
+// service and req_id
+func (h *ResultHandler) GetRetainedResult(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	query := r.URL.Query()
+	usageID, service, reqID := query.Get("usage_id"), query.Get("service"), query.Get("req_id")
+	if usageID == "" && (service == "" || reqID == "") {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrValidation,
+			http.StatusBadRequest,
+			"usage_id, or service and req_id, are required",
+		))
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	var response *models.RetainedResultResponse
+	if usageID != "" {
+		response, err = h.resultStore.GetByUsageID(r.Context(), user.UserID, usageID)
+	} else {
+		response, err = h.resultStore.GetByReqID(r.Context(), user.UserID, service, reqID)
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetRetentionSettings returns the caller's result retention settings
+func (h *ResultHandler) GetRetentionSettings(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.resultStore.GetSettings(r.Context(), user.UserID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// UpdateRetentionSettings opts the caller in to or out of keeping processing results
+func (h *ResultHandler) UpdateRetentionSettings(w http.ResponseWriter, r *http.Request) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	var req models.UpdateResultRetentionRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.resultStore.UpdateSettings(r.Context(), user.UserID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// resultClaim holds a req_id while a processing call runs. Whatever happens to the call,
+// the claim ends either completed with the result or released for a retry.
+type resultClaim struct {
+	resultService services.ProcessingResultService
+	userID        string
+	service       string
+	reqID         string
+	done          bool
+}
+
+// claimProcessingResult reserves req_id for the call, or returns the result stored for an
+// earlier identical request. The claim is nil when the user is not deduplicated; its methods
+// then do nothing.
+func claimProcessingResult(ctx context.Context, resultService services.ProcessingResultService, userID, service, reqID string, request interface{}) (*resultClaim, *models.ProcessingResult, error) {
+	previous, claimed, err := resultService.Claim(ctx, userID, service, reqID, request)
+	if err != nil || !claimed {
+		return nil, previous, err
+	}
+	return &resultClaim{resultService: resultService, userID: userID, service: service, reqID: reqID}, nil, nil
+}
+
+// complete stores the result of a charged call
+func (c *resultClaim) complete(result interface{}, creditsUsed int) {
+	if c == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.done = true
+	if err := c.resultService.Complete(ctx, c.userID, c.service, c.reqID, result, creditsUsed); err != nil {
+		log.Printf("Failed to store %s result for req_id %s: %v", c.service, c.reqID, err)
+	}
+}
+
+// release frees the req_id unless the call completed; meant to be deferred
+func (c *resultClaim) release() {
+	if c == nil || c.done {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.resultService.Release(ctx, c.userID, c.service, c.reqID); err != nil {
+		log.Printf("Failed to release %s req_id %s: %v", c.service, c.reqID, err)
+	}
+}
+
+// replayProcessingResult answers a repeated req_id with the stored result and the current
+// balance; send writes the response the same way a fresh call would
+func replayProcessingResult[T any](ctx context.Context, w http.ResponseWriter, creditsService services.CreditsService, userID string, previous *models.ProcessingResult, send func(result *T, remainingCredits int)) {
+	var result T
+	if err := json.Unmarshal(previous.Result, &result); err != nil {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrInternalServer,
+			http.StatusInternalServerError,
+			"failed to read stored result: "+err.Error(),
+		))
+		return
+	}
+
+	balance, err := creditsService.GetBalance(ctx, userID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	send(&result, balance.Credits)
+}
+
+// retainProcessingResult keeps the result for users who opted in to result retention and
+// returns its ID for the usage record. The caller already has the result, so a failure is
+// only logged.
+func retainProcessingResult(ctx context.Context, resultStore services.ResultStoreService, userID, service, reqID string, result interface{}) string {
+	// The call is already charged, so its result is kept even when the request has timed out
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	resultID, err := resultStore.Retain(ctx, userID, service, reqID, result)
+	if err != nil {
+		log.Printf("Failed to retain %s result for req_id %s: %v", service, reqID, err)
+		return ""
+	}
+	return resultID
+}
@@ -20,6 +20,8 @@ type QRExtractionHandler struct {
 	userService    services.UserService
 	qrAPIService   services.QRExtractionAPIService
 	usageService   services.UsageService
+	resultService  services.ProcessingResultService
+	imagePrep      services.ImagePreprocessor
 	errorMapper    *apperrors.APIErrorMapper
 }
 
@@ -28,12 +30,16 @@ func NewQRExtractionHandler(
 	userService services.UserService,
 	qrAPIService services.QRExtractionAPIService,
 	usageService services.UsageService,
+	resultService services.ProcessingResultService,
+	imagePrep services.ImagePreprocessor,
 ) *QRExtractionHandler {
 	return &QRExtractionHandler{
 		creditsService: creditsService,
 		userService:    userService,
 		qrAPIService:   qrAPIService,
 		usageService:   usageService,
+		resultService:  resultService,
+		imagePrep:      imagePrep,
 		errorMapper:    apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -71,9 +77,14 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.QRExtractionRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.Pages = form.Value("pages")
+		req.Doc, err = form.File("file")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -112,11 +123,35 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them; a PDF is
+	// split into the images of its selected pages
+	var pages []*models.DocumentPage
+	err := req.PrepareImages(h.imagePrep.PrepareDocument)
+	if err == nil && req.Doc.IsPDF() {
+		pages, err = h.imagePrep.ExtractPages(req.Doc, req.Pages)
+	}
+	if err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "qr-extraction",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -187,6 +222,84 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "qr-extraction", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	// A repeated PDF is answered with its stored per-page result
+	if previous != nil && pages != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.DocumentResult, remainingCredits int) {
+			sendDocumentResult(w, isAPIKeyAuth, user.UserID, "QR extraction completed", result, remainingCredits)
+		})
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.QRExtractionResult, remainingCredits int) {
+			if !result.Success {
+				h.sendFailure(w, isAPIKeyAuth, result)
+				return
+			}
+			h.sendResult(w, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
+	// A PDF is processed page by page, each page charged like a single image
+	if pages != nil {
+		extendWriteDeadline(w, r)
+		document, remainingCredits, err := processDocumentPages(r.Context(), h.creditsService, user.UserID, req.ReqID, 1, 30*time.Second, pages, func(ctx context.Context, page *models.DocumentPage) (interface{}, bool, string, error) {
+			pageReq := req
+			pageReq.Doc = page.Image
+			result, err := h.qrAPIService.ProcessQRExtraction(ctx, &pageReq)
+			if err != nil || result == nil {
+				return nil, false, "", err
+			}
+			return result, result.Success, result.Message, nil
+		})
+		if err != nil {
+			// Track balance check failure
+			h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+				UserID:      user.UserID,
+				Email:       email,
+				ServiceName: "qr-extraction",
+				Endpoint:    r.URL.Path,
+				Method:      r.Method,
+				Success:     false,
+				ErrorMsg:    err.Error(),
+				CreditsUsed: 0,
+				IPAddress:   h.getClientIP(r),
+				UserAgent:   r.UserAgent(),
+				AuthMethod:  h.getAuthMethod(r),
+				ProcessTime: time.Since(startTime).Milliseconds(),
+			})
+
+			utils.SendErrorResponse(w, err)
+			return
+		}
+
+		if document.CreditsUsed > 0 {
+			claim.complete(document, document.CreditsUsed)
+		}
+
+		trackDocumentUsage(r.Context(), h.trackUsage, models.UsageTrackingRequest{
+			UserID:      user.UserID,
+			Email:       email,
+			ServiceName: "qr-extraction",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		}, document)
+
+		sendDocumentResult(w, isAPIKeyAuth, user.UserID, "QR extraction completed", document, remainingCredits)
+		return
+	}
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -267,12 +380,18 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 
 	// Check if the API returned success
 	if qrResult == nil || !qrResult.Success {
+		if qrResult == nil {
+			qrResult = &models.QRExtractionResult{ReqID: req.ReqID, Message: "empty response from the processing service"}
+		}
+
 		// Still deduct credits for API usage even when extraction fails
 		deductReq := &models.DeductCreditsRequest{
 			UserID: user.UserID,
 			Amount: 1,
 		}
 		h.creditsService.DeductCredits(ctx, deductReq)
+		// The call is charged, so a retry with the same req_id gets this answer back
+		claim.complete(qrResult, 1)
 
 		// Track API failure (but still consider it a "successful" call since API responded)
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
@@ -290,28 +409,7 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		// Create original response structure with specific field order
-		originalResponse := struct {
-			ReqID        string      `json:"req_id"`
-			Success      bool        `json:"success"`
-			ErrorMessage string      `json:"error_message"`
-			Result       interface{} `json:"result"`
-		}{
-			ReqID:        qrResult.ReqID,
-			Success:      qrResult.Success,
-			ErrorMessage: qrResult.Message,
-			Result:       nil, // null for failed requests
-		}
-
-		// Handle error response based on authentication method
-		if isAPIKeyAuth {
-			// For API key authentication: return only original_response
-			utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
-		} else {
-			// For Bearer token (frontend): return full error with user-friendly message
-			apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, qrResult.Message, originalResponse)
-			utils.SendErrorResponse(w, apiError)
-		}
+		h.sendFailure(w, isAPIKeyAuth, qrResult)
 		return
 	}
 
@@ -347,6 +445,8 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 		return
 	}
 
+	claim.complete(qrResult, 1)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -363,25 +463,62 @@ func (h *QRExtractionHandler) ProcessQRExtraction(w http.ResponseWriter, r *http
 		ProcessTime: time.Since(startTime).Milliseconds(),
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, isAPIKeyAuth, user.UserID, qrResult, updatedBalance.Credits)
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *QRExtractionHandler) sendResult(w http.ResponseWriter, isAPIKeyAuth bool, userID string, result *models.QRExtractionResult, remainingCredits int) {
 	if isAPIKeyAuth {
-		// For API key authentication: return only qrResult
-		utils.SendJSONResponse(w, http.StatusOK, qrResult)
+		// For API key authentication: return only the result
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		// For Bearer token (frontend): return full response with credits info
 		response := &models.QRExtractionResponse{
 			Message:          "QR extraction completed successfully",
-			UserID:           user.UserID,
-			RemainingCredits: updatedBalance.Credits,
-			QRResult:         qrResult,
+			UserID:           userID,
+			RemainingCredits: remainingCredits,
+			QRResult:         result,
 			ProcessedAt:      time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// sendFailure answers a call the upstream service rejected: API key callers get the
+// upstream response, frontend callers a mapped error
+func (h *QRExtractionHandler) sendFailure(w http.ResponseWriter, isAPIKeyAuth bool, result *models.QRExtractionResult) {
+	// Create original response structure with specific field order
+	originalResponse := struct {
+		ReqID        string      `json:"req_id"`
+		Success      bool        `json:"success"`
+		ErrorMessage string      `json:"error_message"`
+		Result       interface{} `json:"result"`
+	}{
+		ReqID:        result.ReqID,
+		Success:      result.Success,
+		ErrorMessage: result.Message,
+		Result:       nil, // null for failed requests
+	}
+
+	// Handle error response based on authentication method
+	if isAPIKeyAuth {
+		// For API key authentication: return only original_response
+		utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
+	} else {
+		// For Bearer token (frontend): return full error with user-friendly message
+		apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, result.Message, originalResponse)
+		utils.SendErrorResponse(w, apiError)
+	}
+}
+
 // Helper methods for the QRExtractionHandler
 func (h *QRExtractionHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
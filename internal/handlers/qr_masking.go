@@ -20,6 +20,9 @@ type QRMaskingHandler struct {
 	userService    services.UserService
 	qrAPIService   services.QRMaskingAPIService
 	usageService   services.UsageService
+	resultService  services.ProcessingResultService
+	resultStore    services.ResultStoreService
+	imagePrep      services.ImagePreprocessor
 	errorMapper    *apperrors.APIErrorMapper
 }
 
@@ -28,12 +31,18 @@ func NewQRMaskingHandler(
 	userService services.UserService,
 	qrAPIService services.QRMaskingAPIService,
 	usageService services.UsageService,
+	resultService services.ProcessingResultService,
+	resultStore services.ResultStoreService,
+	imagePrep services.ImagePreprocessor,
 ) *QRMaskingHandler {
 	return &QRMaskingHandler{
 		creditsService: creditsService,
 		userService:    userService,
 		qrAPIService:   qrAPIService,
 		usageService:   usageService,
+		resultService:  resultService,
+		resultStore:    resultStore,
+		imagePrep:      imagePrep,
 		errorMapper:    apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -71,9 +80,13 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.QRMaskingRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.Image, err = form.File("file")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -112,11 +125,29 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them
+	if err := req.PrepareImages(h.imagePrep.Prepare); err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "qr-masking",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -187,6 +218,24 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "qr-masking", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.QRMaskingResult, remainingCredits int) {
+			if !result.Success {
+				h.sendFailure(w, isAPIKeyAuth, result)
+				return
+			}
+			h.sendResult(w, r, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -267,12 +316,18 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 
 	// Check if the API returned success
 	if qrResult == nil || !qrResult.Success {
+		if qrResult == nil {
+			qrResult = &models.QRMaskingResult{ReqID: req.ReqID, Message: "empty response from the processing service"}
+		}
+
 		// Still deduct credits for API usage even when masking fails
 		deductReq := &models.DeductCreditsRequest{
 			UserID: user.UserID,
 			Amount: 1,
 		}
 		h.creditsService.DeductCredits(ctx, deductReq)
+		// The call is charged, so a retry with the same req_id gets this answer back
+		claim.complete(qrResult, 1)
 
 		// Track API failure (but still consider it a "successful" call since API responded)
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
@@ -290,25 +345,7 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		// Create original response structure
-		originalResponse := struct {
-			ReqID        string                 `json:"req_id"`
-			Success      bool                   `json:"success"`
-			ErrorMessage string                 `json:"error_message"`
-			Data         map[string]interface{} `json:"data"`
-		}{
-			ReqID:        qrResult.ReqID,
-			Success:      qrResult.Success,
-			ErrorMessage: qrResult.Message,
-			Data:         map[string]interface{}{},
-		}
-
-		if isAPIKeyAuth {
-			utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
-		} else {
-			apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, qrResult.Message, originalResponse)
-			utils.SendErrorResponse(w, apiError)
-		}
+		h.sendFailure(w, isAPIKeyAuth, qrResult)
 		return
 	}
 
@@ -344,6 +381,9 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	claim.complete(qrResult, 1)
+	resultID := retainProcessingResult(ctx, h.resultStore, user.UserID, "qr-masking", req.ReqID, qrResult)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -358,27 +398,67 @@ func (h *QRMaskingHandler) ProcessQRMasking(w http.ResponseWriter, r *http.Reque
 		UserAgent:   r.UserAgent(),
 		AuthMethod:  h.getAuthMethod(r),
 		ProcessTime: time.Since(startTime).Milliseconds(),
+		ResultID:    resultID,
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, r, isAPIKeyAuth, user.UserID, qrResult, updatedBalance.Credits)
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *QRMaskingHandler) sendResult(w http.ResponseWriter, r *http.Request, isAPIKeyAuth bool, userID string, result *models.QRMaskingResult, remainingCredits int) {
+	// Clients that accept an image get the masked image itself
+	if wantsImage(r) && sendImage(w, result.MaskedBase64, imageHeaders(result.ReqID, isAPIKeyAuth, remainingCredits)) {
+		return
+	}
+
 	if isAPIKeyAuth {
-		// For API key authentication: return only qrResult
-		utils.SendJSONResponse(w, http.StatusOK, qrResult)
+		// For API key authentication: return only the result
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		// For Bearer token (frontend): return full response with credits info
 		response := &models.QRMaskingResponse{
 			Message:          "QR masking completed successfully",
-			UserID:           user.UserID,
-			RemainingCredits: updatedBalance.Credits,
-			QRResult:         qrResult,
+			UserID:           userID,
+			RemainingCredits: remainingCredits,
+			QRResult:         result,
 			ProcessedAt:      time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// sendFailure answers a call the upstream service rejected: API key callers get the
+// upstream response, frontend callers a mapped error
+func (h *QRMaskingHandler) sendFailure(w http.ResponseWriter, isAPIKeyAuth bool, result *models.QRMaskingResult) {
+	// Create original response structure
+	originalResponse := struct {
+		ReqID        string                 `json:"req_id"`
+		Success      bool                   `json:"success"`
+		ErrorMessage string                 `json:"error_message"`
+		Data         map[string]interface{} `json:"data"`
+	}{
+		ReqID:        result.ReqID,
+		Success:      result.Success,
+		ErrorMessage: result.Message,
+		Data:         map[string]interface{}{},
+	}
+
+	if isAPIKeyAuth {
+		utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
+	} else {
+		apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, result.Message, originalResponse)
+		utils.SendErrorResponse(w, apiError)
+	}
+}
+
 // Helper methods for the QRMaskingHandler
 func (h *QRMaskingHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
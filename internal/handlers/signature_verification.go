@@ -20,6 +20,8 @@ type SignatureVerificationHandler struct {
 	userService         services.UserService
 	signatureAPIService services.SignatureVerificationAPIService
 	usageService        services.UsageService
+	resultService       services.ProcessingResultService
+	imagePrep           services.ImagePreprocessor
 	errorMapper         *apperrors.APIErrorMapper
 }
 
@@ -28,12 +30,16 @@ func NewSignatureVerificationHandler(
 	userService services.UserService,
 	signatureAPIService services.SignatureVerificationAPIService,
 	usageService services.UsageService,
+	resultService services.ProcessingResultService,
+	imagePrep services.ImagePreprocessor,
 ) *SignatureVerificationHandler {
 	return &SignatureVerificationHandler{
 		creditsService:      creditsService,
 		userService:         userService,
 		signatureAPIService: signatureAPIService,
 		usageService:        usageService,
+		resultService:       resultService,
+		imagePrep:           imagePrep,
 		errorMapper:         apperrors.NewAPIErrorMapper(),
 	}
 }
@@ -71,9 +77,14 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 	// Check if request is authenticated via API key
 	_, isAPIKeyAuth := middleware.GetAPIKeyFromContext(r.Context())
 
-	// Parse request body
+	// Parse request body; files may be uploaded as multipart/form-data instead of base64
 	var req models.SignatureVerificationRequest
-	if err := utils.DecodeJSONBody(r, &req); err != nil {
+	if err := decodeProcessingRequest(r, &req, func(form *uploadForm) (err error) {
+		req.ReqID = form.Value("req_id")
+		req.Pages = form.Value("pages")
+		req.Docs, err = form.Files("file")
+		return err
+	}); err != nil {
 		// Track validation failure
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 			UserID:      email,
@@ -112,11 +123,31 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrValidation,
-			http.StatusBadRequest,
-			"validation failed: "+err.Error(),
-		))
+		utils.SendErrorResponse(w, apperrors.NewValidationError(err))
+		return
+	}
+
+	// Check and normalize the images before any credits are reserved for them; a PDF is
+	// split into the images of its selected pages
+	pages, pdfIndex, err := h.prepareDocs(&req)
+	if err != nil {
+		// Track rejected image
+		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+			UserID:      email,
+			Email:       email,
+			ServiceName: "signature-verification",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			Success:     false,
+			ErrorMsg:    "image rejected: " + err.Error(),
+			CreditsUsed: 0,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		})
+
+		utils.SendErrorResponse(w, err)
 		return
 	}
 
@@ -186,6 +217,85 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 		}
 	}
 
+	// A repeated req_id is answered with the stored result, without processing or charging again
+	claim, previous, err := claimProcessingResult(ctx, h.resultService, user.UserID, "signature-verification", req.ReqID, &req)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	// A repeated PDF is answered with its stored per-page result
+	if previous != nil && pages != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.DocumentResult, remainingCredits int) {
+			sendDocumentResult(w, isAPIKeyAuth, user.UserID, "Signature verification completed", result, remainingCredits)
+		})
+		return
+	}
+	if previous != nil {
+		replayProcessingResult(ctx, w, h.creditsService, user.UserID, previous, func(result *models.SignatureVerificationResult, remainingCredits int) {
+			if !result.Success {
+				h.sendFailure(w, isAPIKeyAuth, result)
+				return
+			}
+			h.sendResult(w, isAPIKeyAuth, user.UserID, result, remainingCredits)
+		})
+		return
+	}
+	defer claim.release()
+
+	// A PDF is processed page by page, each page charged like a single image
+	if pages != nil {
+		extendWriteDeadline(w, r)
+		document, remainingCredits, err := processDocumentPages(r.Context(), h.creditsService, user.UserID, req.ReqID, 2, 60*time.Second, pages, func(ctx context.Context, page *models.DocumentPage) (interface{}, bool, string, error) {
+			pageReq := req
+			pageReq.Docs = append([]*models.Upload(nil), req.Docs...)
+			pageReq.Docs[pdfIndex] = page.Image
+			result, err := h.signatureAPIService.ProcessSignatureVerification(ctx, &pageReq)
+			if err != nil || result == nil {
+				return nil, false, "", err
+			}
+			return result, result.Success, result.Message, nil
+		})
+		if err != nil {
+			// Track balance check failure
+			h.trackUsage(r.Context(), &models.UsageTrackingRequest{
+				UserID:      user.UserID,
+				Email:       email,
+				ServiceName: "signature-verification",
+				Endpoint:    r.URL.Path,
+				Method:      r.Method,
+				Success:     false,
+				ErrorMsg:    err.Error(),
+				CreditsUsed: 0,
+				IPAddress:   h.getClientIP(r),
+				UserAgent:   r.UserAgent(),
+				AuthMethod:  h.getAuthMethod(r),
+				ProcessTime: time.Since(startTime).Milliseconds(),
+			})
+
+			utils.SendErrorResponse(w, err)
+			return
+		}
+
+		if document.CreditsUsed > 0 {
+			claim.complete(document, document.CreditsUsed)
+		}
+
+		trackDocumentUsage(r.Context(), h.trackUsage, models.UsageTrackingRequest{
+			UserID:      user.UserID,
+			Email:       email,
+			ServiceName: "signature-verification",
+			Endpoint:    r.URL.Path,
+			Method:      r.Method,
+			IPAddress:   h.getClientIP(r),
+			UserAgent:   r.UserAgent(),
+			AuthMethod:  h.getAuthMethod(r),
+			ProcessTime: time.Since(startTime).Milliseconds(),
+		}, document)
+
+		sendDocumentResult(w, isAPIKeyAuth, user.UserID, "Signature verification completed", document, remainingCredits)
+		return
+	}
+
 	// Check user's credit balance before processing
 	balance, err := h.creditsService.GetBalance(ctx, user.UserID)
 	if err != nil {
@@ -266,12 +376,18 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 
 	// Check if the API returned success
 	if verificationResult == nil || !verificationResult.Success {
+		if verificationResult == nil {
+			verificationResult = &models.SignatureVerificationResult{ReqID: req.ReqID, Message: "empty response from the processing service"}
+		}
+
 		// Still deduct credits for API usage even when verification fails
 		deductReq := &models.DeductCreditsRequest{
 			UserID: user.UserID,
 			Amount: 2,
 		}
 		h.creditsService.DeductCredits(ctx, deductReq)
+		// The call is charged, so a retry with the same req_id gets this answer back
+		claim.complete(verificationResult, 2)
 
 		// Track API failure (but still consider it a "successful" call since API responded)
 		h.trackUsage(r.Context(), &models.UsageTrackingRequest{
@@ -289,25 +405,7 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 			ProcessTime: time.Since(startTime).Milliseconds(),
 		})
 
-		// Create original response structure
-		originalResponse := struct {
-			ReqID        string                 `json:"req_id"`
-			Success      bool                   `json:"success"`
-			ErrorMessage string                 `json:"error_message"`
-			Data         map[string]interface{} `json:"data"`
-		}{
-			ReqID:        verificationResult.ReqID,
-			Success:      verificationResult.Success,
-			ErrorMessage: verificationResult.Message,
-			Data:         map[string]interface{}{},
-		}
-
-		if isAPIKeyAuth {
-			utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
-		} else {
-			apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, verificationResult.Message, originalResponse)
-			utils.SendErrorResponse(w, apiError)
-		}
+		h.sendFailure(w, isAPIKeyAuth, verificationResult)
 		return
 	}
 
@@ -343,6 +441,8 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 		return
 	}
 
+	claim.complete(verificationResult, 2)
+
 	// Track successful operation
 	h.trackUsage(r.Context(), &models.UsageTrackingRequest{
 		UserID:      user.UserID,
@@ -359,23 +459,90 @@ func (h *SignatureVerificationHandler) ProcessSignatureVerification(w http.Respo
 		ProcessTime: time.Since(startTime).Milliseconds(),
 	})
 
-	// Send different responses based on authentication method
+	h.sendResult(w, isAPIKeyAuth, user.UserID, verificationResult, updatedBalance.Credits)
+}
+
+// prepareDocs checks and normalizes the signatures. When one of them is a PDF, it returns
+// the images of its selected pages and its position among the documents; each page is then
+// compared with the other documents in turn.
+func (h *SignatureVerificationHandler) prepareDocs(req *models.SignatureVerificationRequest) ([]*models.DocumentPage, int, error) {
+	if err := req.PrepareImages(h.imagePrep.PrepareDocument); err != nil {
+		return nil, 0, err
+	}
+
+	pdfIndex := -1
+	for i, doc := range req.Docs {
+		if !doc.IsPDF() {
+			continue
+		}
+		if pdfIndex >= 0 {
+			return nil, 0, apperrors.NewAppError(
+				apperrors.ErrValidation,
+				http.StatusBadRequest,
+				"validation failed: only one of the documents may be a PDF",
+			)
+		}
+		pdfIndex = i
+	}
+	if pdfIndex < 0 {
+		return nil, 0, nil
+	}
+
+	pages, err := h.imagePrep.ExtractPages(req.Docs[pdfIndex], req.Pages)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pages, pdfIndex, nil
+}
+
+// sendResult sends the result the way the caller authenticated: API key callers get the bare
+// result, frontend callers get it with their remaining credits
+func (h *SignatureVerificationHandler) sendResult(w http.ResponseWriter, isAPIKeyAuth bool, userID string, result *models.SignatureVerificationResult, remainingCredits int) {
 	if isAPIKeyAuth {
-		utils.SendJSONResponse(w, http.StatusOK, verificationResult)
+		utils.SendJSONResponse(w, http.StatusOK, result)
 	} else {
 		response := &models.SignatureVerificationResponse{
 			Message:            "Signature verification completed successfully",
-			UserID:             user.UserID,
-			RemainingCredits:   updatedBalance.Credits,
-			VerificationResult: verificationResult,
+			UserID:             userID,
+			RemainingCredits:   remainingCredits,
+			VerificationResult: result,
 			ProcessedAt:        time.Now(),
 		}
 		utils.SendJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// sendFailure answers a call the upstream service rejected: API key callers get the
+// upstream response, frontend callers a mapped error
+func (h *SignatureVerificationHandler) sendFailure(w http.ResponseWriter, isAPIKeyAuth bool, result *models.SignatureVerificationResult) {
+	// Create original response structure
+	originalResponse := struct {
+		ReqID        string                 `json:"req_id"`
+		Success      bool                   `json:"success"`
+		ErrorMessage string                 `json:"error_message"`
+		Data         map[string]interface{} `json:"data"`
+	}{
+		ReqID:        result.ReqID,
+		Success:      result.Success,
+		ErrorMessage: result.Message,
+		Data:         map[string]interface{}{},
+	}
+
+	if isAPIKeyAuth {
+		utils.SendJSONResponse(w, http.StatusBadRequest, originalResponse)
+	} else {
+		apiError := apperrors.NewAPIErrorWithOriginalResponse(h.errorMapper, result.Message, originalResponse)
+		utils.SendErrorResponse(w, apiError)
+	}
+}
+
 // Helper methods for the SignatureVerificationHandler
 func (h *SignatureVerificationHandler) trackUsage(ctx context.Context, req *models.UsageTrackingRequest) {
+	// Record which key made the call so users can break their usage down per key
+	if apiKey, ok := middleware.GetAPIKeyFromContext(ctx); ok && apiKey != nil {
+		req.APIKeyID = apiKey.ID.Hex()
+	}
+
 	// Track usage asynchronously to not block the response
 	go func() {
 		trackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
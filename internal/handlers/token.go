@@ -8,6 +8,7 @@ import (
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/services"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 	"chi-mongo-backend/pkg/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -17,13 +18,15 @@ type TokenHandler struct {
 	tokenService   services.CreditTokenService
 	creditsService services.CreditsService
 	userService    services.UserService
+	auditService   services.AdminAuditService
 }
 
-func NewTokenHandler(tokenService services.CreditTokenService, creditsService services.CreditsService, userService services.UserService) *TokenHandler {
+func NewTokenHandler(tokenService services.CreditTokenService, creditsService services.CreditsService, userService services.UserService, auditService services.AdminAuditService) *TokenHandler {
 	return &TokenHandler{
 		tokenService:   tokenService,
 		creditsService: creditsService,
 		userService:    userService,
+		auditService:   auditService,
 	}
 }
 
@@ -61,6 +64,12 @@ func (h *TokenHandler) GenerateToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAdminAction(r, h.auditService, models.AdminActionTokenGenerate, models.AuditTargetToken, response.ID, nil, map[string]interface{}{
+		"credits":     response.Credits,
+		"expiresAt":   response.ExpiresAt,
+		"description": response.Description,
+	})
+
 	utils.SendJSONResponse(w, http.StatusCreated, response)
 }
 
@@ -149,15 +158,22 @@ func (h *TokenHandler) GetMyTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.tokenService.GetTokensByCreatedBy(r.Context(), email)
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	tokens, info, err := h.tokenService.GetTokensByCreatedBy(r.Context(), email, page)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"message": "Tokens retrieved successfully",
-		"tokens":  tokens,
+		"message":    "Tokens retrieved successfully",
+		"tokens":     tokens,
+		"pagination": info,
 	})
 }
 
@@ -173,16 +189,23 @@ func (h *TokenHandler) GetAllTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.tokenService.GetAllTokens(r.Context())
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	tokens, info, err := h.tokenService.GetAllTokens(r.Context(), page)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"message": "All tokens retrieved successfully",
-		"count":   len(tokens),
-		"tokens":  tokens,
+		"message":    "All tokens retrieved successfully",
+		"count":      len(tokens),
+		"tokens":     tokens,
+		"pagination": info,
 	})
 }
 
@@ -198,16 +221,23 @@ func (h *TokenHandler) GetUsedTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.tokenService.GetTokensByStatus(r.Context(), true) // true = used tokens
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	tokens, info, err := h.tokenService.GetTokensByStatus(r.Context(), true, page) // true = used tokens
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"message": "Used tokens retrieved successfully",
-		"count":   len(tokens),
-		"tokens":  tokens,
+		"message":    "Used tokens retrieved successfully",
+		"count":      len(tokens),
+		"tokens":     tokens,
+		"pagination": info,
 	})
 }
 
@@ -223,30 +253,85 @@ func (h *TokenHandler) GetUnusedTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.tokenService.GetTokensByStatus(r.Context(), false) // false = unused tokens
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	tokens, info, err := h.tokenService.GetTokensByStatus(r.Context(), false, page) // false = unused tokens
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"message": "Unused tokens retrieved successfully",
-		"count":   len(tokens),
-		"tokens":  tokens,
+		"message":    "Unused tokens retrieved successfully",
+		"count":      len(tokens),
+		"tokens":     tokens,
+		"pagination": info,
 	})
 }
 
+// DeleteToken - Admin only: Soft-revoke a token. Kept for existing clients; the reason
+// is taken from the "reason" query parameter.
 func (h *TokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
-	// Check if user is admin
-	if !middleware.IsAdminFromContext(r.Context()) {
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "deleted by admin"
+	}
+	h.revokeToken(w, r, &models.RevokeTokenRequest{Reason: reason})
+}
+
+// RevokeToken - Admin only: Soft-revoke a token with a reason
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RevokeTokenRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	h.revokeToken(w, r, &req)
+}
+
+func (h *TokenHandler) revokeToken(w http.ResponseWriter, r *http.Request, req *models.RevokeTokenRequest) {
+	// Get admin email from context
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
 		utils.SendErrorResponse(w, apperrors.NewAppError(
-			apperrors.ErrForbidden,
-			http.StatusForbidden,
-			"admin access required to delete tokens",
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	// Get tokenId from URL path parameter
+	tokenID := chi.URLParam(r, "tokenId")
+	if tokenID == "" {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrBadRequest,
+			http.StatusBadRequest,
+			"token ID is required",
 		))
 		return
 	}
 
+	response, err := h.tokenService.RevokeToken(r.Context(), tokenID, req, email, middleware.IsSuperAdminFromContext(r.Context()))
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	recordAdminAction(r, h.auditService, models.AdminActionTokenRevoke, models.AuditTargetToken, tokenID,
+		map[string]interface{}{"isRevoked": false},
+		map[string]interface{}{"isRevoked": true, "reason": req.Reason},
+	)
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// TransferToken - Admin only: Transfer token ownership to another admin
+func (h *TokenHandler) TransferToken(w http.ResponseWriter, r *http.Request) {
 	// Get admin email from context
 	email, ok := middleware.GetEmailFromContext(r.Context())
 	if !ok {
@@ -258,7 +343,6 @@ func (h *TokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get tokenId from URL path parameter
 	tokenID := chi.URLParam(r, "tokenId")
 	if tokenID == "" {
 		utils.SendErrorResponse(w, apperrors.NewAppError(
@@ -269,7 +353,100 @@ func (h *TokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.tokenService.DeleteToken(r.Context(), tokenID, email)
+	var req models.TransferTokenRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.tokenService.TransferToken(r.Context(), tokenID, &req, email, middleware.IsSuperAdminFromContext(r.Context()))
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	recordAdminAction(r, h.auditService, models.AdminActionTokenTransfer, models.AuditTargetToken, tokenID, nil, map[string]interface{}{
+		"createdBy": req.ToAdmin,
+		"reason":    req.Reason,
+	})
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetTokenAudit - Admin only: Lifecycle audit trail of a token
+func (h *TokenHandler) GetTokenAudit(w http.ResponseWriter, r *http.Request) {
+	tokenID := chi.URLParam(r, "tokenId")
+	if tokenID == "" {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrBadRequest,
+			http.StatusBadRequest,
+			"token ID is required",
+		))
+		return
+	}
+
+	response, err := h.tokenService.GetTokenAudit(r.Context(), tokenID)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// CreatePromoCode - Admin only: Create a multi-use promo code
+func (h *TokenHandler) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	// Get admin email from context
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		))
+		return
+	}
+
+	var req models.CreatePromoCodeRequest
+	if err := utils.DecodeJSONBody(r, &req); err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.tokenService.CreatePromoCode(r.Context(), &req, email)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	recordAdminAction(r, h.auditService, models.AdminActionPromoCreate, models.AuditTargetToken, response.ID, nil, map[string]interface{}{
+		"credits":     response.Credits,
+		"expiresAt":   response.ExpiresAt,
+		"description": response.Description,
+	})
+
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+// GetTokenRedemptions - Admin only: List all redemptions of a token
+func (h *TokenHandler) GetTokenRedemptions(w http.ResponseWriter, r *http.Request) {
+	tokenID := chi.URLParam(r, "tokenId")
+	if tokenID == "" {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrBadRequest,
+			http.StatusBadRequest,
+			"token ID is required",
+		))
+		return
+	}
+
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	response, err := h.tokenService.GetTokenRedemptions(r.Context(), tokenID, page)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
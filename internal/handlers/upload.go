@@ -0,0 +1,165 @@
+// internal/handlers/upload.go
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+const (
+	// maxMultipartMemory is how much of a multipart body is held in memory; the rest of
+	// the files spill to temporary files
+	maxMultipartMemory = 32 << 20
+	// maxUploadFileSize matches the 10MB limit on base64 images
+	maxUploadFileSize = 10 << 20
+)
+
+// uploadForm gives a processing handler the fields and files of a multipart/form-data request
+type uploadForm struct {
+	form *multipart.Form
+}
+
+// Value returns the first value of a text field, or "" when it is missing
+func (f *uploadForm) Value(name string) string {
+	if values := f.form.Value[name]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// File returns the first file sent under name, or nil when there is none
+func (f *uploadForm) File(name string) (*models.Upload, error) {
+	files, err := f.Files(name)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// Files returns every file sent under name, in order
+func (f *uploadForm) Files(name string) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+	for _, header := range f.form.File[name] {
+		if header.Size > maxUploadFileSize {
+			return nil, apperrors.NewAppError(
+				apperrors.ErrValidation,
+				http.StatusBadRequest,
+				fmt.Sprintf("file %s is too large (max 10MB)", header.Filename),
+			)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, apperrors.NewAppError(apperrors.ErrBadRequest, http.StatusBadRequest, "failed to read uploaded file")
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, apperrors.NewAppError(apperrors.ErrBadRequest, http.StatusBadRequest, "failed to read uploaded file")
+		}
+
+		contentType := header.Header.Get("Content-Type")
+		if contentType == "" || contentType == "application/octet-stream" {
+			contentType = http.DetectContentType(data)
+		}
+		uploads = append(uploads, &models.Upload{
+			Filename:    header.Filename,
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+	return uploads, nil
+}
+
+// decodeProcessingRequest reads a processing request sent either as JSON into dst or as
+// multipart/form-data, whose fields and files bind copies into the request
+func decodeProcessingRequest(r *http.Request, dst interface{}, bind func(form *uploadForm) error) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return utils.DecodeJSONBody(r, dst)
+	}
+
+	if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+		if limit, ok := apperrors.IsPayloadTooLarge(err); ok {
+			return apperrors.NewPayloadTooLargeError(limit)
+		}
+		return apperrors.NewAppError(apperrors.ErrBadRequest, http.StatusBadRequest, "invalid multipart form")
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	return bind(&uploadForm{form: r.MultipartForm})
+}
+
+// wantsImage reports whether the Accept header prefers an image over JSON. A wildcard
+// "*/*" does not count, so existing clients keep getting JSON.
+func wantsImage(r *http.Request) bool {
+	imageQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(mediaType, "image/"):
+			imageQ = max(imageQ, q)
+		case mediaType == "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return imageQ > 0 && imageQ > jsonQ
+}
+
+// imageHeaders describe a binary image response; frontend callers also get their balance
+func imageHeaders(reqID string, isAPIKeyAuth bool, remainingCredits int) map[string]string {
+	headers := map[string]string{"X-Req-ID": reqID}
+	if !isAPIKeyAuth {
+		headers["X-Remaining-Credits"] = strconv.Itoa(remainingCredits)
+	}
+	return headers
+}
+
+// sendImage writes a base64 image from an upstream result as binary. It returns false,
+// having written nothing, when the data is not an image, so the caller can send JSON.
+func sendImage(w http.ResponseWriter, encoded string, headers map[string]string) bool {
+	// Some upstreams return data URIs
+	if strings.HasPrefix(encoded, "data:") {
+		if i := strings.Index(encoded, ","); i >= 0 {
+			encoded = encoded[i+1:]
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return false
+	}
+
+	for name, value := range headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+	return true
+}
@@ -6,7 +6,10 @@ import (
 	"strconv"
 	"time"
 
+	"chi-mongo-backend/internal/middleware"
+	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/services"
+	"chi-mongo-backend/pkg/pagination"
 	"chi-mongo-backend/pkg/utils"
 	apperrors "chi-mongo-backend/pkg/errors"
 	"github.com/go-chi/chi/v5"
@@ -14,11 +17,15 @@ import (
 
 type UsageHandler struct {
 	usageService services.UsageService
+	userService  services.UserService
+	auditService services.AdminAuditService
 }
 
-func NewUsageHandler(usageService services.UsageService) *UsageHandler {
+func NewUsageHandler(usageService services.UsageService, userService services.UserService, auditService services.AdminAuditService) *UsageHandler {
 	return &UsageHandler{
 		usageService: usageService,
+		userService:  userService,
+		auditService: auditService,
 	}
 }
 
@@ -105,22 +112,14 @@ func (h *UsageHandler) GetUserUsageHistory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Parse pagination parameters
-	limit := h.parseIntQuery(r, "limit", 50)   // Default 50
-	skip := h.parseIntQuery(r, "skip", 0)      // Default 0
-
-	// Validate pagination parameters
-	if limit > 1000 {
-		limit = 1000 // Cap at 1000 records
-	}
-	if limit < 1 {
-		limit = 50
-	}
-	if skip < 0 {
-		skip = 0
+	// Parse pagination parameters (limit defaults to 50, capped at 1000)
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
 	}
 
-	usage, err := h.usageService.GetUserUsageHistory(r.Context(), userID, limit, skip)
+	usage, info, err := h.usageService.GetUserUsageHistory(r.Context(), userID, page)
 	if err != nil {
 		utils.SendErrorResponse(w, apperrors.NewAppError(
 			apperrors.ErrInternalServer,
@@ -130,14 +129,13 @@ func (h *UsageHandler) GetUserUsageHistory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	recordAdminAction(r, h.auditService, models.AdminActionUserUsage, models.AuditTargetUser, userID, nil, nil)
+
 	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"user_id": userID,
 		"usage_history": usage,
 		"total_records": len(usage),
-		"pagination": map[string]interface{}{
-			"limit": limit,
-			"skip": skip,
-		},
+		"pagination": info,
 	})
 }
 
@@ -153,22 +151,14 @@ func (h *UsageHandler) GetServiceUsageHistory(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Parse pagination parameters
-	limit := h.parseIntQuery(r, "limit", 50)   // Default 50
-	skip := h.parseIntQuery(r, "skip", 0)      // Default 0
-
-	// Validate pagination parameters
-	if limit > 1000 {
-		limit = 1000 // Cap at 1000 records
-	}
-	if limit < 1 {
-		limit = 50
-	}
-	if skip < 0 {
-		skip = 0
+	// Parse pagination parameters (limit defaults to 50, capped at 1000)
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
 	}
 
-	usage, err := h.usageService.GetServiceUsageHistory(r.Context(), serviceName, limit, skip)
+	usage, info, err := h.usageService.GetServiceUsageHistory(r.Context(), serviceName, page)
 	if err != nil {
 		utils.SendErrorResponse(w, apperrors.NewAppError(
 			apperrors.ErrInternalServer,
@@ -182,13 +172,201 @@ func (h *UsageHandler) GetServiceUsageHistory(w http.ResponseWriter, r *http.Req
 		"service_name": serviceName,
 		"usage_history": usage,
 		"total_records": len(usage),
-		"pagination": map[string]interface{}{
-			"limit": limit,
-			"skip": skip,
+		"pagination": info,
+	})
+}
+
+// GetServicePerformance returns per-service latency percentiles and failures by error code
+// GET /api/v1/admin/usage/performance?service=&start_date=&end_date=
+func (h *UsageHandler) GetServicePerformance(w http.ResponseWriter, r *http.Request) {
+	serviceName := r.URL.Query().Get("service")
+	startDate, endDate := h.parseDateRange(r)
+
+	stats, err := h.usageService.GetServicePerformance(r.Context(), serviceName, startDate, endDate)
+	if err != nil {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrInternalServer,
+			http.StatusInternalServerError,
+			"failed to get service performance stats: "+err.Error(),
+		))
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"stats": stats,
+		"service": serviceName,
+		"total_services": len(stats),
+		"date_range": map[string]interface{}{
+			"start_date": startDate,
+			"end_date": endDate,
 		},
 	})
 }
 
+// GetTimeSeries returns bucketed usage for charts
+// GET /api/v1/admin/usage/timeseries?interval=hour|day|week&service=&user=&start_date=&end_date=
+func (h *UsageHandler) GetTimeSeries(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate := h.parseDateRange(r)
+	filter := &models.UsageTimeSeriesFilter{
+		UsageFilter: models.UsageFilter{
+			ServiceName: r.URL.Query().Get("service"),
+			UserID:      r.URL.Query().Get("user"),
+			StartDate:   startDate,
+			EndDate:     endDate,
+		},
+		Interval: r.URL.Query().Get("interval"),
+	}
+
+	points, err := h.usageService.GetTimeSeries(r.Context(), filter)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"interval": filter.Interval,
+		"service": filter.ServiceName,
+		"user": filter.UserID,
+		"points": points,
+		"total_points": len(points),
+		"date_range": map[string]interface{}{
+			"start_date": filter.StartDate,
+			"end_date": filter.EndDate,
+		},
+	})
+}
+
+// GetMyUsage returns the caller's own usage totals broken down per service
+// GET /api/v1/usage/me?service=&api_key_id=&success=true|false&start_date=&end_date=
+func (h *UsageHandler) GetMyUsage(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.myUsageFilter(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	summary, err := h.usageService.GetUsageSummary(r.Context(), filter)
+	if err != nil {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrInternalServer,
+			http.StatusInternalServerError,
+			"failed to get usage summary: "+err.Error(),
+		))
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"user_id": filter.UserID,
+		"summary": summary,
+		"filters": myUsageFilters(filter),
+	})
+}
+
+// GetMyUsageHistory returns the caller's own usage records, newest first
+// GET /api/v1/usage/me/history?service=&api_key_id=&success=&start_date=&end_date=&limit=50&cursor=
+func (h *UsageHandler) GetMyUsageHistory(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.myUsageFilter(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	usage, info, err := h.usageService.GetUsageHistory(r.Context(), filter, page)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"user_id":       filter.UserID,
+		"usage_history": usage,
+		"total_records": len(usage),
+		"filters":       myUsageFilters(filter),
+		"pagination":    info,
+	})
+}
+
+// GetMyTimeSeries returns the caller's own usage bucketed for charts
+// GET /api/v1/usage/me/timeseries?interval=hour|day|week&service=&api_key_id=&success=&start_date=&end_date=
+func (h *UsageHandler) GetMyTimeSeries(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.myUsageFilter(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	seriesFilter := &models.UsageTimeSeriesFilter{
+		UsageFilter: *filter,
+		Interval:    r.URL.Query().Get("interval"),
+	}
+
+	points, err := h.usageService.GetTimeSeries(r.Context(), seriesFilter)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"user_id":      seriesFilter.UserID,
+		"interval":     seriesFilter.Interval,
+		"filters":      myUsageFilters(&seriesFilter.UsageFilter),
+		"points":       points,
+		"total_points": len(points),
+	})
+}
+
+// myUsageFilter builds a usage filter from the query string that is always scoped to the
+// authenticated caller, so users can never read someone else's usage
+func (h *UsageHandler) myUsageFilter(r *http.Request) (*models.UsageFilter, error) {
+	email, ok := middleware.GetEmailFromContext(r.Context())
+	if !ok {
+		return nil, apperrors.NewAppError(
+			apperrors.ErrUnauthorized,
+			http.StatusUnauthorized,
+			"email not found in context",
+		)
+	}
+
+	user, err := h.userService.GetOrCreateUser(r.Context(), email)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate := h.parseDateRange(r)
+	filter := &models.UsageFilter{
+		UserID:      user.UserID,
+		ServiceName: r.URL.Query().Get("service"),
+		APIKeyID:    r.URL.Query().Get("api_key_id"),
+		StartDate:   startDate,
+		EndDate:     endDate,
+	}
+
+	success, err := h.parseBoolQuery(r, "success")
+	if err != nil {
+		return nil, err
+	}
+	filter.Success = success
+
+	return filter, nil
+}
+
+// myUsageFilters echoes the applied filters back in the response
+func myUsageFilters(filter *models.UsageFilter) map[string]interface{} {
+	return map[string]interface{}{
+		"service":    filter.ServiceName,
+		"api_key_id": filter.APIKeyID,
+		"success":    filter.Success,
+		"start_date": filter.StartDate,
+		"end_date":   filter.EndDate,
+	}
+}
+
 // Helper method to parse date range from query parameters
 func (h *UsageHandler) parseDateRange(r *http.Request) (*time.Time, *time.Time) {
 	var startDate, endDate *time.Time
@@ -210,12 +388,19 @@ func (h *UsageHandler) parseDateRange(r *http.Request) (*time.Time, *time.Time)
 	return startDate, endDate
 }
 
-// Helper method to parse integer query parameters with default values
-func (h *UsageHandler) parseIntQuery(r *http.Request, key string, defaultValue int) int {
-	if str := r.URL.Query().Get(key); str != "" {
-		if val, err := strconv.Atoi(str); err == nil {
-			return val
-		}
+// Helper method to parse an optional boolean query parameter; nil means not set
+func (h *UsageHandler) parseBoolQuery(r *http.Request, key string) (*bool, error) {
+	str := r.URL.Query().Get(key)
+	if str == "" {
+		return nil, nil
 	}
-	return defaultValue
-}
\ No newline at end of file
+	val, err := strconv.ParseBool(str)
+	if err != nil {
+		return nil, apperrors.NewAppError(
+			apperrors.ErrValidation,
+			http.StatusBadRequest,
+			key+" must be true or false",
+		)
+	}
+	return &val, nil
+}
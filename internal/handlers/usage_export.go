@@ -0,0 +1,250 @@
+// internal/handlers/usage_export.go
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+const (
+	usageExportCSV    = "csv"
+	usageExportNDJSON = "ndjson"
+
+	// usageExportFlushEvery is how many records are buffered before the response is flushed
+	usageExportFlushEvery = 1000
+
+	// Exports are mounted outside the request timeout and outlive the server's write
+	// timeout: the query gets its own deadline and every flush pushes the write deadline
+	// forward
+	usageExportTimeout       = 30 * time.Minute
+	usageExportWriteDeadline = 30 * time.Second
+)
+
+var usageCSVHeader = []string{
+	"id", "created_at", "user_id", "email", "service_name", "endpoint", "method", "success",
+	"error_code", "error_msg", "credits_used", "process_time_ms", "auth_method", "api_key_id",
+	"request_id", "ip_address", "user_agent",
+}
+
+// ExportUsage - Admin only: Stream raw usage records as CSV or NDJSON, oldest first
+// GET /api/v1/admin/usage/export?format=csv|ndjson&gzip=true&service=&user=&success=&start_date=&end_date=
+func (h *UsageHandler) ExportUsage(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = usageExportCSV
+	}
+	if format != usageExportCSV && format != usageExportNDJSON {
+		utils.SendErrorResponse(w, apperrors.NewAppError(
+			apperrors.ErrValidation,
+			http.StatusBadRequest,
+			"format must be csv or ndjson",
+		))
+		return
+	}
+
+	compress, err := h.parseBoolQuery(r, "gzip")
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	success, err := h.parseBoolQuery(r, "success")
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	startDate, endDate := h.parseDateRange(r)
+	filter := &models.UsageFilter{
+		UserID:      r.URL.Query().Get("user"),
+		ServiceName: r.URL.Query().Get("service"),
+		Success:     success,
+		StartDate:   startDate,
+		EndDate:     endDate,
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), usageExportTimeout)
+	defer cancel()
+
+	exporter := newUsageExporter(w, format, compress != nil && *compress)
+
+	err = h.usageService.ExportUsage(ctx, filter, exporter.write)
+	if err != nil && !exporter.started {
+		// Nothing has been sent yet, so the client still gets a proper error response
+		utils.SendErrorResponse(w, err)
+		return
+	}
+
+	// Audit whatever was sent, even when the stream was cut short by a disconnected client
+	auditCtx, cancelAudit := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancelAudit()
+	recordAdminAction(r.WithContext(auditCtx), h.auditService, models.AdminActionUsageExport, models.AuditTargetUsage, filter.UserID, nil, map[string]interface{}{
+		"format":  format,
+		"service": filter.ServiceName,
+		"records": exporter.count,
+	})
+
+	if err != nil {
+		// Headers are already out; truncating the stream is the only signal left
+		log.Printf("Usage export aborted after %d records: %v", exporter.count, err)
+		return
+	}
+
+	if err := exporter.close(); err != nil {
+		log.Printf("Error finishing usage export: %v", err)
+	}
+}
+
+// usageExporter writes records straight to the response as they come off the cursor,
+// so memory stays constant however large the export is
+type usageExporter struct {
+	w        http.ResponseWriter
+	format   string
+	compress bool
+
+	started bool
+	count   int
+	gz      *gzip.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+func newUsageExporter(w http.ResponseWriter, format string, compress bool) *usageExporter {
+	return &usageExporter{w: w, format: format, compress: compress}
+}
+
+// start sends the headers; it is deferred until the first record so query errors can
+// still be reported as JSON
+func (e *usageExporter) start() error {
+	e.started = true
+
+	contentType, ext := "text/csv", "csv"
+	if e.format == usageExportNDJSON {
+		contentType, ext = "application/x-ndjson", "ndjson"
+	}
+	filename := fmt.Sprintf("usage-%s.%s", time.Now().UTC().Format("20060102-150405"), ext)
+	if e.compress {
+		contentType = "application/gzip"
+		filename += ".gz"
+	}
+
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	e.extendDeadline()
+	e.w.WriteHeader(http.StatusOK)
+
+	var out io.Writer = e.w
+	if e.compress {
+		e.gz = gzip.NewWriter(e.w)
+		out = e.gz
+	}
+
+	if e.format == usageExportNDJSON {
+		e.json = json.NewEncoder(out)
+		return nil
+	}
+	e.csv = csv.NewWriter(out)
+	return e.csv.Write(usageCSVHeader)
+}
+
+func (e *usageExporter) write(usage *models.ServiceUsage) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if e.json != nil {
+		err = e.json.Encode(usage)
+	} else {
+		err = e.csv.Write(usageCSVRecord(usage))
+	}
+	if err != nil {
+		return err
+	}
+
+	e.count++
+	if e.count%usageExportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *usageExporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if e.gz != nil {
+		if err := e.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	e.extendDeadline()
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// extendDeadline keeps the server's WriteTimeout from cutting off a long export
+func (e *usageExporter) extendDeadline() {
+	rc := http.NewResponseController(e.w)
+	if err := rc.SetWriteDeadline(time.Now().Add(usageExportWriteDeadline)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend usage export write deadline: %v", err)
+	}
+}
+
+// close finishes the stream; an empty export still gets headers and the CSV header row
+func (e *usageExporter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	if e.gz != nil {
+		return e.gz.Close()
+	}
+	return nil
+}
+
+func usageCSVRecord(usage *models.ServiceUsage) []string {
+	return []string{
+		usage.ID.Hex(),
+		usage.CreatedAt.UTC().Format(time.RFC3339Nano),
+		usage.UserID,
+		usage.Email,
+		usage.ServiceName,
+		usage.Endpoint,
+		usage.Method,
+		strconv.FormatBool(usage.Success),
+		usage.ErrorCode,
+		usage.ErrorMsg,
+		strconv.Itoa(usage.CreditsUsed),
+		strconv.FormatInt(usage.ProcessTime, 10),
+		usage.AuthMethod,
+		usage.APIKeyID,
+		usage.RequestID,
+		usage.IPAddress,
+		usage.UserAgent,
+	}
+}
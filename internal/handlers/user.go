@@ -3,23 +3,29 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"chi-mongo-backend/internal/middleware"
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/services"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 	"chi-mongo-backend/pkg/utils"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type UserHandler struct {
-	userService services.UserService
+	userService  services.UserService
+	auditService services.AdminAuditService
 }
 
-func NewUserHandler(userService services.UserService) *UserHandler {
+func NewUserHandler(userService services.UserService, auditService services.AdminAuditService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:  userService,
+		auditService: auditService,
 	}
 }
 
@@ -40,6 +46,10 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // Admin-only methods
+
+// GetAllUsers lists users with optional search filters and sorting
+// GET /api/v1/admin/users?email_prefix=&email=&min_credits=&max_credits=&created_from=&created_to=
+//     &has_api_key=true|false&active_since=&sort=created_at|credits|usage&order=asc|desc&limit=&cursor=
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	// Check if user is admin
 	if !middleware.IsAdminFromContext(r.Context()) {
@@ -50,7 +60,17 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		))
 		return
 	}
-	response, err := h.userService.GetAllUsers(r.Context())
+	page, err := pagination.ParseParams(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	filter, err := parseAdminUserFilter(r)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	response, err := h.userService.GetAllUsers(r.Context(), filter, page)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
@@ -82,6 +102,7 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		utils.SendErrorResponse(w, err)
 		return
 	}
+	recordAdminAction(r, h.auditService, models.AdminActionUserView, models.AuditTargetUser, userID, nil, nil)
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
@@ -122,11 +143,17 @@ func (h *UserHandler) GetUserActivity(w http.ResponseWriter, r *http.Request) {
 		))
 		return
 	}
-	response, err := h.userService.GetUserActivity(r.Context(), userID)
+	page, err := pagination.ParseParams(r)
 	if err != nil {
 		utils.SendErrorResponse(w, err)
 		return
 	}
+	response, err := h.userService.GetUserActivity(r.Context(), userID, page)
+	if err != nil {
+		utils.SendErrorResponse(w, err)
+		return
+	}
+	recordAdminAction(r, h.auditService, models.AdminActionUserActivity, models.AuditTargetUser, userID, nil, nil)
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
@@ -154,5 +181,78 @@ func (h *UserHandler) GetUserCredits(w http.ResponseWriter, r *http.Request) {
 		utils.SendErrorResponse(w, err)
 		return
 	}
+	recordAdminAction(r, h.auditService, models.AdminActionUserCredits, models.AuditTargetUser, userID, nil, nil)
 	utils.SendJSONResponse(w, http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// parseAdminUserFilter reads the user search parameters. Dates are YYYY-MM-DD (or RFC 3339);
+// created_to includes the whole day.
+func parseAdminUserFilter(r *http.Request) (*models.AdminUserFilter, error) {
+	query := r.URL.Query()
+	filter := &models.AdminUserFilter{
+		EmailPrefix:   strings.TrimSpace(query.Get("email_prefix")),
+		EmailContains: strings.TrimSpace(query.Get("email")),
+		SortBy:        query.Get("sort"),
+	}
+
+	invalid := func(key, expected string) error {
+		return apperrors.NewAppError(
+			apperrors.ErrValidation,
+			http.StatusBadRequest,
+			key+" must be "+expected,
+		)
+	}
+
+	for key, target := range map[string]**int{"min_credits": &filter.MinCredits, "max_credits": &filter.MaxCredits} {
+		if str := query.Get(key); str != "" {
+			val, err := strconv.Atoi(str)
+			if err != nil {
+				return nil, invalid(key, "an integer")
+			}
+			*target = &val
+		}
+	}
+
+	for key, target := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo, "active_since": &filter.ActiveSince} {
+		if str := query.Get(key); str != "" {
+			val, err := parseQueryTime(str, key == "created_to")
+			if err != nil {
+				return nil, invalid(key, "a date (YYYY-MM-DD) or RFC 3339 time")
+			}
+			*target = &val
+		}
+	}
+
+	if str := query.Get("has_api_key"); str != "" {
+		val, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, invalid("has_api_key", "true or false")
+		}
+		filter.HasAPIKey = &val
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return nil, invalid("order", "asc or desc")
+	}
+
+	return filter, nil
+}
+
+// parseQueryTime accepts a date or an RFC 3339 time; endOfDay moves a bare date to its last second
+func parseQueryTime(str string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", str)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,22 @@
+// internal/middleware/admin_directory.go
+package middleware
+
+import (
+	"net/http"
+
+	"chi-mongo-backend/internal/services"
+)
+
+// TrackAdmins records every authenticated admin in the admin directory. It must run after Auth.
+func TrackAdmins(directory services.AdminDirectoryService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if IsAdminFromContext(r.Context()) {
+				if email, ok := GetEmailFromContext(r.Context()); ok {
+					directory.RecordAdmin(r.Context(), email)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -139,6 +139,7 @@ func AuthOrAPIKey(apiKeyService services.APIKeyService) func(http.Handler) http.
 			// Add user info to context (same as auth.go)
 			ctx := context.WithValue(r.Context(), "email", claims.Email)
 			ctx = context.WithValue(ctx, "isAdmin", isUserAdmin(claims.Roles))
+			ctx = context.WithValue(ctx, "isSuperAdmin", hasRole(claims.Roles, SuperAdminRoleKey))
 			ctx = context.WithValue(ctx, "roles", claims.Roles)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -18,6 +18,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// SuperAdminRoleKey is the Kinde role allowed to override per-admin ownership checks
+const SuperAdminRoleKey = "super-admin"
+
 // Role structure for Kinde roles
 type Role struct {
 	Key  string `json:"key"`
@@ -106,10 +109,12 @@ func Auth() func(http.Handler) http.Handler {
 
 			// Check if user is admin
 			isAdmin := isUserAdmin(claims.Roles)
+			isSuperAdmin := hasRole(claims.Roles, SuperAdminRoleKey)
 
 			// Add email and admin status to request context
 			ctx := context.WithValue(r.Context(), "email", claims.Email)
 			ctx = context.WithValue(ctx, "isAdmin", isAdmin)
+			ctx = context.WithValue(ctx, "isSuperAdmin", isSuperAdmin)
 			ctx = context.WithValue(ctx, "roles", claims.Roles)
 			
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -137,10 +142,15 @@ func AdminOnly() func(http.Handler) http.Handler {
 	}
 }
 
-// isUserAdmin checks if user has admin role
+// isUserAdmin checks if user has admin role. Super-admins are always admins.
 func isUserAdmin(roles []Role) bool {
+	return hasRole(roles, "admin") || hasRole(roles, SuperAdminRoleKey)
+}
+
+// hasRole checks if the roles contain the given role key
+func hasRole(roles []Role, key string) bool {
 	for _, role := range roles {
-		if role.Key == "admin" {
+		if role.Key == key {
 			return true
 		}
 	}
@@ -278,6 +288,12 @@ func IsAdminFromContext(ctx context.Context) bool {
 	return ok && isAdmin
 }
 
+// Helper function to check if user is super-admin from context
+func IsSuperAdminFromContext(ctx context.Context) bool {
+	isSuperAdmin, ok := ctx.Value("isSuperAdmin").(bool)
+	return ok && isSuperAdmin
+}
+
 // Helper function to get roles from context
 func GetRolesFromContext(ctx context.Context) ([]Role, bool) {
 	roles, ok := ctx.Value("roles").([]Role)
@@ -0,0 +1,30 @@
+// internal/middleware/body_limit.go
+package middleware
+
+import (
+	"net/http"
+
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+// BodyLimit caps the request body at maxBytes. A request that declares a larger
+// Content-Length is rejected with 413 before its body is read; otherwise reading past the
+// limit fails, and the decoders report that as 413 too. Each route takes a single limit;
+// stacked limits would each apply, so the smallest would win.
+func BodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				utils.SendErrorResponse(w, apperrors.NewPayloadTooLargeError(maxBytes))
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
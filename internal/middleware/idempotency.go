@@ -0,0 +1,248 @@
+// internal/middleware/idempotency.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/utils"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentResponseSize = 8 << 20 // Stays well under Mongo's 16MB document limit
+)
+
+// replayedHeaders are the response headers stored with a result and sent again on replay
+var replayedHeaders = []string{"Content-Type", "Content-Disposition", "X-Req-ID", "X-Remaining-Credits", "X-Face-Count"}
+
+// Idempotency runs a mutating request sent with an Idempotency-Key header at most once per
+// caller and key. A retry gets the stored response back; the same key with a different
+// method, path or body is rejected. Requests without the header pass straight through.
+// It must run after authentication, because keys are scoped to the caller.
+func Idempotency(idempotencyService services.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				utils.SendErrorResponse(w, apperrors.NewAppError(
+					apperrors.ErrValidation,
+					http.StatusBadRequest,
+					"Idempotency-Key must be at most 255 characters",
+				))
+				return
+			}
+
+			scope, ok := GetEmailFromContext(r.Context())
+			if !ok || scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if limit, ok := apperrors.IsPayloadTooLarge(err); ok {
+				utils.SendErrorResponse(w, apperrors.NewPayloadTooLargeError(limit))
+				return
+			}
+			if err != nil {
+				utils.SendErrorResponse(w, apperrors.NewAppError(
+					apperrors.ErrBadRequest,
+					http.StatusBadRequest,
+					"failed to read request body",
+				))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, err := idempotencyService.Begin(r.Context(), scope, key, requestFingerprint(r, body))
+			if err != nil {
+				utils.SendErrorResponse(w, err)
+				return
+			}
+			if record != nil {
+				replayResponse(w, record)
+				return
+			}
+
+			// Store the outcome even when the client has gone away, so its retry is answered
+			storeCtx := func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() {
+				if p := recover(); p != nil {
+					ctx, cancel := storeCtx()
+					defer cancel()
+					if err := idempotencyService.Release(ctx, scope, key); err != nil {
+						log.Printf("Failed to release Idempotency-Key after panic: %v", err)
+					}
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			ctx, cancel := storeCtx()
+			defer cancel()
+
+			// Server errors are not stored, so the client can retry them
+			if rec.statusCode >= http.StatusInternalServerError {
+				if err := idempotencyService.Release(ctx, scope, key); err != nil {
+					log.Printf("Failed to release Idempotency-Key: %v", err)
+				}
+				return
+			}
+
+			response := &models.IdempotentResponse{
+				StatusCode:  rec.statusCode,
+				Header:      map[string]string{},
+				BodyOmitted: rec.overflow,
+			}
+			if !rec.overflow {
+				response.Body = rec.body.Bytes()
+			}
+			for _, name := range replayedHeaders {
+				if value := w.Header().Get(name); value != "" {
+					response.Header[name] = value
+				}
+			}
+			if err := idempotencyService.Complete(ctx, scope, key, response); err != nil {
+				log.Printf("Failed to store response for Idempotency-Key: %v", err)
+			}
+		})
+	}
+}
+
+// requestFingerprint identifies the request a key was first used for. A multipart body is
+// identified by its fields and files rather than its bytes, because a client retrying an
+// upload picks a new boundary.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	if parts, ok := multipartFingerprint(r.Header.Get("Content-Type"), body); ok {
+		hash.Write(parts)
+	} else {
+		hash.Write(body)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// multipartFingerprint lists each part of a multipart/form-data body as its field name, its
+// kind and the digest of its content, ordered by field name. Parts sharing a name keep
+// their order, which the handlers rely on for lists of files. It reports false for any
+// other body, or one that does not parse, which is then fingerprinted as sent.
+func multipartFingerprint(contentType string, body []byte) ([]byte, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, false
+	}
+
+	type partDigest struct {
+		name   string
+		kind   byte
+		digest [sha256.Size]byte
+	}
+	var parts []partDigest
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		digest := sha256.New()
+		_, err = io.Copy(digest, part)
+		part.Close()
+		if err != nil {
+			return nil, false
+		}
+
+		kind := byte('v')
+		if part.FileName() != "" {
+			kind = 'f'
+		}
+		entry := partDigest{name: part.FormName(), kind: kind}
+		digest.Sum(entry.digest[:0])
+		parts = append(parts, entry)
+	}
+
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].name < parts[j].name })
+
+	var out bytes.Buffer
+	for _, part := range parts {
+		// Length-prefix the name, so names and digests cannot run together
+		fmt.Fprintf(&out, "%d:%s%c", len(part.name), part.name, part.kind)
+		out.Write(part.digest[:])
+	}
+	return out.Bytes(), true
+}
+
+func replayResponse(w http.ResponseWriter, record *models.IdempotencyRecord) {
+	for name, value := range record.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// idempotencyRecorder passes the response through while keeping a copy to store
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.statusCode = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(p []byte) (int, error) {
+	rec.wroteHeader = true
+	if !rec.overflow {
+		if rec.body.Len()+len(p) > maxIdempotentResponseSize {
+			rec.overflow = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,133 @@
+// internal/middleware/idempotency_test.go
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type formPart struct {
+	name     string
+	filename string
+	content  string
+}
+
+// multipartRequest encodes parts with the given boundary
+func multipartRequest(t *testing.T, boundary string, parts ...formPart) (*http.Request, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(boundary); err != nil {
+		t.Fatalf("SetBoundary: %v", err)
+	}
+	for _, part := range parts {
+		if err := writePart(writer, part); err != nil {
+			t.Fatalf("failed to write part %s: %v", part.name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/signature-verification", nil)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r, body.Bytes()
+}
+
+func writePart(writer *multipart.Writer, part formPart) error {
+	if part.filename == "" {
+		return writer.WriteField(part.name, part.content)
+	}
+	w, err := writer.CreateFormFile(part.name, part.filename)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(part.content))
+	return err
+}
+
+func TestRequestFingerprintMultipart(t *testing.T) {
+	fingerprint := func(boundary string, parts ...formPart) string {
+		r, body := multipartRequest(t, boundary, parts...)
+		return requestFingerprint(r, body)
+	}
+
+	original := fingerprint("boundary-one",
+		formPart{name: "req_id", content: "abc"},
+		formPart{name: "docs", filename: "a.png", content: "first image"},
+		formPart{name: "docs", filename: "b.png", content: "second image"},
+	)
+
+	tests := []struct {
+		name  string
+		same  bool
+		parts []formPart
+	}{
+		{
+			name: "retry with a new boundary and field order",
+			same: true,
+			parts: []formPart{
+				{name: "docs", filename: "a.png", content: "first image"},
+				{name: "docs", filename: "b.png", content: "second image"},
+				{name: "req_id", content: "abc"},
+			},
+		},
+		{
+			name: "different field value",
+			parts: []formPart{
+				{name: "req_id", content: "abd"},
+				{name: "docs", filename: "a.png", content: "first image"},
+				{name: "docs", filename: "b.png", content: "second image"},
+			},
+		},
+		{
+			name: "different file content",
+			parts: []formPart{
+				{name: "req_id", content: "abc"},
+				{name: "docs", filename: "a.png", content: "first image"},
+				{name: "docs", filename: "b.png", content: "other image"},
+			},
+		},
+		{
+			name: "files in another order",
+			parts: []formPart{
+				{name: "req_id", content: "abc"},
+				{name: "docs", filename: "b.png", content: "second image"},
+				{name: "docs", filename: "a.png", content: "first image"},
+			},
+		},
+		{
+			name: "field sent as a file",
+			parts: []formPart{
+				{name: "req_id", filename: "req_id.txt", content: "abc"},
+				{name: "docs", filename: "a.png", content: "first image"},
+				{name: "docs", filename: "b.png", content: "second image"},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprint("boundary-"+string(rune('a'+i)), tt.parts...)
+			if (got == original) != tt.same {
+				t.Errorf("fingerprint match = %v, want %v", got == original, tt.same)
+			}
+		})
+	}
+}
+
+func TestRequestFingerprintJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/qr-masking", nil)
+	r.Header.Set("Content-Type", "application/json")
+
+	first := requestFingerprint(r, []byte(`{"req_id":"abc"}`))
+	if first != requestFingerprint(r, []byte(`{"req_id":"abc"}`)) {
+		t.Error("the same JSON body gave different fingerprints")
+	}
+	if first == requestFingerprint(r, []byte(`{"req_id":"abd"}`)) {
+		t.Error("different JSON bodies gave the same fingerprint")
+	}
+}
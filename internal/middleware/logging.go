@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -34,4 +35,8 @@ func Recoverer() func(http.Handler) http.Handler {
 // RealIP gets the real IP from various headers
 func RealIP() func(http.Handler) http.Handler {
 	return middleware.RealIP
-}
\ No newline at end of file
+}
+// GetRequestIDFromContext returns the ID set by RequestID, or "" when there is none
+func GetRequestIDFromContext(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
@@ -0,0 +1,18 @@
+// internal/middleware/strict_json.go
+package middleware
+
+import (
+	"net/http"
+
+	"chi-mongo-backend/pkg/utils"
+)
+
+// StrictJSON rejects JSON bodies with fields the request model does not declare, so a
+// misspelled optional field fails instead of being silently ignored
+func StrictJSON() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(utils.WithStrictJSON(r.Context())))
+		})
+	}
+}
@@ -2,24 +2,68 @@
 package models
 
 import (
+	"errors"
 	"time"
+
+	"chi-mongo-backend/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Response models for admin endpoints
 type AdminUserListResponse struct {
-	Message string        `json:"message"`
-	Users   []AdminUser   `json:"users"`
-	Total   int           `json:"total"`
+	Message    string          `json:"message"`
+	Users      []AdminUser     `json:"users"`
+	Total      int             `json:"total"` // Users on this page
+	Pagination pagination.Info `json:"pagination"`
 }
 
 type AdminUser struct {
-	ID        primitive.ObjectID `json:"id"`
-	UserID    string             `json:"userId"`
-	Email     string             `json:"email"`
-	Credits   int                `json:"credits"`
-	CreatedAt time.Time          `json:"createdAt,omitempty"`
-	UpdatedAt time.Time          `json:"updatedAt,omitempty"`
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	UserID    string             `bson:"userId" json:"userId"`
+	Email     string             `bson:"email" json:"email"`
+	Credits   int                `bson:"credits" json:"credits"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt,omitempty"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt,omitempty"`
+	// Activity fields are filled in by the admin user search
+	HasAPIKey    bool       `bson:"hasApiKey" json:"hasApiKey"`
+	LastActiveAt *time.Time `bson:"lastActiveAt,omitempty" json:"lastActiveAt,omitempty"`
+	TotalCalls   int        `bson:"totalCalls" json:"totalCalls"`
+}
+
+// Sort keys accepted by the admin user search
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortCredits   = "credits"
+	UserSortUsage     = "usage"
+)
+
+// AdminUserFilter narrows and orders the admin user list; empty fields match everything
+type AdminUserFilter struct {
+	EmailPrefix   string
+	EmailContains string
+	MinCredits    *int
+	MaxCredits    *int
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	HasAPIKey     *bool
+	ActiveSince   *time.Time
+	SortBy        string
+	Ascending     bool
+}
+
+func (f *AdminUserFilter) Validate() error {
+	switch f.SortBy {
+	case "", UserSortCreatedAt, UserSortCredits, UserSortUsage:
+	default:
+		return errors.New("sort must be one of created_at, credits or usage")
+	}
+	if f.MinCredits != nil && f.MaxCredits != nil && *f.MaxCredits < *f.MinCredits {
+		return errors.New("max_credits must not be less than min_credits")
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedTo.Before(*f.CreatedFrom) {
+		return errors.New("created_to must not be before created_from")
+	}
+	return nil
 }
 
 type AdminUserDetailResponse struct {
@@ -35,16 +79,30 @@ type UserStatsResponse struct {
 }
 
 type UserActivityResponse struct {
-	Message   string         `json:"message"`
-	UserID    string         `json:"userId"`
-	Activities []ActivityLog `json:"activities"`
+	Message    string          `json:"message"`
+	UserID     string          `json:"userId"`
+	Activities []ActivityLog   `json:"activities"`
+	Pagination pagination.Info `json:"pagination"`
 }
 
+// Activity actions recorded in a user's activity log
+const (
+	ActivityUserRegistered = "user.registered"
+	ActivityCreditsAdded   = "credits.added"
+	ActivityCreditsCharged = "credits.charged"
+	ActivityTokenRedeemed  = "token.redeemed"
+	ActivityAPIKeyCreated  = "api_key.created"
+	ActivityAPIKeyRotated  = "api_key.rotated"
+	ActivityAPIKeyUpdated  = "api_key.updated"
+	ActivityAPIKeyRevoked  = "api_key.revoked"
+)
+
 type ActivityLog struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID      string             `bson:"userId" json:"userId"`
 	Action      string             `bson:"action" json:"action"`
 	Description string             `bson:"description" json:"description"`
+	Actor       string             `bson:"actor,omitempty" json:"actor,omitempty"` // Admin email when an admin acted on the user
 	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`
 	Metadata    map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
 }
@@ -55,4 +113,12 @@ type UserCreditsResponse struct {
 	Credits int    `json:"credits"`
 }
 
-// Note: CreditsResponse and RegisterUserResponse should be in response.go, not here
\ No newline at end of file
+// Note: CreditsResponse and RegisterUserResponse should be in response.go, not here
+// Admin is an entry in the admin directory: everyone seen authenticating with the admin role.
+// Roles live in the identity provider, so this is what server-side checks such as token
+// transfers use to tell whether an email belongs to an admin.
+type Admin struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Email      string             `bson:"email" json:"email"` // lowercased
+	LastSeenAt time.Time          `bson:"lastSeenAt" json:"lastSeenAt"`
+}
@@ -0,0 +1,123 @@
+// internal/models/admin_audit.go
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Admin actions recorded in the admin audit log
+const (
+	AdminActionCreditsAdd    = "credits.add"
+	AdminActionCreditsDeduct = "credits.deduct"
+	AdminActionTokenGenerate = "token.generate"
+	AdminActionTokenBatch    = "token.batch_generate"
+	AdminActionPromoCreate   = "token.promo_create"
+	AdminActionTokenRevoke   = "token.revoke"
+	AdminActionTokenTransfer = "token.transfer"
+	AdminActionUserView      = "user.view"
+	AdminActionUserActivity  = "user.activity_view"
+	AdminActionUserCredits   = "user.credits_view"
+	AdminActionUserUsage     = "user.usage_view"
+	AdminActionUsageExport   = "usage.export"
+)
+
+// Targets of admin actions
+const (
+	AuditTargetUser     = "user"
+	AuditTargetToken    = "token"
+	AuditTargetCampaign = "campaign"
+	AuditTargetUsage    = "usage"
+)
+
+// AdminAuditEntry is an append-only record of an admin action. Entries are numbered and each
+// hash covers the previous one, so an edited, removed or reordered entry breaks the chain.
+type AdminAuditEntry struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	Sequence   int64                  `bson:"sequence" json:"sequence"`
+	Actor      string                 `bson:"actor" json:"actor"` // Admin email
+	Action     string                 `bson:"action" json:"action"`
+	TargetType string                 `bson:"targetType" json:"targetType"`
+	TargetID   string                 `bson:"targetId,omitempty" json:"targetId,omitempty"`
+	Before     map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After      map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`
+	RequestID  string                 `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	IP         string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	Timestamp  time.Time              `bson:"timestamp" json:"timestamp"`
+	PrevHash   string                 `bson:"prevHash" json:"prevHash"`
+	Hash       string                 `bson:"hash" json:"hash"`
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's content and PrevHash. Map keys are
+// encoded in sorted order, so the hash is stable across a round trip through Mongo.
+func (e *AdminAuditEntry) ComputeHash() string {
+	data, _ := json.Marshal(struct {
+		Sequence   int64                  `json:"sequence"`
+		Actor      string                 `json:"actor"`
+		Action     string                 `json:"action"`
+		TargetType string                 `json:"targetType"`
+		TargetID   string                 `json:"targetId"`
+		Before     map[string]interface{} `json:"before"`
+		After      map[string]interface{} `json:"after"`
+		RequestID  string                 `json:"requestId"`
+		IP         string                 `json:"ip"`
+		Timestamp  int64                  `json:"timestamp"`
+		PrevHash   string                 `json:"prevHash"`
+	}{
+		Sequence:   e.Sequence,
+		Actor:      e.Actor,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     e.Before,
+		After:      e.After,
+		RequestID:  e.RequestID,
+		IP:         e.IP,
+		Timestamp:  e.Timestamp.UnixMilli(),
+		PrevHash:   e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AdminAuditFilter narrows the audit log; empty fields match everything
+type AdminAuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+func (f *AdminAuditFilter) Validate() error {
+	if f.StartDate != nil && f.EndDate != nil && f.EndDate.Before(*f.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+type AdminAuditListResponse struct {
+	Message    string            `json:"message"`
+	Entries    []AdminAuditEntry `json:"entries"`
+	Count      int               `json:"count"`
+	Pagination pagination.Info   `json:"pagination"`
+}
+
+// AdminAuditVerifyResponse reports the result of walking the hash chain from the first entry.
+// BrokenAt is the sequence of the first entry that does not match.
+type AdminAuditVerifyResponse struct {
+	Message      string `json:"message"`
+	Valid        bool   `json:"valid"`
+	Checked      int64  `json:"checked"`
+	LastSequence int64  `json:"lastSequence"`
+	LastHash     string `json:"lastHash,omitempty"`
+	BrokenAt     *int64 `json:"brokenAt,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
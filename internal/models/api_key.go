@@ -2,26 +2,28 @@
 package models
 
 import (
-	"errors"
 	"strings"
 	"time"
 
+	"chi-mongo-backend/pkg/validation"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type APIKey struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID      string             `bson:"userId" json:"userId"`
-	Email       string             `bson:"email" json:"email"`
-	KeyName     string             `bson:"keyName" json:"keyName"`
-	KeyHash     string             `bson:"keyHash" json:"-"` // Never expose in JSON
-	KeyPrefix   string             `bson:"keyPrefix" json:"keyPrefix"` // First 8 chars for identification
-	IsActive    bool               `bson:"isActive" json:"isActive"`
-	LastUsedAt  *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
-	UsageCount  int64              `bson:"usageCount" json:"usageCount"`
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
-	ExpiresAt   *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID           string             `bson:"userId" json:"userId"`
+	Email            string             `bson:"email" json:"email"`
+	KeyName          string             `bson:"keyName" json:"keyName"`
+	KeyHash          string             `bson:"keyHash" json:"-"`           // Never expose in JSON
+	KeyPrefix        string             `bson:"keyPrefix" json:"keyPrefix"` // First 8 chars for identification
+	IsActive         bool               `bson:"isActive" json:"isActive"`
+	LastUsedAt       *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	UsageCount       int64              `bson:"usageCount" json:"usageCount"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ExpiresAt        *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	ExpiryNotifiedAt *time.Time         `bson:"expiryNotifiedAt,omitempty" json:"-"` // Set once the expiry notice has been sent
 }
 
 type CreateAPIKeyRequest struct {
@@ -53,7 +55,7 @@ type APIKeyListResponse struct {
 }
 
 type UpdateAPIKeyRequest struct {
-	KeyName  string `json:"keyName,omitempty"`
+	KeyName  string `json:"keyName,omitempty" validate:"omitempty,max=50"`
 	IsActive *bool  `json:"isActive,omitempty"`
 }
 
@@ -92,51 +94,31 @@ type IndividualAPIKeyStats struct {
 
 // Validation methods
 func (r *CreateAPIKeyRequest) Validate() error {
-	if strings.TrimSpace(r.KeyName) == "" {
-		return errors.New("keyName is required")
-	}
-	
 	r.KeyName = strings.TrimSpace(r.KeyName)
-	
-	if len(r.KeyName) > 50 {
-		return errors.New("keyName must be 50 characters or less")
-	}
-	
-	if len(r.KeyName) < 1 {
-		return errors.New("keyName must be at least 1 character long")
-	}
-	
+	errs := validation.Struct(r)
+
 	if r.ExpiresAt != nil && r.ExpiresAt.Before(time.Now()) {
-		return errors.New("expiresAt must be in the future")
+		errs.Add("expiresAt", validation.CodeInvalid, "expiresAt must be in the future")
 	}
 	
 	// Validate expiry date is not too far in the future (e.g., max 1 year)
 	if r.ExpiresAt != nil && r.ExpiresAt.After(time.Now().AddDate(1, 0, 0)) {
-		return errors.New("expiresAt cannot be more than 1 year in the future")
+		errs.Add("expiresAt", validation.CodeInvalid, "expiresAt cannot be more than 1 year in the future")
 	}
 	
-	return nil
+	return errs.Err()
 }
 
 func (r *UpdateAPIKeyRequest) Validate() error {
-	if r.KeyName != "" {
-		r.KeyName = strings.TrimSpace(r.KeyName)
-		
-		if len(r.KeyName) > 50 {
-			return errors.New("keyName must be 50 characters or less")
-		}
-		
-		if len(r.KeyName) < 1 {
-			return errors.New("keyName must be at least 1 character long")
-		}
-	}
+	r.KeyName = strings.TrimSpace(r.KeyName)
+	errs := validation.Struct(r)
 	
 	// Validate that at least one field is being updated
 	if r.KeyName == "" && r.IsActive == nil {
-		return errors.New("at least one field must be provided for update")
+		errs.Add("keyName", validation.CodeRequired, "at least one field must be provided for update")
 	}
 	
-	return nil
+	return errs.Err()
 }
 
 // Helper methods for APIKey
@@ -191,19 +173,18 @@ type APIKeyValidationRequest struct {
 }
 
 func (r *APIKeyValidationRequest) Validate() error {
-	if strings.TrimSpace(r.APIKey) == "" {
-		return errors.New("apiKey is required")
+	errs := validation.Struct(r)
+	if errs.Has("apiKey") {
+		return errs.Err()
 	}
 	
 	// Validate API key format (should start with ak_live_)
 	if !strings.HasPrefix(r.APIKey, "ak_live_") {
-		return errors.New("invalid API key format")
+		errs.Add("apiKey", validation.CodeInvalid, "invalid API key format")
+	} else if len(r.APIKey) != 72 {
+		// Validate length (ak_live_ + 64 hex characters = 72 total)
+		errs.Add("apiKey", validation.CodeInvalid, "invalid API key length")
 	}
 	
-	// Validate length (ak_live_ + 64 hex characters = 72 total)
-	if len(r.APIKey) != 72 {
-		return errors.New("invalid API key length")
-	}
-	
-	return nil
-}
\ No newline at end of file
+	return errs.Err()
+}
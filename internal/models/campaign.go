@@ -0,0 +1,85 @@
+// internal/models/campaign.go
+package models
+
+import (
+	"strings"
+	"time"
+
+	"chi-mongo-backend/pkg/pagination"
+	"chi-mongo-backend/pkg/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBatchTokenCount caps how many tokens a single batch request may create
+const MaxBatchTokenCount = 10000
+
+// Campaign groups credit tokens generated together for a partner campaign
+type Campaign struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name            string             `bson:"name" json:"name"`
+	Description     string             `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedBy       string             `bson:"createdBy" json:"createdBy"`
+	TokenCount      int                `bson:"tokenCount" json:"tokenCount"`
+	CreditsPerToken int                `bson:"creditsPerToken" json:"creditsPerToken"`
+	ExpiresAt       time.Time          `bson:"expiresAt" json:"expiresAt"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type GenerateTokenBatchRequest struct {
+	Count        int        `json:"count" validate:"required,min=1,max=10000"`
+	Credits      int        `json:"credits" validate:"required,min=1"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CampaignName string     `json:"campaignName" validate:"required,max=100"`
+	Description  string     `json:"description,omitempty"`
+}
+
+type TokenBatchResponse struct {
+	Message  string    `json:"message"`
+	Campaign *Campaign `json:"campaign"`
+	Tokens   []string  `json:"tokens"`
+}
+
+type CampaignListResponse struct {
+	Message    string          `json:"message"`
+	Campaigns  []Campaign      `json:"campaigns"`
+	Total      int             `json:"total"` // Campaigns on this page
+	Pagination pagination.Info `json:"pagination"`
+}
+
+// CampaignTimelinePoint is the number of redemptions on a single day
+type CampaignTimelinePoint struct {
+	Date            string `bson:"_id" json:"date"`
+	Redeemed        int    `bson:"redeemed" json:"redeemed"`
+	CreditsRedeemed int    `bson:"credits_redeemed" json:"creditsRedeemed"`
+}
+
+type CampaignAnalytics struct {
+	TotalTokens     int                     `json:"totalTokens"`
+	RedeemedCount   int                     `json:"redeemedCount"`
+	UnredeemedCount int                     `json:"unredeemedCount"`
+	ExpiredCount    int                     `json:"expiredCount"`
+	CreditsIssued   int                     `json:"creditsIssued"`
+	CreditsRedeemed int                     `json:"creditsRedeemed"`
+	RedemptionRate  float64                 `json:"redemptionRate"`
+	Timeline        []CampaignTimelinePoint `json:"timeline"`
+}
+
+type CampaignAnalyticsResponse struct {
+	Message   string            `json:"message"`
+	Campaign  *Campaign         `json:"campaign"`
+	Analytics CampaignAnalytics `json:"analytics"`
+}
+
+func (r *GenerateTokenBatchRequest) Validate() error {
+	r.CampaignName = strings.TrimSpace(r.CampaignName)
+	errs := validation.Struct(r)
+
+	if r.ExpiresAt != nil && r.ExpiresAt.Before(time.Now()) {
+		errs.Add("expiresAt", validation.CodeInvalid, "expiresAt must be in the future")
+	} else if r.ExpiresAt != nil && r.ExpiresAt.After(time.Now().AddDate(1, 0, 0)) {
+		errs.Add("expiresAt", validation.CodeInvalid, "expiresAt cannot be more than 1 year in the future")
+	}
+
+	return errs.Err()
+}
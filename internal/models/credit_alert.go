@@ -0,0 +1,75 @@
+// internal/models/credit_alert.go
+package models
+
+import (
+	"strings"
+	"time"
+
+	"chi-mongo-backend/pkg/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditAlertSettings holds a user's low-balance alert and auto top-up configuration
+type CreditAlertSettings struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID           string             `bson:"userId" json:"userId"`
+	Enabled          bool               `bson:"enabled" json:"enabled"`
+	Threshold        int                `bson:"threshold" json:"threshold"`
+	NotifyEmail      string             `bson:"notifyEmail,omitempty" json:"notifyEmail,omitempty"`
+	WebhookURL       string             `bson:"webhookUrl,omitempty" json:"webhookUrl,omitempty"`
+	AutoTopUpEnabled bool               `bson:"autoTopUpEnabled" json:"autoTopUpEnabled"`
+	AutoTopUpAmount  int                `bson:"autoTopUpAmount,omitempty" json:"autoTopUpAmount,omitempty"`
+	PaymentMethodID  string             `bson:"paymentMethodId,omitempty" json:"paymentMethodId,omitempty"`
+	Triggered        bool               `bson:"triggered" json:"triggered"` // True while the balance stays below the threshold
+	LastTriggeredAt  *time.Time         `bson:"lastTriggeredAt,omitempty" json:"lastTriggeredAt,omitempty"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type UpdateCreditAlertRequest struct {
+	Enabled          bool   `json:"enabled"`
+	Threshold        int    `json:"threshold" validate:"required,min=1"`
+	NotifyEmail      string `json:"notifyEmail,omitempty" validate:"omitempty,email"`
+	WebhookURL       string `json:"webhookUrl,omitempty" validate:"omitempty,url"`
+	AutoTopUpEnabled bool   `json:"autoTopUpEnabled"`
+	AutoTopUpAmount  int    `json:"autoTopUpAmount,omitempty" validate:"omitempty,min=1"`
+	PaymentMethodID  string `json:"paymentMethodId,omitempty"`
+}
+
+type CreditAlertResponse struct {
+	Message  string               `json:"message"`
+	Settings *CreditAlertSettings `json:"settings"`
+}
+
+// LowBalanceEvent is the payload delivered by email or webhook when a threshold is crossed
+type LowBalanceEvent struct {
+	Event           string    `json:"event"`
+	UserID          string    `json:"userId"`
+	Threshold       int       `json:"threshold"`
+	PreviousBalance int       `json:"previousBalance"`
+	CurrentBalance  int       `json:"currentBalance"`
+	AutoTopUpAmount int       `json:"autoTopUpAmount,omitempty"`
+	OccurredAt      time.Time `json:"occurredAt"`
+}
+
+func (r *UpdateCreditAlertRequest) Validate() error {
+	r.NotifyEmail = strings.TrimSpace(r.NotifyEmail)
+	r.WebhookURL = strings.TrimSpace(r.WebhookURL)
+	errs := validation.Struct(r)
+
+	if r.Enabled && r.NotifyEmail == "" && r.WebhookURL == "" && !r.AutoTopUpEnabled {
+		errs.Add("enabled", validation.CodeInvalid, "notifyEmail, webhookUrl or auto top-up is required when alerts are enabled")
+	}
+
+	if r.AutoTopUpEnabled {
+		if r.AutoTopUpAmount <= 0 && !errs.Has("autoTopUpAmount") {
+			errs.Add("autoTopUpAmount", validation.CodeRequired, "autoTopUpAmount must be positive when auto top-up is enabled")
+		}
+		if strings.TrimSpace(r.PaymentMethodID) == "" {
+			errs.Add("paymentMethodId", validation.CodeRequired, "paymentMethodId is required when auto top-up is enabled")
+		}
+	}
+
+	return errs.Err()
+}
@@ -2,7 +2,7 @@
 package models
 
 import (
-	"errors"
+	"chi-mongo-backend/pkg/validation"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -11,6 +11,9 @@ type Credits struct {
 	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID  string             `bson:"userId" json:"userId"`
 	Credits int                `bson:"credits" json:"credits"`
+	// Version counts balance changes so the copy on the user document is never overwritten
+	// by an older balance
+	Version int64 `bson:"version,omitempty" json:"-"`
 }
 
 type AddCreditsRequest struct {
@@ -18,27 +21,36 @@ type AddCreditsRequest struct {
 	Amount int    `json:"amount" validate:"required,min=1"`
 }
 
+// ChargeServiceName is the usage service name of a direct charge that names no service
+const ChargeServiceName = "custom-charge"
+
+// ChargeServicePrefix namespaces the service a direct charge names, so a caller cannot add
+// usage records that count as calls of a processing service such as "qr-masking"
+const ChargeServicePrefix = "manual:"
+
 type DeductCreditsRequest struct {
 	UserID string `json:"userId" validate:"required"`
 	Amount int    `json:"amount" validate:"required,min=1"` // Added amount field
+
+	// Optional fields for charges made by external systems through /credits/deduct; the
+	// service is recorded as "manual:<service>"
+	Service        string `json:"service,omitempty" validate:"omitempty,max=100"`
+	Reason         string `json:"reason,omitempty" validate:"omitempty,max=500"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty" validate:"omitempty,max=255"`
 }
 
-func (r *AddCreditsRequest) Validate() error {
-	if r.UserID == "" {
-		return errors.New("userId is required")
-	}
-	if r.Amount <= 0 {
-		return errors.New("amount must be positive")
+// ChargeService returns the usage service name the charge is recorded under
+func (r *DeductCreditsRequest) ChargeService() string {
+	if r.Service == "" {
+		return ChargeServiceName
 	}
-	return nil
+	return ChargeServicePrefix + r.Service
+}
+
+func (r *AddCreditsRequest) Validate() error {
+	return validation.Struct(r).Err()
 }
 
 func (r *DeductCreditsRequest) Validate() error {
-	if r.UserID == "" {
-		return errors.New("userId is required")
-	}
-	if r.Amount <= 0 {
-		return errors.New("amount must be positive")
-	}
-	return nil
-}
\ No newline at end of file
+	return validation.Struct(r).Err()
+}
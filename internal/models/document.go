@@ -0,0 +1,46 @@
+// internal/models/document.go
+package models
+
+import "time"
+
+// ContentTypePDF is the content type of an upload that is a PDF document
+const ContentTypePDF = "application/pdf"
+
+// IsPDF reports whether the upload is a PDF document rather than an image
+func (u *Upload) IsPDF() bool {
+	return u != nil && u.ContentType == ContentTypePDF
+}
+
+// DocumentPage is the image of one page of a PDF, processed like a single uploaded image
+type DocumentPage struct {
+	Number int // 1-based
+	Image  *Upload
+}
+
+// DocumentPageResult is the outcome of one page. CreditsUsed is zero when the page was
+// not processed.
+type DocumentPageResult struct {
+	Page        int         `json:"page"`
+	Success     bool        `json:"success"`
+	CreditsUsed int         `json:"creditsUsed"`
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// DocumentResult is the outcome of a PDF processed page by page; Success means every
+// page succeeded
+type DocumentResult struct {
+	ReqID       string               `json:"req_id"`
+	Success     bool                 `json:"success"`
+	CreditsUsed int                  `json:"creditsUsed"`
+	Pages       []DocumentPageResult `json:"pages"`
+}
+
+// DocumentResponse is returned to frontend callers for a PDF
+type DocumentResponse struct {
+	Message          string          `json:"message"`
+	UserID           string          `json:"userId"`
+	RemainingCredits int             `json:"remainingCredits"`
+	DocumentResult   *DocumentResult `json:"documentResult"`
+	ProcessedAt      time.Time       `json:"processedAt"`
+}
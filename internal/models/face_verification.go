@@ -2,17 +2,23 @@
 package models
 
 import (
-	"errors"
 	"strings"
 	"time"
+
+	"chi-mongo-backend/pkg/validation"
 )
 
 // Face Verification request structure - matches the API expectations
 type FaceVerificationRequest struct {
 	ReqID       string `json:"req_id" validate:"required"`
-	DocBase64_1 string `json:"doc_base64_1" validate:"required"`
-	DocBase64_2 string `json:"doc_base64_2" validate:"required"`
+	DocBase64_1 string `json:"doc_base64_1" validate:"required_without=Doc1,omitempty,min=10"`
+	DocBase64_2 string `json:"doc_base64_2" validate:"required_without=Doc2,omitempty,min=10"`
 	DocType     string `json:"doc_type" validate:"required"`
+
+	// Doc1 and Doc2 are the faces uploaded as multipart/form-data in place of doc_base64_1
+	// and doc_base64_2
+	Doc1 *Upload `json:"-" form:"file_1"`
+	Doc2 *Upload `json:"-" form:"file_2"`
 }
 
 // Face Verification data structure (nested in API response)
@@ -40,31 +46,45 @@ type FaceVerificationResponse struct {
 }
 
 func (r *FaceVerificationRequest) Validate() error {
-	if strings.TrimSpace(r.ReqID) == "" {
-		return errors.New("req_id is required and cannot be empty")
+	// required, required_without and the minimum base64 lengths come from the validate tags
+	errs := validation.Struct(r)
+	if r.DocBase64_1 != "" && r.Doc1 != nil {
+		errs.Add("doc_base64_1", validation.CodeInvalid, "send doc_base64_1 either as base64 or as an uploaded file, not both")
 	}
-	if strings.TrimSpace(r.DocBase64_1) == "" {
-		return errors.New("doc_base64_1 is required and cannot be empty")
+	if r.DocBase64_2 != "" && r.Doc2 != nil {
+		errs.Add("doc_base64_2", validation.CodeInvalid, "send doc_base64_2 either as base64 or as an uploaded file, not both")
 	}
-	if strings.TrimSpace(r.DocBase64_2) == "" {
-		return errors.New("doc_base64_2 is required and cannot be empty")
+
+	// Validate doc_type (must be "face" according to API spec)
+	if !errs.Has("doc_type") && strings.ToLower(strings.TrimSpace(r.DocType)) != "face" {
+		errs.Add("doc_type", validation.CodeInvalid, "doc_type must be 'face'")
 	}
-	if strings.TrimSpace(r.DocType) == "" {
-		return errors.New("doc_type is required and cannot be empty")
+
+	if r.Doc1 != nil && len(r.Doc1.Data) == 0 {
+		errs.Add("file_1", validation.CodeInvalid, "uploaded file is empty")
 	}
-	
-	// Validate doc_type (must be "face" according to API spec)
-	if strings.ToLower(strings.TrimSpace(r.DocType)) != "face" {
-		return errors.New("doc_type must be 'face'")
+	if r.Doc2 != nil && len(r.Doc2.Data) == 0 {
+		errs.Add("file_2", validation.CodeInvalid, "uploaded file is empty")
 	}
-	
-	// Basic validation for base64 strings
-	if len(r.DocBase64_1) < 10 {
-		return errors.New("doc_base64_1 appears to be too short to be a valid document")
+
+	return errs.Err()
+}
+
+func (r *FaceVerificationRequest) Uploads() []*Upload {
+	return []*Upload{r.Doc1, r.Doc2}
+}
+
+// PrepareImages replaces both images with their checked, normalized uploads
+func (r *FaceVerificationRequest) PrepareImages(prepare ImagePreparer) error {
+	doc1, err := prepare("doc_base64_1", r.DocBase64_1, r.Doc1)
+	if err != nil {
+		return err
 	}
-	if len(r.DocBase64_2) < 10 {
-		return errors.New("doc_base64_2 appears to be too short to be a valid document")
+	doc2, err := prepare("doc_base64_2", r.DocBase64_2, r.Doc2)
+	if err != nil {
+		return err
 	}
-	
+	r.DocBase64_1, r.Doc1 = "", doc1
+	r.DocBase64_2, r.Doc2 = "", doc2
 	return nil
-}
\ No newline at end of file
+}
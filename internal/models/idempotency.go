@@ -0,0 +1,35 @@
+// internal/models/idempotency.go
+package models
+
+import "time"
+
+// States of a claimed key, shared by idempotency records and processing results
+const (
+	ClaimStatePending   = "pending"
+	ClaimStateCompleted = "completed"
+)
+
+// IdempotencyRecord holds the outcome of a request sent with an Idempotency-Key so retries
+// can be answered without running the request again. ID is derived from the caller and key.
+type IdempotencyRecord struct {
+	ID          string            `bson:"_id"`
+	Scope       string            `bson:"scope"`       // Caller the key belongs to
+	Fingerprint string            `bson:"fingerprint"` // Hash of method, path and body
+	State       string            `bson:"state"`
+	StatusCode  int               `bson:"statusCode,omitempty"`
+	Header      map[string]string `bson:"header,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	// BodyOmitted is set when the response was too large to store and cannot be replayed
+	BodyOmitted bool      `bson:"bodyOmitted,omitempty"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	ExpiresAt   time.Time `bson:"expiresAt"` // Removed by the TTL index
+}
+
+// IdempotentResponse is a finished response to store against a key
+type IdempotentResponse struct {
+	StatusCode  int
+	Header      map[string]string
+	Body        []byte
+	BodyOmitted bool
+}
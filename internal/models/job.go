@@ -0,0 +1,37 @@
+// internal/models/job.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job run statuses
+const (
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// JobLock ensures only one replica runs each scheduled activation of a job
+type JobLock struct {
+	JobName      string    `bson:"_id" json:"jobName"`
+	Owner        string    `bson:"owner" json:"owner"`
+	ScheduledFor time.Time `bson:"scheduledFor" json:"scheduledFor"`
+	LockedUntil  time.Time `bson:"lockedUntil" json:"lockedUntil"`
+	AcquiredAt   time.Time `bson:"acquiredAt" json:"acquiredAt"`
+}
+
+// JobRun is a history record of a single job execution
+type JobRun struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	JobName      string             `bson:"jobName" json:"jobName"`
+	Owner        string             `bson:"owner" json:"owner"`
+	ScheduledFor time.Time          `bson:"scheduledFor" json:"scheduledFor"`
+	StartedAt    time.Time          `bson:"startedAt" json:"startedAt"`
+	FinishedAt   time.Time          `bson:"finishedAt" json:"finishedAt"`
+	DurationMs   int64              `bson:"durationMs" json:"durationMs"`
+	Status       string             `bson:"status" json:"status"`
+	Result       string             `bson:"result,omitempty" json:"result,omitempty"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+}
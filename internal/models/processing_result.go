@@ -0,0 +1,31 @@
+// internal/models/processing_result.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProcessingResult is the outcome of a processing call, kept per user, service and req_id
+// for the dedup window so a repeated req_id is answered without reprocessing
+type ProcessingResult struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID      string             `bson:"userId" json:"userId"`
+	ServiceName string             `bson:"service" json:"service"`
+	ReqID       string             `bson:"reqId" json:"req_id"`
+	Fingerprint string             `bson:"fingerprint" json:"-"` // Hash of the request body
+	State       string             `bson:"state" json:"state"`
+	Result      json.RawMessage    `bson:"result,omitempty" json:"result,omitempty"` // Upstream result as JSON
+	CreditsUsed int                `bson:"creditsUsed" json:"creditsUsed"`
+	LockedUntil time.Time          `bson:"lockedUntil" json:"-"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"` // Removed by the TTL index
+}
+
+type ProcessingResultResponse struct {
+	Message string            `json:"message"`
+	Result  *ProcessingResult `json:"result"`
+}
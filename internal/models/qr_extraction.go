@@ -2,15 +2,20 @@
 package models
 
 import (
-	"errors"
-	"strings"
 	"time"
+
+	"chi-mongo-backend/pkg/validation"
 )
 
 // QR Extraction request structure - matches the API expectations
 type QRExtractionRequest struct {
 	ReqID      string `json:"req_id" validate:"required"`
-	DocBase64  string `json:"doc_base64" validate:"required"`
+	DocBase64  string `json:"doc_base64" validate:"required_without=Doc,omitempty,min=10"`
+	// Pages selects the pages of a PDF, such as "1,3-5" or "all"; every page when empty
+	Pages      string `json:"pages,omitempty"`
+
+	// Doc is the document uploaded as multipart/form-data in place of doc_base64
+	Doc *Upload `json:"-" form:"file"`
 }
 
 // QR Extraction result structure (returned by external API)
@@ -32,17 +37,27 @@ type QRExtractionResponse struct {
 }
 
 func (r *QRExtractionRequest) Validate() error {
-	if strings.TrimSpace(r.ReqID) == "" {
-		return errors.New("req_id is required and cannot be empty")
+	// required, required_without and the minimum base64 length come from the validate tags
+	errs := validation.Struct(r)
+	if r.DocBase64 != "" && r.Doc != nil {
+		errs.Add("doc_base64", validation.CodeInvalid, "send either doc_base64 or an uploaded file, not both")
 	}
-	if strings.TrimSpace(r.DocBase64) == "" {
-		return errors.New("doc_base64 is required and cannot be empty")
+	if r.Doc != nil && len(r.Doc.Data) == 0 {
+		errs.Add("file", validation.CodeInvalid, "uploaded file is empty")
 	}
-	
-	// Basic validation for base64 string
-	if len(r.DocBase64) < 10 {
-		return errors.New("doc_base64 appears to be too short to be a valid document")
+	return errs.Err()
+}
+
+func (r *QRExtractionRequest) Uploads() []*Upload {
+	return []*Upload{r.Doc}
+}
+
+// PrepareImages replaces the image with its checked, normalized upload
+func (r *QRExtractionRequest) PrepareImages(prepare ImagePreparer) error {
+	image, err := prepare("doc_base64", r.DocBase64, r.Doc)
+	if err != nil {
+		return err
 	}
-	
+	r.DocBase64, r.Doc = "", image
 	return nil
-}
\ No newline at end of file
+}
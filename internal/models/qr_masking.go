@@ -2,15 +2,18 @@
 package models
 
 import (
-	"errors"
-	"strings"
 	"time"
+
+	"chi-mongo-backend/pkg/validation"
 )
 
 // QR Masking request structure - matches the API expectations
 type QRMaskingRequest struct {
 	ReqID     string `json:"req_id" validate:"required"`
-	Base64Str string `json:"base64_str" validate:"required"`
+	Base64Str string `json:"base64_str" validate:"required_without=Image,omitempty,min=10"`
+
+	// Image is the image uploaded as multipart/form-data in place of base64_str
+	Image *Upload `json:"-" form:"file"`
 }
 
 // QR Masking result structure (returned by external API)
@@ -32,17 +35,27 @@ type QRMaskingResponse struct {
 }
 
 func (r *QRMaskingRequest) Validate() error {
-	if strings.TrimSpace(r.ReqID) == "" {
-		return errors.New("req_id is required and cannot be empty")
+	// required, required_without and the minimum base64 length come from the validate tags
+	errs := validation.Struct(r)
+	if r.Base64Str != "" && r.Image != nil {
+		errs.Add("base64_str", validation.CodeInvalid, "send either base64_str or an uploaded file, not both")
 	}
-	if strings.TrimSpace(r.Base64Str) == "" {
-		return errors.New("base64_str is required and cannot be empty")
+	if r.Image != nil && len(r.Image.Data) == 0 {
+		errs.Add("file", validation.CodeInvalid, "uploaded file is empty")
 	}
-	
-	// Basic validation for base64 string
-	if len(r.Base64Str) < 10 {
-		return errors.New("base64_str appears to be too short to be a valid image")
+	return errs.Err()
+}
+
+func (r *QRMaskingRequest) Uploads() []*Upload {
+	return []*Upload{r.Image}
+}
+
+// PrepareImages replaces the image with its checked, normalized upload
+func (r *QRMaskingRequest) PrepareImages(prepare ImagePreparer) error {
+	image, err := prepare("base64_str", r.Base64Str, r.Image)
+	if err != nil {
+		return err
 	}
-	
+	r.Base64Str, r.Image = "", image
 	return nil
-}
\ No newline at end of file
+}
@@ -8,9 +8,11 @@ type RegisterUserResponse struct {
 }
 
 type CreditsResponse struct {
-	Message string `json:"message"`
-	UserID  string `json:"userId"`
-	Credits int    `json:"credits"`
+	Message  string `json:"message"`
+	UserID   string `json:"userId"`
+	Credits  int    `json:"credits"`
+	ChargeID string `json:"chargeId,omitempty"` // Usage record of a direct charge
+	Replayed bool   `json:"replayed,omitempty"` // The idempotency key matched an earlier charge
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,64 @@
+// internal/models/result_store.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"chi-mongo-backend/pkg/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResultRetentionSettings is a user's opt-in to keeping processing results. Results are not
+// kept unless the user enabled retention. DedupEnabled separately opts in to req_id
+// deduplication, which keeps each result for the dedup window to answer a repeated req_id.
+type ResultRetentionSettings struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        string             `bson:"userId" json:"userId"`
+	Enabled       bool               `bson:"enabled" json:"enabled"`
+	RetentionDays int                `bson:"retentionDays" json:"retentionDays"`
+	DedupEnabled  bool               `bson:"dedupEnabled" json:"dedupEnabled"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type UpdateResultRetentionRequest struct {
+	Enabled       bool `json:"enabled"`
+	RetentionDays int  `json:"retentionDays,omitempty" validate:"omitempty,min=1"`
+	DedupEnabled  bool `json:"dedupEnabled"`
+}
+
+func (r *UpdateResultRetentionRequest) Validate() error {
+	errs := validation.Struct(r)
+	if r.Enabled && r.RetentionDays == 0 {
+		errs.Add("retentionDays", validation.CodeRequired, "retentionDays must be positive when retention is enabled")
+	}
+	return errs.Err()
+}
+
+type ResultRetentionResponse struct {
+	Message          string                   `json:"message"`
+	Settings         *ResultRetentionSettings `json:"settings"`
+	MaxRetentionDays int                      `json:"maxRetentionDays"`
+}
+
+// RetainedResult describes a processing result kept in the result store. The result itself
+// is stored as a blob under BlobKey and deleted with the record once ExpiresAt has passed.
+type RetainedResult struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"userId" json:"userId"`
+	ServiceName string             `bson:"service" json:"service"`
+	ReqID       string             `bson:"reqId" json:"req_id"`
+	BlobKey     string             `bson:"blobKey" json:"-"`
+	ContentType string             `bson:"contentType" json:"contentType"`
+	Size        int64              `bson:"size" json:"size"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+type RetainedResultResponse struct {
+	Message string          `json:"message"`
+	Record  *RetainedResult `json:"record"`
+	Result  json.RawMessage `json:"result"`
+}
@@ -2,39 +2,77 @@
 package models
 
 import (
-	"errors"
+	"fmt"
 	"time"
+
+	"chi-mongo-backend/pkg/validation"
 )
 
 // SignatureVerificationRequest represents the request payload for signature verification
 type SignatureVerificationRequest struct {
-	ReqID      string   `json:"req_id" bson:"req_id"`
-	DocBase64  []string `json:"doc_base64" bson:"doc_base64"`
+	ReqID      string   `json:"req_id" bson:"req_id" validate:"required"`
+	DocBase64  []string `json:"doc_base64" bson:"doc_base64" validate:"required_without=Docs,max=10"`
+	// Pages selects the pages of a PDF among the documents, such as "1,3-5" or "all"; every
+	// page when empty
+	Pages      string   `json:"pages,omitempty" bson:"pages,omitempty"`
+
+	// Docs are the signatures uploaded as multipart/form-data in place of doc_base64
+	Docs []*Upload `json:"-" bson:"-" form:"file"`
 }
 
 // Validate validates the signature verification request
 func (r *SignatureVerificationRequest) Validate() error {
-	if r.ReqID == "" {
-		return errors.New("req_id is required")
-	}
+	// req_id, the presence and the number of images come from the validate tags
+	errs := validation.Struct(r)
 
-	if len(r.DocBase64) == 0 {
-		return errors.New("doc_base64 array cannot be empty")
+	if len(r.DocBase64) > 0 && len(r.Docs) > 0 {
+		errs.Add("doc_base64", validation.CodeInvalid, "send the images either as doc_base64 or as uploaded files, not both")
+	}
+	if !errs.Has("doc_base64") && len(r.DocBase64)+len(r.Docs) > 10 { // reasonable limit
+		errs.Add("doc_base64", validation.CodeMax, "doc_base64 array cannot contain more than 10 images")
 	}
 
-	if len(r.DocBase64) > 10 { // reasonable limit
-		return errors.New("doc_base64 array cannot contain more than 10 images")
+	for i, doc := range r.Docs {
+		if len(doc.Data) == 0 {
+			errs.Add(fmt.Sprintf("file[%d]", i), validation.CodeInvalid, "uploaded file is empty")
+		}
 	}
 
-	for _, base64Str := range r.DocBase64 {
+	for i, base64Str := range r.DocBase64 {
+		field := fmt.Sprintf("doc_base64[%d]", i)
 		if base64Str == "" {
-			return errors.New("doc_base64 array cannot contain empty base64 strings")
-		}
-		if len(base64Str) > 10*1024*1024 { // 10MB limit per image
-			return errors.New("base64 string too large (max 10MB per image)")
+			errs.Add(field, validation.CodeRequired, "doc_base64 array cannot contain empty base64 strings")
+		} else if len(base64Str) > 10*1024*1024 { // 10MB limit per image
+			errs.Add(field, validation.CodeMax, "base64 string too large (max 10MB per image)")
 		}
 	}
 
+	return errs.Err()
+}
+
+// Uploads returns the uploaded signature images
+func (r *SignatureVerificationRequest) Uploads() []*Upload {
+	return r.Docs
+}
+
+// PrepareImages replaces the signatures with their checked, normalized uploads
+func (r *SignatureVerificationRequest) PrepareImages(prepare ImagePreparer) error {
+	docs := make([]*Upload, 0, len(r.DocBase64)+len(r.Docs))
+	for i, base64Str := range r.DocBase64 {
+		doc, err := prepare(fmt.Sprintf("doc_base64[%d]", i), base64Str, nil)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	for i, upload := range r.Docs {
+		doc, err := prepare(fmt.Sprintf("file[%d]", i), "", upload)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	r.DocBase64, r.Docs = nil, docs
 	return nil
 }
 
@@ -4,23 +4,88 @@ package models
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
+	"strings"
 	"time"
 
+	"chi-mongo-backend/pkg/pagination"
+	"chi-mongo-backend/pkg/validation"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type CreditToken struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Token       string             `bson:"token" json:"token"`
-	Credits     int                `bson:"credits" json:"credits"`
-	CreatedBy   string             `bson:"createdBy" json:"createdBy"`
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
-	IsUsed      bool               `bson:"isUsed" json:"isUsed"`
-	UsedBy      string             `bson:"usedBy,omitempty" json:"usedBy,omitempty"`
-	UsedAt      *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
-	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Token       string              `bson:"token" json:"token"`
+	Credits     int                 `bson:"credits" json:"credits"`
+	CreatedBy   string              `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+	ExpiresAt   time.Time           `bson:"expiresAt" json:"expiresAt"`
+	IsUsed      bool                `bson:"isUsed" json:"isUsed"`
+	UsedBy      string              `bson:"usedBy,omitempty" json:"usedBy,omitempty"`
+	UsedAt      *time.Time          `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	CampaignID  *primitive.ObjectID `bson:"campaignId,omitempty" json:"campaignId,omitempty"`
+
+	// Promo code fields - a token with MaxRedemptions > 0 is a multi-use promo code
+	MaxRedemptions  int        `bson:"maxRedemptions,omitempty" json:"maxRedemptions,omitempty"`
+	MaxPerUser      int        `bson:"maxPerUser,omitempty" json:"maxPerUser,omitempty"`
+	StartsAt        *time.Time `bson:"startsAt,omitempty" json:"startsAt,omitempty"`
+	NewUsersOnly    bool       `bson:"newUsersOnly,omitempty" json:"newUsersOnly,omitempty"`
+	RedemptionCount int        `bson:"redemptionCount" json:"redemptionCount"`
+
+	// Revocation fields - revoked tokens are kept for the audit trail but cannot be redeemed
+	IsRevoked    bool       `bson:"isRevoked,omitempty" json:"isRevoked,omitempty"`
+	RevokedBy    string     `bson:"revokedBy,omitempty" json:"revokedBy,omitempty"`
+	RevokedAt    *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokeReason string     `bson:"revokeReason,omitempty" json:"revokeReason,omitempty"`
+}
+
+// Redemption statuses while a promo redemption is in flight. Completed redemptions have no
+// status, so records written before statuses existed read as completed.
+const (
+	RedemptionStatusPending   = "pending"   // per-user slot claimed
+	RedemptionStatusReserved  = "reserved"  // total-limit slot also claimed on the token
+	RedemptionStatusCrediting = "crediting" // credit increment started and may have been applied
+)
+
+// TokenRedemption records a single redemption of a promo code
+type TokenRedemption struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TokenID    primitive.ObjectID `bson:"tokenId" json:"tokenId"`
+	Token      string             `bson:"token" json:"token"`
+	UserID     string             `bson:"userId" json:"userId"`
+	Sequence   int                `bson:"sequence" json:"sequence"` // Nth redemption by this user, unique per token and user
+	Credits    int                `bson:"credits" json:"credits"`
+	RedeemedAt time.Time          `bson:"redeemedAt" json:"redeemedAt"`
+	Status     string             `bson:"status,omitempty" json:"status,omitempty"`
+}
+
+type CreatePromoCodeRequest struct {
+	Code           string     `json:"code,omitempty" validate:"omitempty,min=4,max=32,alphanum"`
+	Credits        int        `json:"credits" validate:"required,min=1"`
+	MaxRedemptions int        `json:"maxRedemptions" validate:"required,min=1"`
+	MaxPerUser     int        `json:"maxPerUser,omitempty" validate:"omitempty,min=1"`
+	StartsAt       *time.Time `json:"startsAt,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	NewUsersOnly   bool       `json:"newUsersOnly,omitempty"`
+	Description    string     `json:"description,omitempty"`
+}
+
+type RevokeTokenRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type TransferTokenRequest struct {
+	ToAdmin string `json:"toAdmin" validate:"required,email"`
+	Reason  string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+type TokenRedemptionsResponse struct {
+	Message     string            `json:"message"`
+	Token       *CreditToken      `json:"token"`
+	Redemptions []TokenRedemption `json:"redemptions"`
+	Count       int               `json:"count"` // Redemptions on this page
+	Pagination  pagination.Info   `json:"pagination"`
 }
 
 type GenerateTokenRequest struct {
@@ -34,6 +99,7 @@ type RedeemTokenRequest struct {
 
 type TokenResponse struct {
 	Message           string     `json:"message"`
+	ID                string     `json:"id,omitempty"` // set when a token is created; audit records use it instead of the code
 	Token             string     `json:"token,omitempty"`
 	Credits           int        `json:"credits"`
 	RemainingCredits  int        `json:"remainingCredits,omitempty"`  // Add this field
@@ -43,21 +109,47 @@ type TokenResponse struct {
 }
 
 func (r *GenerateTokenRequest) Validate() error {
-	if r.Credits <= 0 {
-		return errors.New("credits must be positive")
-	}
-	return nil
+	return validation.Struct(r).Err()
 }
 
 func (r *RedeemTokenRequest) Validate() error {
-	if r.Token == "" {
-		return errors.New("token is required")
+	return validation.Struct(r).Err()
+}
+
+func (r *CreatePromoCodeRequest) Validate() error {
+	r.Code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(r.Code), "-", ""))
+	errs := validation.Struct(r)
+	if r.Code != "" && !errs.Has("code") && IsReservedCodeLength(r.Code) {
+		errs.Add("code", validation.CodeInvalid, "code length is reserved for generated codes")
+	}
+	if r.MaxPerUser > r.MaxRedemptions && !errs.Has("maxPerUser") && !errs.Has("maxRedemptions") {
+		errs.Add("maxPerUser", validation.CodeMax, "maxPerUser cannot exceed maxRedemptions")
+	}
+	if r.ExpiresAt != nil && r.ExpiresAt.Before(time.Now()) {
+		errs.Add("expiresAt", validation.CodeInvalid, "expiresAt must be in the future")
 	}
-	return nil
+	if r.StartsAt != nil && r.ExpiresAt != nil && !r.StartsAt.Before(*r.ExpiresAt) {
+		errs.Add("startsAt", validation.CodeInvalid, "startsAt must be before expiresAt")
+	}
+	return errs.Err()
+}
+
+func (r *RevokeTokenRequest) Validate() error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	return validation.Struct(r).Err()
 }
 
-// GenerateToken creates a new random token
+func (r *TransferTokenRequest) Validate() error {
+	r.ToAdmin = strings.TrimSpace(r.ToAdmin)
+	return validation.Struct(r).Err()
+}
+
+// GenerateToken creates a new random token in the configured code format
 func GenerateToken() (string, error) {
+	if codeLength() > 0 {
+		return generateTokenCode()
+	}
+
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
@@ -65,6 +157,16 @@ func GenerateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// IsPromo reports whether the token is a multi-use promo code
+func (t *CreditToken) IsPromo() bool {
+	return t.MaxRedemptions > 0
+}
+
+// HasStarted checks if the token's redemption window has opened
+func (t *CreditToken) HasStarted() bool {
+	return t.StartsAt == nil || !time.Now().Before(*t.StartsAt)
+}
+
 // IsExpired checks if the token has expired
 func (t *CreditToken) IsExpired() bool {
 	return time.Now().After(t.ExpiresAt)
@@ -0,0 +1,35 @@
+// internal/models/token_audit.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Token lifecycle events recorded in the token audit collection
+const (
+	TokenEventCreated     = "created"
+	TokenEventRevoked     = "revoked"
+	TokenEventRedeemed    = "redeemed"
+	TokenEventExpired     = "expired"
+	TokenEventTransferred = "transferred"
+)
+
+// TokenAuditEvent is an append-only record of a credit token lifecycle change
+type TokenAuditEvent struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	TokenID   primitive.ObjectID     `bson:"tokenId" json:"tokenId"`
+	Token     string                 `bson:"token" json:"token"`
+	Event     string                 `bson:"event" json:"event"`
+	Actor     string                 `bson:"actor" json:"actor"` // Admin email, redeeming user ID or "system"
+	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Timestamp time.Time              `bson:"timestamp" json:"timestamp"`
+}
+
+type TokenAuditResponse struct {
+	Message string            `json:"message"`
+	TokenID string            `json:"tokenId"`
+	Events  []TokenAuditEvent `json:"events"`
+	Count   int               `json:"count"`
+}
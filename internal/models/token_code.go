@@ -0,0 +1,211 @@
+// internal/models/token_code.go
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Token codes are generated from a format pattern such as "XXXX-XXXX-XXXX": every X is a
+// Crockford base32 character and the last X is a Luhn mod 32 check character. Codes are
+// stored without dashes and formatted for display. The special format "hex" keeps the
+// legacy 32-character hex tokens.
+const (
+	DefaultTokenCodeFormat = "XXXX-XXXX-XXXX"
+	HexTokenCodeFormat     = "hex"
+
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	minCodeSymbols    = 8
+	legacyHexLength   = 32
+)
+
+var (
+	ErrInvalidTokenCode = errors.New("invalid token code")
+
+	tokenCodeMu     sync.RWMutex
+	tokenCodeFormat = DefaultTokenCodeFormat
+)
+
+// SetTokenCodeFormat configures the pattern used by GenerateToken
+func SetTokenCodeFormat(format string) error {
+	format = strings.TrimSpace(format)
+	if format == "" {
+		format = DefaultTokenCodeFormat
+	}
+
+	if !strings.EqualFold(format, HexTokenCodeFormat) {
+		symbols := 0
+		for _, c := range format {
+			switch c {
+			case 'X', 'x':
+				symbols++
+			case '-':
+			default:
+				return errors.New("token code format may only contain 'X' and '-'")
+			}
+		}
+		if symbols < minCodeSymbols {
+			return errors.New("token code format must contain at least 8 'X' characters")
+		}
+		format = strings.ToUpper(format)
+	} else {
+		format = HexTokenCodeFormat
+	}
+
+	tokenCodeMu.Lock()
+	tokenCodeFormat = format
+	tokenCodeMu.Unlock()
+	return nil
+}
+
+func currentTokenCodeFormat() string {
+	tokenCodeMu.RLock()
+	defer tokenCodeMu.RUnlock()
+	return tokenCodeFormat
+}
+
+// codeLength returns the number of symbols in a generated code, or 0 for hex codes
+func codeLength() int {
+	format := currentTokenCodeFormat()
+	if format == HexTokenCodeFormat {
+		return 0
+	}
+	return strings.Count(format, "X")
+}
+
+// generateTokenCode creates a checksummed Crockford base32 code without dashes
+func generateTokenCode() (string, error) {
+	length := codeLength()
+	alphabetSize := big.NewInt(int64(len(crockfordAlphabet)))
+
+	code := make([]byte, 0, length)
+	for i := 0; i < length-1; i++ {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code = append(code, crockfordAlphabet[n.Int64()])
+	}
+	code = append(code, checkSymbol(string(code)))
+	return string(code), nil
+}
+
+// checkSymbol computes the Luhn mod 32 check character, which catches every single
+// character substitution and most adjacent transpositions
+func checkSymbol(payload string) byte {
+	const n = len(crockfordAlphabet)
+	factor := 2
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, payload[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return crockfordAlphabet[(n-sum%n)%n]
+}
+
+// NormalizeTokenCode turns user input into the stored form of a code. Case, spaces and
+// dashes are ignored, and ambiguous characters are mapped (I/L to 1, O to 0). Codes of
+// the generated length are rejected when the check character does not match, so typos
+// never reach the database. Custom promo codes pass through in upper case, and so do
+// 32-character hex codes unless the configured format is "hex"; see LegacyHexVariant.
+func NormalizeTokenCode(input string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(input))
+	if cleaned == "" {
+		return "", ErrInvalidTokenCode
+	}
+
+	if currentTokenCodeFormat() == HexTokenCodeFormat && isLegacyHex(cleaned) {
+		return strings.ToLower(cleaned), nil
+	}
+
+	upper := strings.ToUpper(cleaned)
+	length := codeLength()
+	if length == 0 || len(upper) != length {
+		return upper, nil
+	}
+
+	mapped := strings.Map(func(r rune) rune {
+		switch r {
+		case 'I', 'L':
+			return '1'
+		case 'O':
+			return '0'
+		}
+		return r
+	}, upper)
+	for i := 0; i < len(mapped); i++ {
+		if strings.IndexByte(crockfordAlphabet, mapped[i]) < 0 {
+			return "", ErrInvalidTokenCode
+		}
+	}
+	if checkSymbol(mapped[:len(mapped)-1]) != mapped[len(mapped)-1] {
+		return "", ErrInvalidTokenCode
+	}
+	return mapped, nil
+}
+
+// LegacyHexVariant returns the other stored form of a 32-character hex code: legacy hex
+// tokens are stored in lower case and custom promo codes in upper case, so a code of that
+// shape is looked up again in the other case when the normalized form is not found
+func LegacyHexVariant(code string) (string, bool) {
+	if !isLegacyHex(code) {
+		return "", false
+	}
+	variant := strings.ToLower(code)
+	if variant == code {
+		variant = strings.ToUpper(code)
+	}
+	if variant == code {
+		return "", false
+	}
+	return variant, true
+}
+
+// IsReservedCodeLength reports whether a custom code would collide with the checksummed
+// generated-code length
+func IsReservedCodeLength(code string) bool {
+	length := codeLength()
+	return length > 0 && len(code) == length
+}
+
+// FormatTokenCode inserts the configured dashes into a stored code for display
+func FormatTokenCode(code string) string {
+	format := currentTokenCodeFormat()
+	if format == HexTokenCodeFormat || len(code) != strings.Count(format, "X") {
+		return code
+	}
+
+	var b strings.Builder
+	i := 0
+	for _, c := range format {
+		if c == '-' {
+			b.WriteByte('-')
+			continue
+		}
+		b.WriteByte(code[i])
+		i++
+	}
+	return b.String()
+}
+
+func isLegacyHex(s string) bool {
+	return len(s) == legacyHexLength && isHex(s)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+// internal/models/token_code_test.go
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withTokenCodeFormat sets the code format for one test and restores the default after it
+func withTokenCodeFormat(t *testing.T, format string) {
+	t.Helper()
+	if err := SetTokenCodeFormat(format); err != nil {
+		t.Fatalf("SetTokenCodeFormat(%q): %v", format, err)
+	}
+	t.Cleanup(func() { SetTokenCodeFormat(DefaultTokenCodeFormat) })
+}
+
+// luhnValid checks a full code the way a reader of the code would: doubling every second
+// symbol from the right, starting with the check symbol itself undoubled
+func luhnValid(code string) bool {
+	const n = len(crockfordAlphabet)
+	factor := 1
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, code[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return sum%n == 0
+}
+
+func TestCheckSymbol(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    byte
+	}{
+		{payload: "0", want: '0'},
+		{payload: "1", want: 'Y'},
+		{payload: "00000000000", want: '0'},
+		{payload: "ZZZZZZZZZZZ", want: 'B'},
+		{payload: "ABCD1234XYZ", want: 'P'},
+		{payload: "ABCD1243XYZ", want: 'N'},
+		{payload: "10ABCDEFGH1", want: 'V'},
+		{payload: "7K3M9P2Q8R4", want: 'J'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			got := checkSymbol(tt.payload)
+			if got != tt.want {
+				t.Errorf("checkSymbol(%q) = %q, want %q", tt.payload, got, tt.want)
+			}
+			if !luhnValid(tt.payload + string(got)) {
+				t.Errorf("%q%c does not validate", tt.payload, got)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenCodeValidates(t *testing.T) {
+	withTokenCodeFormat(t, DefaultTokenCodeFormat)
+
+	for i := 0; i < 100; i++ {
+		code, err := generateTokenCode()
+		if err != nil {
+			t.Fatalf("generateTokenCode: %v", err)
+		}
+		if len(code) != 12 || !luhnValid(code) {
+			t.Fatalf("generated code %q does not validate", code)
+		}
+		if normalized, err := NormalizeTokenCode(FormatTokenCode(code)); err != nil || normalized != code {
+			t.Fatalf("NormalizeTokenCode(%q) = %q, %v; want %q", FormatTokenCode(code), normalized, err, code)
+		}
+	}
+}
+
+func TestNormalizeTokenCode(t *testing.T) {
+	const hexCode = "0123456789ABCDEF0123456789ABCDEF"
+
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+		err    bool
+	}{
+		{name: "formatted code", input: "ABCD-1234-XYZP", want: "ABCD1234XYZP"},
+		{name: "lower case with spaces", input: " abcd 1234 xyzp ", want: "ABCD1234XYZP"},
+		{name: "I is read as 1", input: "ABCD-I234-XYZP", want: "ABCD1234XYZP"},
+		{name: "L and O are read as 1 and 0", input: "lOab-cdef-gh1v", want: "10ABCDEFGH1V"},
+		{name: "wrong check symbol", input: "ABCD-1234-XYZQ", err: true},
+		{name: "transposed symbols", input: "ABCD-1243-XYZP", err: true},
+		{name: "symbol outside the alphabet", input: "ABCD-1234-XYU#", err: true},
+		{name: "empty", input: " - ", err: true},
+		{name: "custom promo code", input: "summer-sale", want: "SUMMERSALE"},
+		{name: "hex shaped custom promo code", input: strings.ToLower(hexCode), want: hexCode},
+		{name: "legacy hex token", format: HexTokenCodeFormat, input: hexCode, want: strings.ToLower(hexCode)},
+		{name: "custom promo code with the hex format", format: HexTokenCodeFormat, input: "summer-sale", want: "SUMMERSALE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			if format == "" {
+				format = DefaultTokenCodeFormat
+			}
+			withTokenCodeFormat(t, format)
+
+			got, err := NormalizeTokenCode(tt.input)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidTokenCode) {
+					t.Errorf("NormalizeTokenCode(%q) = %q, %v; want ErrInvalidTokenCode", tt.input, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeTokenCode(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyHexVariant(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{code: "0123456789ABCDEF0123456789ABCDEF", want: "0123456789abcdef0123456789abcdef", ok: true},
+		{code: "0123456789abcdef0123456789abcdef", want: "0123456789ABCDEF0123456789ABCDEF", ok: true},
+		{code: "01234567890123456789012345678901"},
+		{code: "0123456789ABCDEF"},
+		{code: "SUMMERSALE"},
+	}
+
+	for _, tt := range tests {
+		got, ok := LegacyHexVariant(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("LegacyHexVariant(%q) = %q, %v; want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
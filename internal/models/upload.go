@@ -0,0 +1,20 @@
+// internal/models/upload.go
+package models
+
+// Upload is an image sent as a multipart/form-data file instead of a base64 JSON field. The
+// API services base64-encode it for the upstream call.
+type Upload struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// UploadCarrier is implemented by processing requests that may carry uploads. Uploads are
+// not part of the request's JSON, so anything fingerprinting a request must add them.
+type UploadCarrier interface {
+	Uploads() []*Upload
+}
+
+// ImagePreparer checks one image of a request, given either as base64 or as an upload, and
+// returns it as a normalized upload. field names the image in error details.
+type ImagePreparer func(field, inline string, upload *Upload) (*Upload, error)
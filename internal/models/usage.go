@@ -3,6 +3,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -17,13 +18,23 @@ type ServiceUsage struct {
 	Method      string             `bson:"method" json:"method"`
 	Success     bool               `bson:"success" json:"success"`
 	ErrorMsg    string             `bson:"error_msg,omitempty" json:"error_msg,omitempty"`
+	ErrorCode   string             `bson:"error_code,omitempty" json:"error_code,omitempty"` // Code APIErrorMapper assigns to ErrorMsg
 	CreditsUsed int                `bson:"credits_used" json:"credits_used"`
 	RequestID   string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
 	IPAddress   string             `bson:"ip_address,omitempty" json:"ip_address,omitempty"`
 	UserAgent   string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
-	AuthMethod  string             `bson:"auth_method" json:"auth_method"` // "bearer" or "api_key"
+	AuthMethod  string             `bson:"auth_method" json:"auth_method"` // "bearer_token" or "api_key"
+	APIKeyID    string             `bson:"api_key_id,omitempty" json:"api_key_id,omitempty"` // Set when AuthMethod is "api_key"
 	ProcessTime int64              `bson:"process_time_ms" json:"process_time_ms"` // Processing time in milliseconds
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+
+	// Set on direct charges made through /credits/deduct
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+	Reason         string `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChargedBy      string `bson:"charged_by,omitempty" json:"charged_by,omitempty"` // Admin email when an admin charged the user
+
+	// Set when the result of the call was kept in the result store
+	ResultID string `bson:"result_id,omitempty" json:"result_id,omitempty"`
 }
 
 // UsageStats represents aggregated usage statistics
@@ -66,10 +77,120 @@ type UsageTrackingRequest struct {
 	Method      string
 	Success     bool
 	ErrorMsg    string
+	ErrorCode   string // Optional; derived from ErrorMsg when empty
 	CreditsUsed int
 	RequestID   string
 	IPAddress   string
 	UserAgent   string
 	AuthMethod  string
+	APIKeyID    string
 	ProcessTime int64
-}
\ No newline at end of file
+	ResultID    string // Retained result, when the user keeps results
+}
+// UsageRollupKey identifies one pre-aggregated usage bucket
+type UsageRollupKey struct {
+	Bucket      time.Time `bson:"bucket" json:"bucket"` // Start of the hour or day (UTC)
+	ServiceName string    `bson:"service_name" json:"service_name"`
+	UserID      string    `bson:"user_id" json:"user_id"`
+	AuthMethod  string    `bson:"auth_method" json:"auth_method"`
+}
+
+// UsageRollup holds usage totals for one bucket, maintained by the rollup compactor
+type UsageRollup struct {
+	Key                UsageRollupKey `bson:"_id" json:"key"`
+	Email              string         `bson:"email" json:"email"`
+	TotalCalls         int            `bson:"total_calls" json:"total_calls"`
+	SuccessCalls       int            `bson:"success_calls" json:"success_calls"`
+	FailedCalls        int            `bson:"failed_calls" json:"failed_calls"`
+	TotalCredits       int            `bson:"total_credits" json:"total_credits"`
+	TotalProcessTimeMs int64          `bson:"total_process_time_ms" json:"total_process_time_ms"`
+	UpdatedAt          time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// Time-series bucket intervals
+const (
+	UsageIntervalHour = "hour"
+	UsageIntervalDay  = "day"
+	UsageIntervalWeek = "week"
+)
+
+// UsageFilter selects raw usage records; empty fields match everything
+type UsageFilter struct {
+	UserID      string
+	ServiceName string
+	APIKeyID    string
+	Success     *bool
+	StartDate   *time.Time
+	EndDate     *time.Time
+}
+
+func (f *UsageFilter) Validate() error {
+	if f.StartDate != nil && f.EndDate != nil && f.EndDate.Before(*f.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// UsageTimeSeriesFilter selects the usage records bucketed by a time-series query
+type UsageTimeSeriesFilter struct {
+	UsageFilter
+	Interval string
+}
+
+// UsageSummary is a caller's own usage totals with a per-service breakdown
+type UsageSummary struct {
+	TotalCalls   int          `json:"total_calls"`
+	SuccessCalls int          `json:"success_calls"`
+	FailedCalls  int          `json:"failed_calls"`
+	TotalCredits int          `json:"total_credits"`
+	Services     []UsageStats `json:"services"`
+}
+
+// UsageTimeSeriesPoint is one bucket of a usage time series
+type UsageTimeSeriesPoint struct {
+	Bucket           time.Time `bson:"_id" json:"bucket"` // Start of the hour, day or ISO week (UTC)
+	TotalCalls       int       `bson:"total_calls" json:"total_calls"`
+	SuccessCalls     int       `bson:"success_calls" json:"success_calls"`
+	FailedCalls      int       `bson:"failed_calls" json:"failed_calls"`
+	TotalCredits     int       `bson:"total_credits" json:"total_credits"`
+	P50ProcessTimeMs int64     `bson:"p50_process_time_ms" json:"p50_process_time_ms"`
+	P95ProcessTimeMs int64     `bson:"p95_process_time_ms" json:"p95_process_time_ms"`
+}
+
+func (f *UsageTimeSeriesFilter) Validate() error {
+	switch f.Interval {
+	case UsageIntervalHour, UsageIntervalDay, UsageIntervalWeek:
+	default:
+		return errors.New("interval must be one of hour, day or week")
+	}
+	return f.UsageFilter.Validate()
+}
+
+// ServicePerformanceStats holds per-service latency percentiles and failure causes
+type ServicePerformanceStats struct {
+	ServiceName      string           `bson:"_id" json:"service_name"`
+	TotalCalls       int              `bson:"total_calls" json:"total_calls"`
+	FailedCalls      int              `bson:"failed_calls" json:"failed_calls"`
+	AvgProcessTimeMs float64          `bson:"avg_process_time_ms" json:"avg_process_time_ms"`
+	MaxProcessTimeMs int64            `bson:"max_process_time_ms" json:"max_process_time_ms"`
+	P50ProcessTimeMs int64            `bson:"p50_process_time_ms" json:"p50_process_time_ms"`
+	P90ProcessTimeMs int64            `bson:"p90_process_time_ms" json:"p90_process_time_ms"`
+	P95ProcessTimeMs int64            `bson:"p95_process_time_ms" json:"p95_process_time_ms"`
+	P99ProcessTimeMs int64            `bson:"p99_process_time_ms" json:"p99_process_time_ms"`
+	Errors           []ErrorCodeCount `bson:"-" json:"errors"`
+}
+
+// ErrorCodeCount is the number of failed calls with one error code
+type ErrorCodeCount struct {
+	ErrorCode string `json:"error_code"`
+	Count     int    `json:"count"`
+}
+
+// UsageErrorGroup counts failures of a service by stored error code, or by message for
+// records tracked before error codes were stored
+type UsageErrorGroup struct {
+	ServiceName string `bson:"service_name"`
+	ErrorCode   string `bson:"error_code"`
+	ErrorMsg    string `bson:"error_msg"`
+	Count       int    `bson:"count"`
+}
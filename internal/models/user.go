@@ -2,10 +2,10 @@
 package models
 
 import (
-	"errors"
-	"strings"
 	"time"
 
+	"chi-mongo-backend/pkg/validation"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,6 +15,13 @@ type User struct {
 	Email     string             `bson:"email" json:"email"`
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	// LastActiveAt is the time of the user's latest service call, kept current by usage tracking
+	LastActiveAt *time.Time `bson:"lastActiveAt,omitempty" json:"lastActiveAt,omitempty"`
+	// Search fields let the admin user search filter and sort on indexed fields of the user
+	// document alone; the credits and usage repositories keep them up to date
+	EmailLower string `bson:"emailLower" json:"-"`
+	Credits    int    `bson:"credits" json:"-"`    // Mirror of the credit balance
+	TotalCalls int    `bson:"totalCalls" json:"-"` // Calls up to the last daily rollup
 }
 
 type RegisterUserRequest struct {
@@ -23,19 +30,5 @@ type RegisterUserRequest struct {
 }
 
 func (r *RegisterUserRequest) Validate() error {
-	if strings.TrimSpace(r.UserID) == "" {
-		return errors.New("userId is required")
-	}
-	if strings.TrimSpace(r.Email) == "" {
-		return errors.New("email is required")
-	}
-	if !isValidEmail(r.Email) {
-		return errors.New("invalid email format")
-	}
-	return nil
+	return validation.Struct(r).Err()
 }
-
-func isValidEmail(email string) bool {
-	// Basic email validation - in production, use a proper validation library
-	return strings.Contains(email, "@") && strings.Contains(email, ".")
-}
\ No newline at end of file
@@ -5,10 +5,10 @@ import (
 	"context"
 
 	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/pkg/pagination"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // ActivityRepository interface is defined in interfaces.go
@@ -28,11 +28,9 @@ func (r *activityRepository) Create(ctx context.Context, activity *models.Activi
 	return err
 }
 
-func (r *activityRepository) GetByUserID(ctx context.Context, userID string) ([]models.ActivityLog, error) {
+func (r *activityRepository) GetByUserID(ctx context.Context, userID string, page *pagination.Params) ([]models.ActivityLog, error) {
 	// Sort by timestamp descending (most recent first)
-	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
-	
-	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	cursor, err := r.collection.Find(ctx, page.Filter(bson.M{"userId": userID}, "timestamp"), page.FindOptions("timestamp"))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,124 @@
+// internal/repository/admin_audit_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AdminAuditRepository is append-only: entries are never updated or deleted
+type AdminAuditRepository interface {
+	// Create fails with ErrConflict when another entry already holds the sequence
+	Create(ctx context.Context, entry *models.AdminAuditEntry) error
+	// GetLatest returns the entry with the highest sequence, or nil when the log is empty
+	GetLatest(ctx context.Context) (*models.AdminAuditEntry, error)
+	GetFiltered(ctx context.Context, filter *models.AdminAuditFilter, page *pagination.Params) ([]models.AdminAuditEntry, error)
+	// StreamAll calls fn for every entry in sequence order and stops at the first error
+	StreamAll(ctx context.Context, fn func(*models.AdminAuditEntry) error) error
+}
+
+type adminAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAdminAuditRepository(collection *mongo.Collection) AdminAuditRepository {
+	return &adminAuditRepository{
+		collection: collection,
+	}
+}
+
+func (r *adminAuditRepository) Create(ctx context.Context, entry *models.AdminAuditEntry) error {
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperrors.NewAppError(apperrors.ErrConflict, 409, "audit sequence already taken")
+		}
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *adminAuditRepository) GetLatest(ctx context.Context) (*models.AdminAuditEntry, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+
+	var entry models.AdminAuditEntry
+	if err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *adminAuditRepository) GetFiltered(ctx context.Context, filter *models.AdminAuditFilter, page *pagination.Params) ([]models.AdminAuditEntry, error) {
+	query := bson.M{}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["targetType"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["targetId"] = filter.TargetID
+	}
+	if filter.StartDate != nil || filter.EndDate != nil {
+		timestamp := bson.M{}
+		if filter.StartDate != nil {
+			timestamp["$gte"] = *filter.StartDate
+		}
+		if filter.EndDate != nil {
+			timestamp["$lte"] = *filter.EndDate
+		}
+		query["timestamp"] = timestamp
+	}
+
+	cursor, err := r.collection.Find(ctx, page.Filter(query, "timestamp"), page.FindOptions("timestamp"))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AdminAuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *adminAuditRepository) StreamAll(ctx context.Context, fn func(*models.AdminAuditEntry) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sequence", Value: 1}}).
+		SetBatchSize(1000)
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.AdminAuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,41 @@
+// internal/repository/admin_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AdminRepository interface {
+	// Touch records that the admin with this lowercased email was seen at the given time
+	Touch(ctx context.Context, email string, at time.Time) error
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+}
+
+type adminRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAdminRepository(collection *mongo.Collection) AdminRepository {
+	return &adminRepository{
+		collection: collection,
+	}
+}
+
+func (r *adminRepository) Touch(ctx context.Context, email string, at time.Time) error {
+	update := bson.M{"$max": bson.M{"lastSeenAt": at}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *adminRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"email": email}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
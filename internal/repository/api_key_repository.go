@@ -25,6 +25,9 @@ type APIKeyRepository interface {
 	DeleteByUserID(ctx context.Context, userID string) error // New method to delete by userID
 	UpdateLastUsed(ctx context.Context, keyHash string) error
 	GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// Expiry notice methods
+	GetExpiringUnnotified(ctx context.Context, before time.Time) ([]*models.APIKey, error)
+	MarkExpiryNotified(ctx context.Context, id primitive.ObjectID) (bool, error)
 }
 
 type apiKeyRepository struct {
@@ -189,4 +192,38 @@ func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, keyHash string) e
 		bson.M{"$set": update},
 	)
 	return err
-}
\ No newline at end of file
+}
+
+// GetExpiringUnnotified returns active keys expiring before the cutoff that have not been notified yet
+func (r *apiKeyRepository) GetExpiringUnnotified(ctx context.Context, before time.Time) ([]*models.APIKey, error) {
+	filter := bson.M{
+		"isActive":         true,
+		"expiresAt":        bson.M{"$gt": time.Now(), "$lte": before},
+		"expiryNotifiedAt": bson.M{"$exists": false},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var apiKeys []*models.APIKey
+	if err = cursor.All(ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// MarkExpiryNotified flags a key as notified; it returns false if another run already did
+func (r *apiKeyRepository) MarkExpiryNotified(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "expiryNotifiedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"expiryNotifiedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
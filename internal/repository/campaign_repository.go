@@ -0,0 +1,77 @@
+// internal/repository/campaign_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CampaignRepository interface {
+	Create(ctx context.Context, campaign *models.Campaign) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Campaign, error)
+	GetAll(ctx context.Context, page *pagination.Params) ([]models.Campaign, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type campaignRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCampaignRepository(collection *mongo.Collection) CampaignRepository {
+	return &campaignRepository{
+		collection: collection,
+	}
+}
+
+func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+	result, err := r.collection.InsertOne(ctx, campaign)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperrors.NewAppError(apperrors.ErrConflict, 409, "campaign name already exists")
+		}
+		return err
+	}
+
+	campaign.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *campaignRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Campaign, error) {
+	var campaign models.Campaign
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&campaign)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrNotFound, 404, "campaign not found")
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *campaignRepository) GetAll(ctx context.Context, page *pagination.Params) ([]models.Campaign, error) {
+	// Sort by creation date (newest first)
+	cursor, err := r.collection.Find(ctx, page.Filter(bson.M{}, "createdAt"), page.FindOptions("createdAt"))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var campaigns []models.Campaign
+	if err = cursor.All(ctx, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+func (r *campaignRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
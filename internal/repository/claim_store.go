@@ -0,0 +1,101 @@
+// internal/repository/claim_store.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// claimStore implements the claim protocol shared by idempotency records and processing
+// results. A request claims a key for its fingerprint, then either completes the claim with
+// its outcome or releases it so the key can be sent again. The key fields must be covered by
+// a unique index.
+type claimStore struct {
+	collection *mongo.Collection
+}
+
+// claim marks key as pending for the request with this fingerprint, also setting the fields
+// in set. It returns false when the key is completed, held by a live request or claimed for
+// another fingerprint.
+func (s claimStore) claim(ctx context.Context, key bson.M, fingerprint string, lockTTL, ttl time.Duration, set bson.M) (bool, error) {
+	now := time.Now()
+	// A pending claim whose lock ran out was left by a request that died; the same request
+	// may take it over
+	filter := bson.M{
+		"fingerprint": fingerprint,
+		"state":       models.ClaimStatePending,
+		"lockedUntil": bson.M{"$lt": now},
+	}
+	for field, value := range key {
+		filter[field] = value
+	}
+
+	fields := bson.M{
+		"fingerprint": fingerprint,
+		"state":       models.ClaimStatePending,
+		"lockedUntil": now.Add(lockTTL),
+		"createdAt":   now,
+		"expiresAt":   now.Add(ttl),
+	}
+	for field, value := range set {
+		fields[field] = value
+	}
+
+	// When the key exists but does not match, the upsert collides on the unique index
+	_, err := s.collection.UpdateOne(ctx, filter, bson.M{"$set": fields}, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// get decodes the claim on key into out and returns false when there is none
+func (s claimStore) get(ctx context.Context, key bson.M, out interface{}) (bool, error) {
+	if err := s.collection.FindOne(ctx, key).Decode(out); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// complete stores the outcome fields in set on a pending claim and keeps it for ttl
+func (s claimStore) complete(ctx context.Context, key bson.M, ttl time.Duration, set bson.M) error {
+	filter := bson.M{"state": models.ClaimStatePending}
+	for field, value := range key {
+		filter[field] = value
+	}
+
+	fields := bson.M{
+		"state":     models.ClaimStateCompleted,
+		"expiresAt": time.Now().Add(ttl),
+	}
+	for field, value := range set {
+		fields[field] = value
+	}
+
+	_, err := s.collection.UpdateOne(ctx, filter, bson.M{"$set": fields})
+	return err
+}
+
+// release drops a pending claim so the key can be sent again
+func (s claimStore) release(ctx context.Context, key bson.M) error {
+	filter := bson.M{"state": models.ClaimStatePending}
+	for field, value := range key {
+		filter[field] = value
+	}
+
+	_, err := s.collection.DeleteOne(ctx, filter)
+	return err
+}
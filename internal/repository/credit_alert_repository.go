@@ -0,0 +1,94 @@
+// internal/repository/credit_alert_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CreditAlertRepository interface {
+	GetByUserID(ctx context.Context, userID string) (*models.CreditAlertSettings, error)
+	Upsert(ctx context.Context, settings *models.CreditAlertSettings) error
+	// MarkTriggered flips the alert into the triggered state and reports whether this call did it
+	MarkTriggered(ctx context.Context, userID string) (bool, error)
+	ResetTriggered(ctx context.Context, userID string) error
+}
+
+type creditAlertRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCreditAlertRepository(collection *mongo.Collection) CreditAlertRepository {
+	return &creditAlertRepository{
+		collection: collection,
+	}
+}
+
+func (r *creditAlertRepository) GetByUserID(ctx context.Context, userID string) (*models.CreditAlertSettings, error) {
+	var settings models.CreditAlertSettings
+	err := r.collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&settings)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrNotFound, 404, "credit alert settings not found")
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *creditAlertRepository) Upsert(ctx context.Context, settings *models.CreditAlertSettings) error {
+	now := time.Now()
+	settings.UpdatedAt = now
+
+	update := bson.M{
+		"$set": bson.M{
+			"enabled":          settings.Enabled,
+			"threshold":        settings.Threshold,
+			"notifyEmail":      settings.NotifyEmail,
+			"webhookUrl":       settings.WebhookURL,
+			"autoTopUpEnabled": settings.AutoTopUpEnabled,
+			"autoTopUpAmount":  settings.AutoTopUpAmount,
+			"paymentMethodId":  settings.PaymentMethodID,
+			"triggered":        settings.Triggered,
+			"updatedAt":        now,
+		},
+		"$setOnInsert": bson.M{
+			"userId":    settings.UserID,
+			"createdAt": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"userId": settings.UserID}, update, opts).Decode(settings)
+}
+
+func (r *creditAlertRepository) MarkTriggered(ctx context.Context, userID string) (bool, error) {
+	now := time.Now()
+	// Only the caller that observes triggered=false wins, so concurrent deductions alert once
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"userId": userID, "enabled": true, "triggered": false},
+		bson.M{"$set": bson.M{"triggered": true, "lastTriggeredAt": now, "updatedAt": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *creditAlertRepository) ResetTriggered(ctx context.Context, userID string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"userId": userID, "triggered": true},
+		bson.M{"$set": bson.M{"triggered": false, "updatedAt": time.Now()}},
+	)
+	return err
+}
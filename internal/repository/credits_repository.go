@@ -4,6 +4,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"log"
 
 	"chi-mongo-backend/internal/models"
 	apperrors "chi-mongo-backend/pkg/errors"
@@ -11,15 +12,21 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type creditsRepository struct {
 	collection *mongo.Collection
+	users      *mongo.Collection
+	listener   BalanceListener
 }
 
-func NewCreditsRepository(collection *mongo.Collection) CreditsRepository {
+// NewCreditsRepository stores balances in collection and copies each one onto the matching
+// document in users, where the admin user search filters and sorts on it
+func NewCreditsRepository(collection, users *mongo.Collection) CreditsRepository {
 	return &creditsRepository{
 		collection: collection,
+		users:      users,
 	}
 }
 
@@ -30,6 +37,7 @@ func (r *creditsRepository) Create(ctx context.Context, credits *models.Credits)
 	}
 	
 	credits.ID = result.InsertedID.(primitive.ObjectID)
+	r.mirrorBalance(ctx, credits)
 	return nil
 }
 
@@ -45,30 +53,61 @@ func (r *creditsRepository) GetByUserID(ctx context.Context, userID string) (*mo
 	return &credits, nil
 }
 
+// SetBalanceListener registers a listener that is notified after every balance change
+func (r *creditsRepository) SetBalanceListener(listener BalanceListener) {
+	r.listener = listener
+}
+
 func (r *creditsRepository) UpdateCredits(ctx context.Context, userID string, amount int) error {
-	update := bson.M{"$inc": bson.M{"credits": amount}}
-	result, err := r.collection.UpdateOne(ctx, bson.M{"userId": userID}, update)
+	updated, err := r.changeBalance(ctx, bson.M{"userId": userID}, amount)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return apperrors.NewCreditsNotFoundError()
+		}
 		return err
 	}
-	if result.MatchedCount == 0 {
-		return apperrors.NewCreditsNotFoundError()
-	}
+
+	r.balanceChanged(ctx, updated, amount)
 	return nil
 }
 
+// DeductCredits checks the balance and deducts in one conditional update, so concurrent
+// deductions can never take the balance below zero
 func (r *creditsRepository) DeductCredits(ctx context.Context, userID string, amount int) error {
-	// First check if user has enough credits
-	credits, err := r.GetByUserID(ctx, userID)
+	updated, err := r.changeBalance(ctx, bson.M{"userId": userID, "credits": bson.M{"$gte": amount}}, -amount)
 	if err != nil {
-		return err
-	}
-	
-	if credits.Credits < amount {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		// Either the user has no credits record or not enough credits
+		if _, err := r.GetByUserID(ctx, userID); err != nil {
+			return err
+		}
 		return apperrors.NewInsufficientCreditsError()
 	}
-	
-	return r.UpdateCredits(ctx, userID, -amount)
+
+	r.balanceChanged(ctx, updated, -amount)
+	return nil
+}
+
+// changeBalance adds amount to the balance matched by filter and returns the new balance
+func (r *creditsRepository) changeBalance(ctx context.Context, filter bson.M, amount int) (*models.Credits, error) {
+	update := bson.M{"$inc": bson.M{"credits": amount, "version": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Credits
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// balanceChanged mirrors a changed balance onto the user and notifies the listener
+func (r *creditsRepository) balanceChanged(ctx context.Context, updated *models.Credits, amount int) {
+	r.mirrorBalance(ctx, updated)
+	if r.listener != nil {
+		r.listener.OnBalanceChanged(ctx, updated.UserID, updated.Credits-amount, updated.Credits)
+	}
 }
 
 func (r *creditsRepository) GetTotalCredits(ctx context.Context) (int64, error) {
@@ -101,41 +140,45 @@ func (r *creditsRepository) GetTotalCredits(ctx context.Context) (int64, error)
 	return 0, nil // No credits found
 }
 
-func (r *creditsRepository) GetAllWithUsers(ctx context.Context) ([]models.AdminUser, error) {
+// mirrorBalance copies a balance onto the user document unless a newer version is already
+// there, so concurrent changes finishing out of order leave the latest balance. A failed
+// copy is logged rather than failing the balance change; SyncUserBalances repairs it.
+func (r *creditsRepository) mirrorBalance(ctx context.Context, credits *models.Credits) {
+	filter := bson.M{"userId": credits.UserID, "creditsVersion": bson.M{"$not": bson.M{"$gte": credits.Version}}}
+	update := bson.M{"$set": bson.M{"credits": credits.Credits, "creditsVersion": credits.Version}}
+	if _, err := r.users.UpdateOne(ctx, filter, update); err != nil {
+		log.Printf("Failed to copy balance of user %s to the user document: %v", credits.UserID, err)
+	}
+}
+
+func (r *creditsRepository) SyncUserBalances(ctx context.Context) error {
 	pipeline := []bson.M{
-		{
-			"$lookup": bson.M{
-				"from":         "users", // Assuming your users collection is named "users"
-				"localField":   "userId",
-				"foreignField": "userId", 
-				"as":           "userInfo",
+		{"$project": bson.M{
+			"_id":     0,
+			"userId":  1,
+			"credits": 1,
+			"version": bson.M{"$ifNull": []interface{}{"$version", 0}},
+		}},
+		{"$merge": bson.M{
+			"into": r.users.Name(),
+			"on":   "userId",
+			"whenMatched": []bson.M{
+				{"$set": bson.M{
+					"credits": bson.M{"$cond": []interface{}{
+						bson.M{"$gte": []interface{}{"$$new.version", bson.M{"$ifNull": []interface{}{"$creditsVersion", -1}}}},
+						"$$new.credits",
+						"$credits",
+					}},
+					"creditsVersion": bson.M{"$max": []interface{}{"$$new.version", "$creditsVersion"}},
+				}},
 			},
-		},
-		{
-			"$unwind": "$userInfo",
-		},
-		{
-			"$project": bson.M{
-				"_id":       "$userInfo._id",
-				"userId":    "$userId",
-				"email":     "$userInfo.email",
-				"credits":   "$credits",
-				"createdAt": "$userInfo.createdAt",
-				"updatedAt": "$userInfo.updatedAt",
-			},
-		},
+			"whenNotMatched": "discard",
+		}},
 	}
-	
+
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-	
-	var adminUsers []models.AdminUser
-	if err = cursor.All(ctx, &adminUsers); err != nil {
-		return nil, err
+		return err
 	}
-	
-	return adminUsers, nil
-}
\ No newline at end of file
+	return cursor.Close(ctx)
+}
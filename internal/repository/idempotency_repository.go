@@ -0,0 +1,59 @@
+// internal/repository/idempotency_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type IdempotencyRepository interface {
+	// Claim marks the key as in progress for this request. It returns false when the key
+	// already has a stored response, is held by a live request or belongs to another request.
+	Claim(ctx context.Context, id, scope, fingerprint string, lockTTL, ttl time.Duration) (bool, error)
+	// Get returns nil when the key is unknown or has expired
+	Get(ctx context.Context, id string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, id string, response *models.IdempotentResponse, ttl time.Duration) error
+	// Release drops an unfinished claim so the request can be retried
+	Release(ctx context.Context, id string) error
+}
+
+type idempotencyRepository struct {
+	claims claimStore
+}
+
+func NewIdempotencyRepository(collection *mongo.Collection) IdempotencyRepository {
+	return &idempotencyRepository{
+		claims: claimStore{collection: collection},
+	}
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, id, scope, fingerprint string, lockTTL, ttl time.Duration) (bool, error) {
+	return r.claims.claim(ctx, bson.M{"_id": id}, fingerprint, lockTTL, ttl, bson.M{"scope": scope})
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, id string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	found, err := r.claims.get(ctx, bson.M{"_id": id}, &record)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, id string, response *models.IdempotentResponse, ttl time.Duration) error {
+	return r.claims.complete(ctx, bson.M{"_id": id}, ttl, bson.M{
+		"statusCode":  response.StatusCode,
+		"header":      response.Header,
+		"body":        response.Body,
+		"bodyOmitted": response.BodyOmitted,
+	})
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, id string) error {
+	return r.claims.release(ctx, bson.M{"_id": id})
+}
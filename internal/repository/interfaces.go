@@ -3,8 +3,10 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/pkg/pagination"
 )
 
 type UserRepository interface {
@@ -14,6 +16,12 @@ type UserRepository interface {
 	Delete(ctx context.Context, userID string) error
 	// Admin methods
 	GetAll(ctx context.Context) ([]models.User, error)
+	// Search returns a page of users joined with their balances and activity
+	Search(ctx context.Context, filter *models.AdminUserFilter, page *pagination.Params) ([]models.AdminUser, error)
+	// TouchLastActive moves the user's lastActiveAt forward to at
+	TouchLastActive(ctx context.Context, userID string, at time.Time) error
+	// BackfillSearchFields fills the admin search fields of users stored before they existed
+	BackfillSearchFields(ctx context.Context) error
 	GetTotalCount(ctx context.Context) (int64, error)
 }
 
@@ -22,12 +30,19 @@ type CreditsRepository interface {
 	GetByUserID(ctx context.Context, userID string) (*models.Credits, error)
 	UpdateCredits(ctx context.Context, userID string, amount int) error
 	DeductCredits(ctx context.Context, userID string, amount int) error
+	SetBalanceListener(listener BalanceListener)
+	// SyncUserBalances copies every balance onto its user document for the admin user search
+	SyncUserBalances(ctx context.Context) error
 	// Admin methods
 	GetTotalCredits(ctx context.Context) (int64, error)
-	GetAllWithUsers(ctx context.Context) ([]models.AdminUser, error)
+}
+
+// BalanceListener is notified after a user's credit balance changes
+type BalanceListener interface {
+	OnBalanceChanged(ctx context.Context, userID string, previousBalance, currentBalance int)
 }
 
 type ActivityRepository interface {
 	Create(ctx context.Context, activity *models.ActivityLog) error
-	GetByUserID(ctx context.Context, userID string) ([]models.ActivityLog, error)
+	GetByUserID(ctx context.Context, userID string, page *pagination.Params) ([]models.ActivityLog, error)
 }
\ No newline at end of file
@@ -0,0 +1,92 @@
+// internal/repository/job_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobLockRepository interface {
+	// Acquire takes the lock for one scheduled activation of a job. It returns false when
+	// another replica holds the lock or has already run that activation.
+	Acquire(ctx context.Context, jobName, owner string, scheduledFor time.Time, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, jobName, owner string) error
+}
+
+type JobRunRepository interface {
+	Create(ctx context.Context, run *models.JobRun) error
+}
+
+type jobLockRepository struct {
+	collection *mongo.Collection
+}
+
+func NewJobLockRepository(collection *mongo.Collection) JobLockRepository {
+	return &jobLockRepository{
+		collection: collection,
+	}
+}
+
+func (r *jobLockRepository) Acquire(ctx context.Context, jobName, owner string, scheduledFor time.Time, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":          jobName,
+		"scheduledFor": bson.M{"$lt": scheduledFor},
+		"lockedUntil":  bson.M{"$lt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":        owner,
+			"scheduledFor": scheduledFor,
+			"lockedUntil":  now.Add(ttl),
+			"acquiredAt":   now,
+		},
+	}
+
+	// When the lock document exists but does not match, the upsert collides on _id
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Release frees the lock early. scheduledFor is kept so the same activation never runs twice.
+func (r *jobLockRepository) Release(ctx context.Context, jobName, owner string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": jobName, "owner": owner},
+		bson.M{"$set": bson.M{"lockedUntil": time.Now()}},
+	)
+	return err
+}
+
+type jobRunRepository struct {
+	collection *mongo.Collection
+}
+
+func NewJobRunRepository(collection *mongo.Collection) JobRunRepository {
+	return &jobRunRepository{
+		collection: collection,
+	}
+}
+
+func (r *jobRunRepository) Create(ctx context.Context, run *models.JobRun) error {
+	result, err := r.collection.InsertOne(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	run.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
@@ -0,0 +1,64 @@
+// internal/repository/processing_result_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ProcessingResultRepository interface {
+	// Claim marks (user, service, req_id) as being processed for the request with this
+	// fingerprint. It returns false when the req_id already has a result, is held by a live
+	// request or was sent with a different body.
+	Claim(ctx context.Context, userID, service, reqID, fingerprint string, lockTTL, window time.Duration) (bool, error)
+	// Get returns nil when there is no result for the req_id
+	Get(ctx context.Context, userID, service, reqID string) (*models.ProcessingResult, error)
+	Complete(ctx context.Context, userID, service, reqID string, result []byte, creditsUsed int, window time.Duration) error
+	// Release drops an unfinished claim so the req_id can be sent again
+	Release(ctx context.Context, userID, service, reqID string) error
+}
+
+type processingResultRepository struct {
+	claims claimStore
+}
+
+func NewProcessingResultRepository(collection *mongo.Collection) ProcessingResultRepository {
+	return &processingResultRepository{
+		claims: claimStore{collection: collection},
+	}
+}
+
+// resultKey identifies a result; the fields are covered by a unique index
+func resultKey(userID, service, reqID string) bson.M {
+	return bson.M{"userId": userID, "service": service, "reqId": reqID}
+}
+
+func (r *processingResultRepository) Claim(ctx context.Context, userID, service, reqID, fingerprint string, lockTTL, window time.Duration) (bool, error) {
+	return r.claims.claim(ctx, resultKey(userID, service, reqID), fingerprint, lockTTL, window, bson.M{"creditsUsed": 0})
+}
+
+func (r *processingResultRepository) Get(ctx context.Context, userID, service, reqID string) (*models.ProcessingResult, error) {
+	var result models.ProcessingResult
+	found, err := r.claims.get(ctx, resultKey(userID, service, reqID), &result)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *processingResultRepository) Complete(ctx context.Context, userID, service, reqID string, result []byte, creditsUsed int, window time.Duration) error {
+	return r.claims.complete(ctx, resultKey(userID, service, reqID), window, bson.M{
+		"result":      result,
+		"creditsUsed": creditsUsed,
+		"completedAt": time.Now(),
+	})
+}
+
+func (r *processingResultRepository) Release(ctx context.Context, userID, service, reqID string) error {
+	return r.claims.release(ctx, resultKey(userID, service, reqID))
+}
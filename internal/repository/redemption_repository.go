@@ -0,0 +1,130 @@
+// internal/repository/redemption_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RedemptionRepository interface {
+	// Create fails with ErrConflict when the (token, user, sequence) slot is already taken
+	Create(ctx context.Context, redemption *models.TokenRedemption) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// UsedSequences returns the sequences the user holds on the token; a rolled back
+	// redemption leaves a gap that can be claimed again
+	UsedSequences(ctx context.Context, tokenID primitive.ObjectID, userID string) ([]int, error)
+	GetByTokenID(ctx context.Context, tokenID primitive.ObjectID, page *pagination.Params) ([]models.TokenRedemption, error)
+	// UpdateStatus moves an in-flight redemption along; an empty status marks it completed
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error
+	// GetStale returns in-flight redemptions started before the cutoff
+	GetStale(ctx context.Context, before time.Time) ([]models.TokenRedemption, error)
+	// DeleteIfStatus deletes a redemption only if it is still in the given status
+	DeleteIfStatus(ctx context.Context, id primitive.ObjectID, status string) (bool, error)
+}
+
+type redemptionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRedemptionRepository(collection *mongo.Collection) RedemptionRepository {
+	return &redemptionRepository{
+		collection: collection,
+	}
+}
+
+func (r *redemptionRepository) Create(ctx context.Context, redemption *models.TokenRedemption) error {
+	result, err := r.collection.InsertOne(ctx, redemption)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperrors.NewAppError(apperrors.ErrConflict, 409, "redemption already recorded")
+		}
+		return err
+	}
+
+	redemption.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *redemptionRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *redemptionRepository) UsedSequences(ctx context.Context, tokenID primitive.ObjectID, userID string) ([]int, error) {
+	opts := options.Find().SetProjection(bson.M{"sequence": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"tokenId": tokenID, "userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var redemptions []models.TokenRedemption
+	if err = cursor.All(ctx, &redemptions); err != nil {
+		return nil, err
+	}
+	sequences := make([]int, len(redemptions))
+	for i, redemption := range redemptions {
+		sequences[i] = redemption.Sequence
+	}
+	return sequences, nil
+}
+
+func (r *redemptionRepository) GetByTokenID(ctx context.Context, tokenID primitive.ObjectID, page *pagination.Params) ([]models.TokenRedemption, error) {
+	// Sort by redemption time (newest first)
+	filter := page.Filter(bson.M{"tokenId": tokenID}, "redeemedAt")
+	cursor, err := r.collection.Find(ctx, filter, page.FindOptions("redeemedAt"))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var redemptions []models.TokenRedemption
+	if err = cursor.All(ctx, &redemptions); err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+func (r *redemptionRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	update := bson.M{"$set": bson.M{"status": status}}
+	if status == "" {
+		update = bson.M{"$unset": bson.M{"status": ""}}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *redemptionRepository) GetStale(ctx context.Context, before time.Time) ([]models.TokenRedemption, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status":     bson.M{"$in": []string{models.RedemptionStatusPending, models.RedemptionStatusReserved}},
+		"redeemedAt": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var redemptions []models.TokenRedemption
+	if err = cursor.All(ctx, &redemptions); err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+func (r *redemptionRepository) DeleteIfStatus(ctx context.Context, id primitive.ObjectID, status string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "status": status})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,62 @@
+// internal/repository/result_retention_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ResultRetentionRepository interface {
+	// GetByUserID returns nil when the user never changed their retention settings
+	GetByUserID(ctx context.Context, userID string) (*models.ResultRetentionSettings, error)
+	Upsert(ctx context.Context, settings *models.ResultRetentionSettings) error
+}
+
+type resultRetentionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewResultRetentionRepository(collection *mongo.Collection) ResultRetentionRepository {
+	return &resultRetentionRepository{
+		collection: collection,
+	}
+}
+
+func (r *resultRetentionRepository) GetByUserID(ctx context.Context, userID string) (*models.ResultRetentionSettings, error) {
+	var settings models.ResultRetentionSettings
+	err := r.collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&settings)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *resultRetentionRepository) Upsert(ctx context.Context, settings *models.ResultRetentionSettings) error {
+	now := time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"enabled":       settings.Enabled,
+			"retentionDays": settings.RetentionDays,
+			"dedupEnabled":  settings.DedupEnabled,
+			"updatedAt":     now,
+		},
+		"$setOnInsert": bson.M{
+			"userId":    settings.UserID,
+			"createdAt": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"userId": settings.UserID}, update, opts).Decode(settings)
+}
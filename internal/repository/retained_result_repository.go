@@ -0,0 +1,90 @@
+// internal/repository/retained_result_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RetainedResultRepository interface {
+	Create(ctx context.Context, result *models.RetainedResult) error
+	// GetByID returns nil when the user has no unexpired result with the ID
+	GetByID(ctx context.Context, userID string, id primitive.ObjectID) (*models.RetainedResult, error)
+	// GetLatestByReqID returns the user's newest unexpired result for the req_id, or nil
+	GetLatestByReqID(ctx context.Context, userID, service, reqID string) (*models.RetainedResult, error)
+	// GetExpired returns up to limit results that expired before the given time, oldest first
+	GetExpired(ctx context.Context, before time.Time, limit int) ([]models.RetainedResult, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type retainedResultRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRetainedResultRepository(collection *mongo.Collection) RetainedResultRepository {
+	return &retainedResultRepository{
+		collection: collection,
+	}
+}
+
+func (r *retainedResultRepository) Create(ctx context.Context, result *models.RetainedResult) error {
+	if result.ID.IsZero() {
+		result.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, result)
+	return err
+}
+
+func (r *retainedResultRepository) GetByID(ctx context.Context, userID string, id primitive.ObjectID) (*models.RetainedResult, error) {
+	filter := bson.M{"_id": id, "userId": userID, "expiresAt": bson.M{"$gt": time.Now()}}
+	return r.findOne(ctx, filter, options.FindOne())
+}
+
+func (r *retainedResultRepository) GetLatestByReqID(ctx context.Context, userID, service, reqID string) (*models.RetainedResult, error) {
+	filter := bson.M{"userId": userID, "service": service, "reqId": reqID, "expiresAt": bson.M{"$gt": time.Now()}}
+	return r.findOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+}
+
+func (r *retainedResultRepository) findOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*models.RetainedResult, error) {
+	var result models.RetainedResult
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *retainedResultRepository) GetExpired(ctx context.Context, before time.Time, limit int) ([]models.RetainedResult, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "expiresAt", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"expiresAt": bson.M{"$lte": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.RetainedResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *retainedResultRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,77 @@
+// internal/repository/token_audit_repository.go
+package repository
+
+import (
+	"context"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenAuditRepository is append-only: events are never updated or deleted
+type TokenAuditRepository interface {
+	Create(ctx context.Context, event *models.TokenAuditEvent) error
+	CreateMany(ctx context.Context, events []*models.TokenAuditEvent) error
+	GetByTokenID(ctx context.Context, tokenID primitive.ObjectID) ([]models.TokenAuditEvent, error)
+}
+
+type tokenAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTokenAuditRepository(collection *mongo.Collection) TokenAuditRepository {
+	return &tokenAuditRepository{
+		collection: collection,
+	}
+}
+
+func (r *tokenAuditRepository) Create(ctx context.Context, event *models.TokenAuditEvent) error {
+	result, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *tokenAuditRepository) CreateMany(ctx context.Context, events []*models.TokenAuditEvent) error {
+	for start := 0; start < len(events); start += tokenInsertBatchSize {
+		end := start + tokenInsertBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		docs := make([]interface{}, 0, end-start)
+		for _, event := range events[start:end] {
+			docs = append(docs, event)
+		}
+
+		// Unordered so one bad document does not drop the rest of the batch
+		if _, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *tokenAuditRepository) GetByTokenID(ctx context.Context, tokenID primitive.ObjectID) ([]models.TokenAuditEvent, error) {
+	// Oldest first so the trail reads as a timeline
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"tokenId": tokenID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.TokenAuditEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
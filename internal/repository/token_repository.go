@@ -8,6 +8,7 @@ import (
 
 	"chi-mongo-backend/internal/models"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,16 +18,36 @@ import (
 
 type TokenRepository interface {
 	Create(ctx context.Context, token *models.CreditToken) error
+	CreateMany(ctx context.Context, tokens []*models.CreditToken) error
 	GetByToken(ctx context.Context, token string) (*models.CreditToken, error)
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.CreditToken, error)  // Add this line
 	MarkAsUsed(ctx context.Context, token string, userID string) error
-	GetByCreatedBy(ctx context.Context, createdBy string) ([]*models.CreditToken, error)
-	GetAll(ctx context.Context) ([]*models.CreditToken, error)
-	GetByStatus(ctx context.Context, isUsed bool) ([]*models.CreditToken, error)
+	// ClaimUnused atomically marks an unused, unexpired single-use token as used by userID
+	ClaimUnused(ctx context.Context, token string, userID string) (*models.CreditToken, error)
+	ReleaseClaim(ctx context.Context, token string, userID string) error
+	// List methods return newest first, one page at a time (see pkg/pagination)
+	GetByCreatedBy(ctx context.Context, createdBy string, page *pagination.Params) ([]*models.CreditToken, error)
+	GetAll(ctx context.Context, page *pagination.Params) ([]*models.CreditToken, error)
+	GetByStatus(ctx context.Context, isUsed bool, page *pagination.Params) ([]*models.CreditToken, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error                           // Add this line
-	DeleteExpiredTokens(ctx context.Context) error
+	// DeleteExpiredByIDs deletes the given tokens if they are still expired and unredeemed
+	DeleteExpiredByIDs(ctx context.Context, ids []primitive.ObjectID) error
+	GetExpiredUnused(ctx context.Context) ([]*models.CreditToken, error)
+	// Revocation and ownership methods
+	Revoke(ctx context.Context, id primitive.ObjectID, revokedBy string, reason string) (*models.CreditToken, error)
+	TransferOwnership(ctx context.Context, id primitive.ObjectID, fromAdmin string, toAdmin string) (*models.CreditToken, error)
+	// Promo code methods
+	ReserveRedemption(ctx context.Context, id primitive.ObjectID) (*models.CreditToken, error)
+	ReleaseRedemption(ctx context.Context, id primitive.ObjectID) error
+	// Campaign methods
+	GetByCampaign(ctx context.Context, campaignID primitive.ObjectID) ([]*models.CreditToken, error)
+	DeleteByCampaign(ctx context.Context, campaignID primitive.ObjectID) error
+	GetCampaignAnalytics(ctx context.Context, campaignID primitive.ObjectID) (*models.CampaignAnalytics, error)
 }
 
+// tokenInsertBatchSize bounds the size of each InsertMany call during bulk generation
+const tokenInsertBatchSize = 1000
+
 type tokenRepository struct {
 	collection *mongo.Collection
 }
@@ -40,6 +61,9 @@ func NewTokenRepository(collection *mongo.Collection) TokenRepository {
 func (r *tokenRepository) Create(ctx context.Context, token *models.CreditToken) error {
 	result, err := r.collection.InsertOne(ctx, token)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperrors.NewAppError(apperrors.ErrConflict, 409, "token code already exists")
+		}
 		return err
 	}
 	
@@ -47,6 +71,29 @@ func (r *tokenRepository) Create(ctx context.Context, token *models.CreditToken)
 	return nil
 }
 
+func (r *tokenRepository) CreateMany(ctx context.Context, tokens []*models.CreditToken) error {
+	for start := 0; start < len(tokens); start += tokenInsertBatchSize {
+		end := start + tokenInsertBatchSize
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+
+		docs := make([]interface{}, 0, end-start)
+		for _, token := range tokens[start:end] {
+			docs = append(docs, token)
+		}
+
+		result, err := r.collection.InsertMany(ctx, docs)
+		if err != nil {
+			return err
+		}
+		for i, id := range result.InsertedIDs {
+			tokens[start+i].ID = id.(primitive.ObjectID)
+		}
+	}
+	return nil
+}
+
 func (r *tokenRepository) GetByToken(ctx context.Context, token string) (*models.CreditToken, error) {
 	var creditToken models.CreditToken
 	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&creditToken)
@@ -79,33 +126,67 @@ func (r *tokenRepository) MarkAsUsed(ctx context.Context, token string, userID s
 	return nil
 }
 
-func (r *tokenRepository) GetByCreatedBy(ctx context.Context, createdBy string) ([]*models.CreditToken, error) {
-	// Sort by creation date (newest first)
-	opts := options.Find().SetSort(bson.M{"createdAt": -1})
-	
-	cursor, err := r.collection.Find(ctx, bson.M{"createdBy": createdBy}, opts)
-	if err != nil {
-		return nil, err
+func (r *tokenRepository) ClaimUnused(ctx context.Context, token string, userID string) (*models.CreditToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"token":     token,
+		"isUsed":    false,
+		"isRevoked": bson.M{"$ne": true},
+		"expiresAt": bson.M{"$gt": now},
+		// Promo codes are redeemed through the redemptions collection instead
+		"$or": []bson.M{
+			{"maxRedemptions": bson.M{"$exists": false}},
+			{"maxRedemptions": 0},
+		},
 	}
-	defer cursor.Close(ctx)
+	update := bson.M{
+		"$set": bson.M{
+			"isUsed": true,
+			"usedBy": userID,
+			"usedAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	var tokens []*models.CreditToken
-	for cursor.Next(ctx) {
-		var token models.CreditToken
-		if err := cursor.Decode(&token); err != nil {
-			return nil, err
+	var creditToken models.CreditToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&creditToken)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrConflict, 409, "token is not available for redemption")
 		}
-		tokens = append(tokens, &token)
+		return nil, err
 	}
+	return &creditToken, nil
+}
 
-	return tokens, cursor.Err()
+// ReleaseClaim reverts a ClaimUnused when crediting the user fails
+func (r *tokenRepository) ReleaseClaim(ctx context.Context, token string, userID string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"token": token, "isUsed": true, "usedBy": userID},
+		bson.M{
+			"$set":   bson.M{"isUsed": false},
+			"$unset": bson.M{"usedBy": "", "usedAt": ""},
+		},
+	)
+	return err
 }
 
-func (r *tokenRepository) GetAll(ctx context.Context) ([]*models.CreditToken, error) {
-	// Sort by creation date (newest first)
-	opts := options.Find().SetSort(bson.M{"createdAt": -1})
-	
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+func (r *tokenRepository) GetByCreatedBy(ctx context.Context, createdBy string, page *pagination.Params) ([]*models.CreditToken, error) {
+	return r.findPage(ctx, bson.M{"createdBy": createdBy}, page)
+}
+
+func (r *tokenRepository) GetAll(ctx context.Context, page *pagination.Params) ([]*models.CreditToken, error) {
+	return r.findPage(ctx, bson.M{}, page)
+}
+
+func (r *tokenRepository) GetByStatus(ctx context.Context, isUsed bool, page *pagination.Params) ([]*models.CreditToken, error) {
+	return r.findPage(ctx, bson.M{"isUsed": isUsed}, page)
+}
+
+// findPage returns one page of tokens matching filter, newest first
+func (r *tokenRepository) findPage(ctx context.Context, filter bson.M, page *pagination.Params) ([]*models.CreditToken, error) {
+	cursor, err := r.collection.Find(ctx, page.Filter(filter, "createdAt"), page.FindOptions("createdAt"))
 	if err != nil {
 		return nil, err
 	}
@@ -123,36 +204,88 @@ func (r *tokenRepository) GetAll(ctx context.Context) ([]*models.CreditToken, er
 	return tokens, cursor.Err()
 }
 
-func (r *tokenRepository) GetByStatus(ctx context.Context, isUsed bool) ([]*models.CreditToken, error) {
-	filter := bson.M{"isUsed": isUsed}
-	
-	// Sort by creation date (newest first)
-	opts := options.Find().SetSort(bson.M{"createdAt": -1})
-	
-	cursor, err := r.collection.Find(ctx, filter, opts)
+// expiredUnusedFilter matches tokens that expired without being redeemed. Revoked tokens
+// are kept so the revocation reason stays visible on the token itself, campaign tokens so
+// campaign analytics and exports still count them, and promo codes that were redeemed so
+// their redemptions keep pointing at a token.
+func expiredUnusedFilter(now time.Time) bson.M {
+	return bson.M{
+		"expiresAt":       bson.M{"$lt": now},
+		"isUsed":          false,
+		"isRevoked":       bson.M{"$ne": true},
+		"campaignId":      bson.M{"$exists": false},
+		"redemptionCount": bson.M{"$not": bson.M{"$gt": 0}},
+	}
+}
+
+func (r *tokenRepository) DeleteExpiredByIDs(ctx context.Context, ids []primitive.ObjectID) error {
+	filter := expiredUnusedFilter(time.Now())
+	filter["_id"] = bson.M{"$in": ids}
+	_, err := r.collection.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *tokenRepository) GetExpiredUnused(ctx context.Context) ([]*models.CreditToken, error) {
+	cursor, err := r.collection.Find(ctx, expiredUnusedFilter(time.Now()))
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var tokens []*models.CreditToken
-	for cursor.Next(ctx) {
-		var token models.CreditToken
-		if err := cursor.Decode(&token); err != nil {
-			return nil, err
-		}
-		tokens = append(tokens, &token)
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
 	}
-
-	return tokens, cursor.Err()
+	return tokens, nil
 }
 
-func (r *tokenRepository) DeleteExpiredTokens(ctx context.Context) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{
-		"expiresAt": bson.M{"$lt": time.Now()},
+// Revoke soft-revokes a token that has not been redeemed or revoked yet. The conditional
+// update means a token redeemed concurrently with the revocation is never marked revoked.
+func (r *tokenRepository) Revoke(ctx context.Context, id primitive.ObjectID, revokedBy string, reason string) (*models.CreditToken, error) {
+	filter := bson.M{
+		"_id":       id,
 		"isUsed":    false,
-	})
-	return err
+		"isRevoked": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"isRevoked":    true,
+			"revokedBy":    revokedBy,
+			"revokedAt":    time.Now(),
+			"revokeReason": reason,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var creditToken models.CreditToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&creditToken)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrConflict, 409, "token has already been used or revoked")
+		}
+		return nil, err
+	}
+	return &creditToken, nil
+}
+
+// TransferOwnership moves a token to another admin, provided it is still owned by fromAdmin
+func (r *tokenRepository) TransferOwnership(ctx context.Context, id primitive.ObjectID, fromAdmin string, toAdmin string) (*models.CreditToken, error) {
+	filter := bson.M{
+		"_id":       id,
+		"createdBy": fromAdmin,
+	}
+	update := bson.M{"$set": bson.M{"createdBy": toAdmin}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var creditToken models.CreditToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&creditToken)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrConflict, 409, "token ownership changed during transfer")
+		}
+		return nil, err
+	}
+	return &creditToken, nil
 }
 
 func (r *tokenRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.CreditToken, error) {
@@ -176,4 +309,169 @@ func (r *tokenRepository) Delete(ctx context.Context, id primitive.ObjectID) err
 		return apperrors.NewAppError(apperrors.ErrNotFound, 404, "token not found")
 	}
 	return nil
+}
+
+// ReserveRedemption atomically claims one redemption slot on a promo code that is
+// active and below its total limit. Concurrent callers can never exceed maxRedemptions.
+func (r *tokenRepository) ReserveRedemption(ctx context.Context, id primitive.ObjectID) (*models.CreditToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":       id,
+		"isRevoked": bson.M{"$ne": true},
+		"expiresAt": bson.M{"$gt": now},
+		"$expr":     bson.M{"$lt": []interface{}{"$redemptionCount", "$maxRedemptions"}},
+		"$or": []bson.M{
+			{"startsAt": bson.M{"$exists": false}},
+			{"startsAt": nil},
+			{"startsAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$inc": bson.M{"redemptionCount": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var creditToken models.CreditToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&creditToken)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "promo code is no longer available")
+		}
+		return nil, err
+	}
+	return &creditToken, nil
+}
+
+// ReleaseRedemption returns a slot claimed by ReserveRedemption when redemption fails
+func (r *tokenRepository) ReleaseRedemption(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "redemptionCount": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"redemptionCount": -1}},
+	)
+	return err
+}
+
+func (r *tokenRepository) GetByCampaign(ctx context.Context, campaignID primitive.ObjectID) ([]*models.CreditToken, error) {
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"campaignId": campaignID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.CreditToken
+	for cursor.Next(ctx) {
+		var token models.CreditToken
+		if err := cursor.Decode(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, cursor.Err()
+}
+
+func (r *tokenRepository) DeleteByCampaign(ctx context.Context, campaignID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"campaignId": campaignID})
+	return err
+}
+
+func (r *tokenRepository) GetCampaignAnalytics(ctx context.Context, campaignID primitive.ObjectID) (*models.CampaignAnalytics, error) {
+	now := time.Now()
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"campaignId": campaignID},
+		},
+		{
+			"$facet": bson.M{
+				"totals": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":          nil,
+							"total_tokens": bson.M{"$sum": 1},
+							"redeemed_count": bson.M{
+								"$sum": bson.M{"$cond": bson.M{"if": "$isUsed", "then": 1, "else": 0}},
+							},
+							"expired_count": bson.M{
+								"$sum": bson.M{"$cond": bson.M{
+									"if": bson.M{"$and": []interface{}{
+										bson.M{"$not": []interface{}{"$isUsed"}},
+										bson.M{"$lt": []interface{}{"$expiresAt", now}},
+									}},
+									"then": 1,
+									"else": 0,
+								}},
+							},
+							"credits_issued": bson.M{"$sum": "$credits"},
+							"credits_redeemed": bson.M{
+								"$sum": bson.M{"$cond": bson.M{"if": "$isUsed", "then": "$credits", "else": 0}},
+							},
+						},
+					},
+				},
+				"timeline": []bson.M{
+					{
+						"$match": bson.M{"isUsed": true},
+					},
+					{
+						"$group": bson.M{
+							"_id": bson.M{
+								"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$usedAt"},
+							},
+							"redeemed":         bson.M{"$sum": 1},
+							"credits_redeemed": bson.M{"$sum": "$credits"},
+						},
+					},
+					{
+						"$sort": bson.M{"_id": 1},
+					},
+				},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Totals []struct {
+			TotalTokens     int `bson:"total_tokens"`
+			RedeemedCount   int `bson:"redeemed_count"`
+			ExpiredCount    int `bson:"expired_count"`
+			CreditsIssued   int `bson:"credits_issued"`
+			CreditsRedeemed int `bson:"credits_redeemed"`
+		} `bson:"totals"`
+		Timeline []models.CampaignTimelinePoint `bson:"timeline"`
+	}
+
+	analytics := &models.CampaignAnalytics{
+		Timeline: []models.CampaignTimelinePoint{},
+	}
+	if !cursor.Next(ctx) {
+		return analytics, cursor.Err()
+	}
+	if err := cursor.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Totals) > 0 {
+		totals := result.Totals[0]
+		analytics.TotalTokens = totals.TotalTokens
+		analytics.RedeemedCount = totals.RedeemedCount
+		analytics.UnredeemedCount = totals.TotalTokens - totals.RedeemedCount
+		analytics.ExpiredCount = totals.ExpiredCount
+		analytics.CreditsIssued = totals.CreditsIssued
+		analytics.CreditsRedeemed = totals.CreditsRedeemed
+		if totals.TotalTokens > 0 {
+			analytics.RedemptionRate = float64(totals.RedeemedCount) / float64(totals.TotalTokens)
+		}
+	}
+	if result.Timeline != nil {
+		analytics.Timeline = result.Timeline
+	}
+
+	return analytics, nil
 }
\ No newline at end of file
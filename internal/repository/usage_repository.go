@@ -4,9 +4,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sort"
 	"time"
 
 	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,12 +19,25 @@ import (
 )
 
 type UsageRepository interface {
+	// CreateUsage fails with ErrConflict when the user already has a record with the idempotency key
 	CreateUsage(ctx context.Context, usage *models.ServiceUsage) error
+	// GetByIdempotencyKey returns nil when the user has no record with the key
+	GetByIdempotencyKey(ctx context.Context, userID, key string) (*models.ServiceUsage, error)
+	DeleteUsage(ctx context.Context, id primitive.ObjectID) error
+	// GetUsageByID returns nil when there is no record with the ID
+	GetUsageByID(ctx context.Context, id primitive.ObjectID) (*models.ServiceUsage, error)
 	GetGlobalStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UsageStats, error)
 	GetUserStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UserUsageStats, error)
 	GetServiceUserStats(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServiceUserStats, error)
-	GetUserUsageHistory(ctx context.Context, userID string, limit, skip int) ([]models.ServiceUsage, error)
-	GetServiceUsageHistory(ctx context.Context, serviceName string, limit, skip int) ([]models.ServiceUsage, error)
+	GetTimeSeries(ctx context.Context, filter *models.UsageTimeSeriesFilter) ([]models.UsageTimeSeriesPoint, error)
+	GetServicePerformance(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServicePerformanceStats, error)
+	GetErrorGroups(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.UsageErrorGroup, error)
+	GetFilteredStats(ctx context.Context, filter *models.UsageFilter) ([]models.UsageStats, error)
+	// GetFilteredHistory returns one page of matching records, newest first
+	GetFilteredHistory(ctx context.Context, filter *models.UsageFilter, page *pagination.Params) ([]models.ServiceUsage, error)
+	// StreamFiltered calls fn for every matching record in created_at order without
+	// loading the result set into memory; iteration stops at the first error fn returns
+	StreamFiltered(ctx context.Context, filter *models.UsageFilter, fn func(*models.ServiceUsage) error) error
 }
 
 type usageRepository struct {
@@ -37,9 +55,41 @@ func (r *usageRepository) CreateUsage(ctx context.Context, usage *models.Service
 	usage.CreatedAt = time.Now()
 	
 	_, err := r.collection.InsertOne(ctx, usage)
+	if mongo.IsDuplicateKeyError(err) {
+		return apperrors.NewAppError(apperrors.ErrConflict, 409, "idempotency key already used")
+	}
+	return err
+}
+
+func (r *usageRepository) GetByIdempotencyKey(ctx context.Context, userID, key string) (*models.ServiceUsage, error) {
+	var usage models.ServiceUsage
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "idempotency_key": key}).Decode(&usage)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (r *usageRepository) DeleteUsage(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
 
+func (r *usageRepository) GetUsageByID(ctx context.Context, id primitive.ObjectID) (*models.ServiceUsage, error) {
+	var usage models.ServiceUsage
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&usage)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usage, nil
+}
+
 func (r *usageRepository) GetGlobalStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UsageStats, error) {
 	pipeline := []bson.M{
 		{
@@ -211,35 +261,251 @@ func (r *usageRepository) GetServiceUserStats(ctx context.Context, serviceName s
 	return stats, nil
 }
 
-func (r *usageRepository) GetUserUsageHistory(ctx context.Context, userID string, limit, skip int) ([]models.ServiceUsage, error) {
-	filter := bson.M{"user_id": userID}
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(skip))
+func (r *usageRepository) GetTimeSeries(ctx context.Context, filter *models.UsageTimeSeriesFilter) ([]models.UsageTimeSeriesPoint, error) {
+	pipeline := []bson.M{
+		{
+			"$match": r.buildUsageFilter(&filter.UsageFilter),
+		},
+		{
+			// Count calls per latency bin first, so each bucket carries a bounded histogram
+			"$group": bson.M{
+				"_id": bson.M{"bucket": bucketExpr("$created_at", filter.Interval), "bin": latencyBinExpr},
+				"calls": bson.M{"$sum": 1},
+				"success_calls": bson.M{
+					"$sum": bson.M{"$cond": []interface{}{"$success", 1, 0}},
+				},
+				"credits": bson.M{"$sum": "$credits_used"},
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":               "$_id.bucket",
+				"total_calls":       bson.M{"$sum": "$calls"},
+				"success_calls":     bson.M{"$sum": "$success_calls"},
+				"total_credits":     bson.M{"$sum": "$credits"},
+				"latency_histogram": bson.M{"$push": bson.M{"bin": "$_id.bin", "count": "$calls"}},
+			},
+		},
+		{
+			"$sort": bson.M{"_id": 1},
+		},
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cursor, err := r.collection.Aggregate(ctx, pipeline, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var usage []models.ServiceUsage
-	if err = cursor.All(ctx, &usage); err != nil {
+	var rows []struct {
+		models.UsageTimeSeriesPoint `bson:",inline"`
+		Histogram                   latencyHistogram `bson:"latency_histogram"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
 		return nil, err
 	}
 
-	return usage, nil
+	points := make([]models.UsageTimeSeriesPoint, len(rows))
+	for i, row := range rows {
+		point := row.UsageTimeSeriesPoint
+		point.FailedCalls = point.TotalCalls - point.SuccessCalls
+		point.P50ProcessTimeMs = row.Histogram.percentile(0.50)
+		point.P95ProcessTimeMs = row.Histogram.percentile(0.95)
+		points[i] = point
+	}
+	return points, nil
 }
 
-func (r *usageRepository) GetServiceUsageHistory(ctx context.Context, serviceName string, limit, skip int) ([]models.ServiceUsage, error) {
-	filter := bson.M{"service_name": serviceName}
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(skip))
+func (r *usageRepository) GetServicePerformance(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServicePerformanceStats, error) {
+	matchFilter := r.buildDateFilter(startDate, endDate)
+	if serviceName != "" {
+		matchFilter["service_name"] = serviceName
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": matchFilter,
+		},
+		{
+			// Count calls per latency bin first, so each service carries a bounded histogram
+			"$group": bson.M{
+				"_id":   bson.M{"service": "$service_name", "bin": latencyBinExpr},
+				"calls": bson.M{"$sum": 1},
+				"failed_calls": bson.M{
+					"$sum": bson.M{"$cond": []interface{}{"$success", 0, 1}},
+				},
+				"process_time_ms": bson.M{"$sum": "$process_time_ms"},
+				"max_process_time_ms": bson.M{"$max": "$process_time_ms"},
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":                   "$_id.service",
+				"total_calls":           bson.M{"$sum": "$calls"},
+				"failed_calls":          bson.M{"$sum": "$failed_calls"},
+				"total_process_time_ms": bson.M{"$sum": "$process_time_ms"},
+				"max_process_time_ms":   bson.M{"$max": "$max_process_time_ms"},
+				"latency_histogram":     bson.M{"$push": bson.M{"bin": "$_id.bin", "count": "$calls"}},
+			},
+		},
+		{
+			"$sort": bson.M{"total_calls": -1},
+		},
+	}
+
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cursor, err := r.collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		models.ServicePerformanceStats `bson:",inline"`
+		TotalProcessTimeMs             int64            `bson:"total_process_time_ms"`
+		Histogram                      latencyHistogram `bson:"latency_histogram"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.ServicePerformanceStats, len(rows))
+	for i, row := range rows {
+		stat := row.ServicePerformanceStats
+		if stat.TotalCalls > 0 {
+			stat.AvgProcessTimeMs = float64(row.TotalProcessTimeMs) / float64(stat.TotalCalls)
+		}
+		// A bin's upper edge can lie above the slowest call, which is known exactly
+		percentile := func(p float64) int64 {
+			if value := row.Histogram.percentile(p); value < stat.MaxProcessTimeMs {
+				return value
+			}
+			return stat.MaxProcessTimeMs
+		}
+		stat.P50ProcessTimeMs = percentile(0.50)
+		stat.P90ProcessTimeMs = percentile(0.90)
+		stat.P95ProcessTimeMs = percentile(0.95)
+		stat.P99ProcessTimeMs = percentile(0.99)
+		stats[i] = stat
+	}
+	return stats, nil
+}
+
+func (r *usageRepository) GetErrorGroups(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.UsageErrorGroup, error) {
+	matchFilter := r.buildDateFilter(startDate, endDate)
+	matchFilter["success"] = false
+	if serviceName != "" {
+		matchFilter["service_name"] = serviceName
+	}
+
+	// Records with a stored code are grouped by code alone; older ones by their message
+	hasCode := bson.M{"$gt": []interface{}{bson.M{"$ifNull": []interface{}{"$error_code", ""}}, ""}}
+	pipeline := []bson.M{
+		{
+			"$match": matchFilter,
+		},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"service_name": "$service_name",
+					"error_code":   bson.M{"$ifNull": []interface{}{"$error_code", ""}},
+					"error_msg":    bson.M{"$cond": []interface{}{hasCode, "", bson.M{"$ifNull": []interface{}{"$error_msg", ""}}}},
+				},
+				"count": bson.M{"$sum": 1},
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":          0,
+				"service_name": "$_id.service_name",
+				"error_code":   "$_id.error_code",
+				"error_msg":    "$_id.error_msg",
+				"count":        1,
+			},
+		},
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.UsageErrorGroup
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// bucketExpr truncates a date to the start of its UTC hour, day or ISO week (Monday).
+// $dateFromParts is used instead of $dateTrunc to support MongoDB 4.x.
+func bucketExpr(field, interval string) bson.M {
+	switch interval {
+	case models.UsageIntervalHour:
+		return bson.M{"$dateFromParts": bson.M{
+			"year":  bson.M{"$year": field},
+			"month": bson.M{"$month": field},
+			"day":   bson.M{"$dayOfMonth": field},
+			"hour":  bson.M{"$hour": field},
+		}}
+	case models.UsageIntervalWeek:
+		return bson.M{"$dateFromParts": bson.M{
+			"isoWeekYear":  bson.M{"$isoWeekYear": field},
+			"isoWeek":      bson.M{"$isoWeek": field},
+			"isoDayOfWeek": 1,
+		}}
+	default:
+		return bson.M{"$dateFromParts": bson.M{
+			"year":  bson.M{"$year": field},
+			"month": bson.M{"$month": field},
+			"day":   bson.M{"$dayOfMonth": field},
+		}}
+	}
+}
+
+func (r *usageRepository) GetFilteredStats(ctx context.Context, filter *models.UsageFilter) ([]models.UsageStats, error) {
+	pipeline := []bson.M{
+		{
+			"$match": r.buildUsageFilter(filter),
+		},
+		{
+			"$group": bson.M{
+				"_id": "$service_name",
+				"total_calls": bson.M{"$sum": 1},
+				"success_calls": bson.M{
+					"$sum": bson.M{"$cond": []interface{}{"$success", 1, 0}},
+				},
+				"failed_calls": bson.M{
+					"$sum": bson.M{"$cond": []interface{}{"$success", 0, 1}},
+				},
+				"total_credits": bson.M{"$sum": "$credits_used"},
+			},
+		},
+		{
+			"$sort": bson.M{"total_calls": -1},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []models.UsageStats
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (r *usageRepository) GetFilteredHistory(ctx context.Context, filter *models.UsageFilter, page *pagination.Params) ([]models.ServiceUsage, error) {
+	matchFilter := page.Filter(r.buildUsageFilter(filter), "created_at")
+	cursor, err := r.collection.Find(ctx, matchFilter, page.FindOptions("created_at"))
 	if err != nil {
 		return nil, err
 	}
@@ -253,6 +519,51 @@ func (r *usageRepository) GetServiceUsageHistory(ctx context.Context, serviceNam
 	return usage, nil
 }
 
+// exportBatchSize bounds how many records the cursor holds per round trip while streaming
+const exportBatchSize = 1000
+
+func (r *usageRepository) StreamFiltered(ctx context.Context, filter *models.UsageFilter, fn func(*models.ServiceUsage) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetBatchSize(exportBatchSize)
+
+	cursor, err := r.collection.Find(ctx, r.buildUsageFilter(filter), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var usage models.ServiceUsage
+		if err := cursor.Decode(&usage); err != nil {
+			return err
+		}
+		if err := fn(&usage); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// buildUsageFilter extends the date filter with the optional equality filters
+func (r *usageRepository) buildUsageFilter(filter *models.UsageFilter) bson.M {
+	matchFilter := r.buildDateFilter(filter.StartDate, filter.EndDate)
+	if filter.UserID != "" {
+		matchFilter["user_id"] = filter.UserID
+	}
+	if filter.ServiceName != "" {
+		matchFilter["service_name"] = filter.ServiceName
+	}
+	if filter.APIKeyID != "" {
+		matchFilter["api_key_id"] = filter.APIKeyID
+	}
+	if filter.Success != nil {
+		matchFilter["success"] = *filter.Success
+	}
+	return matchFilter
+}
+
 func (r *usageRepository) buildDateFilter(startDate, endDate *time.Time) bson.M {
 	filter := bson.M{}
 	
@@ -268,4 +579,48 @@ func (r *usageRepository) buildDateFilter(startDate, endDate *time.Time) bson.M
 	}
 	
 	return filter
-}
\ No newline at end of file
+}
+// latencyBinsPerE sets the latency histogram resolution: a process time of t ms falls in bin
+// floor(ln(t+1) * latencyBinsPerE), so each bin is about 5% wide and a day-long request
+// still lands below bin 400.
+const latencyBinsPerE = 20
+
+// latencyBinExpr is the histogram bin of the document's process_time_ms
+var latencyBinExpr = bson.M{"$floor": bson.M{"$multiply": []interface{}{
+	bson.M{"$ln": bson.M{"$add": []interface{}{
+		bson.M{"$max": []interface{}{bson.M{"$ifNull": []interface{}{"$process_time_ms", 0}}, 0}},
+		1,
+	}}},
+	latencyBinsPerE,
+}}}
+
+// latencyHistogram holds call counts per latency bin. Percentiles are read from it rather
+// than from every pushed process time, which could exceed the 16MB document limit.
+type latencyHistogram []struct {
+	Bin   int   `bson:"bin"`
+	Count int64 `bson:"count"`
+}
+
+// percentile returns the nearest-rank percentile p as the upper edge of the bin holding it,
+// which overstates the exact value by at most one bin width
+func (h latencyHistogram) percentile(p float64) int64 {
+	sort.Slice(h, func(i, j int) bool { return h[i].Bin < h[j].Bin })
+
+	var total int64
+	for _, bin := range h {
+		total += bin.Count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(p * float64(total)))
+	var seen int64
+	for _, bin := range h {
+		seen += bin.Count
+		if seen >= rank {
+			return int64(math.Round(math.Exp(float64(bin.Bin+1)/latencyBinsPerE) - 1))
+		}
+	}
+	return 0
+}
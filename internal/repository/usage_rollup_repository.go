@@ -0,0 +1,263 @@
+// internal/repository/usage_rollup_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UsageRollupRepository maintains hourly and daily pre-aggregated copies of the raw usage
+// collection. Every compaction recomputes whole buckets and replaces them, so re-running a
+// window is always safe.
+type UsageRollupRepository interface {
+	CompactHourly(ctx context.Context, from, to time.Time) error
+	CompactDaily(ctx context.Context, from, to time.Time) error
+	// LatestHourlyBucket returns the start of the newest hourly bucket, or nil when empty
+	LatestHourlyBucket(ctx context.Context) (*time.Time, error)
+	// EarliestUsage returns the time of the oldest raw usage record, or nil when empty
+	EarliestUsage(ctx context.Context) (*time.Time, error)
+	// Read methods cover [from, to); both bounds must be on hour boundaries
+	GetGlobalStats(ctx context.Context, from, to time.Time) ([]models.UsageStats, error)
+	GetUserStats(ctx context.Context, from, to time.Time) ([]models.UserUsageStats, error)
+	// SyncLastActive moves each user's lastActiveAt forward to their newest hourly bucket
+	SyncLastActive(ctx context.Context) error
+	// SyncTotalCalls sets each user's totalCalls to the sum of their daily rollups
+	SyncTotalCalls(ctx context.Context) error
+}
+
+type usageRollupRepository struct {
+	usage  *mongo.Collection
+	hourly *mongo.Collection
+	daily  *mongo.Collection
+}
+
+func NewUsageRollupRepository(usage, hourly, daily *mongo.Collection) UsageRollupRepository {
+	return &usageRollupRepository{
+		usage:  usage,
+		hourly: hourly,
+		daily:  daily,
+	}
+}
+
+func (r *usageRollupRepository) CompactHourly(ctx context.Context, from, to time.Time) error {
+	pipeline := []bson.M{
+		{"$match": bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"bucket":       bucketExpr("$created_at", models.UsageIntervalHour),
+					"service_name": "$service_name",
+					"user_id":      "$user_id",
+					"auth_method":  "$auth_method",
+				},
+				"email":                 bson.M{"$last": "$email"},
+				"total_calls":           bson.M{"$sum": 1},
+				"success_calls":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$success", 1, 0}}},
+				"failed_calls":          bson.M{"$sum": bson.M{"$cond": []interface{}{"$success", 0, 1}}},
+				"total_credits":         bson.M{"$sum": "$credits_used"},
+				"total_process_time_ms": bson.M{"$sum": "$process_time_ms"},
+			},
+		},
+		{"$addFields": bson.M{"updated_at": time.Now()}},
+		{"$merge": bson.M{"into": r.hourly.Name(), "whenMatched": "replace", "whenNotMatched": "insert"}},
+	}
+
+	cursor, err := r.usage.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *usageRollupRepository) CompactDaily(ctx context.Context, from, to time.Time) error {
+	pipeline := []bson.M{
+		{"$match": bson.M{"_id.bucket": bson.M{"$gte": from, "$lt": to}}},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"bucket":       bucketExpr("$_id.bucket", models.UsageIntervalDay),
+					"service_name": "$_id.service_name",
+					"user_id":      "$_id.user_id",
+					"auth_method":  "$_id.auth_method",
+				},
+				"email":                 bson.M{"$last": "$email"},
+				"total_calls":           bson.M{"$sum": "$total_calls"},
+				"success_calls":         bson.M{"$sum": "$success_calls"},
+				"failed_calls":          bson.M{"$sum": "$failed_calls"},
+				"total_credits":         bson.M{"$sum": "$total_credits"},
+				"total_process_time_ms": bson.M{"$sum": "$total_process_time_ms"},
+			},
+		},
+		{"$addFields": bson.M{"updated_at": time.Now()}},
+		{"$merge": bson.M{"into": r.daily.Name(), "whenMatched": "replace", "whenNotMatched": "insert"}},
+	}
+
+	cursor, err := r.hourly.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *usageRollupRepository) LatestHourlyBucket(ctx context.Context) (*time.Time, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id.bucket", Value: -1}})
+
+	var rollup models.UsageRollup
+	if err := r.hourly.FindOne(ctx, bson.M{}, opts).Decode(&rollup); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rollup.Key.Bucket, nil
+}
+
+func (r *usageRollupRepository) SyncLastActive(ctx context.Context) error {
+	// Bucket starts are a lower bound of the last call; $max keeps the exact time written by
+	// usage tracking whenever it is newer
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$_id.user_id", "lastActiveAt": bson.M{"$max": "$_id.bucket"}}},
+		{"$project": bson.M{"_id": 0, "userId": "$_id", "lastActiveAt": 1}},
+		{"$merge": bson.M{
+			"into": "users",
+			"on":   "userId",
+			"whenMatched": []bson.M{
+				{"$set": bson.M{"lastActiveAt": bson.M{"$max": []interface{}{"$lastActiveAt", "$$new.lastActiveAt"}}}},
+			},
+			"whenNotMatched": "discard",
+		}},
+	}
+
+	cursor, err := r.hourly.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *usageRollupRepository) SyncTotalCalls(ctx context.Context) error {
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$_id.user_id", "totalCalls": bson.M{"$sum": "$total_calls"}}},
+		{"$project": bson.M{"_id": 0, "userId": "$_id", "totalCalls": 1}},
+		{"$merge": bson.M{
+			"into":           "users",
+			"on":             "userId",
+			"whenMatched":    []bson.M{{"$set": bson.M{"totalCalls": "$$new.totalCalls"}}},
+			"whenNotMatched": "discard",
+		}},
+	}
+
+	cursor, err := r.daily.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *usageRollupRepository) EarliestUsage(ctx context.Context) (*time.Time, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	var usage models.ServiceUsage
+	if err := r.usage.FindOne(ctx, bson.M{}, opts).Decode(&usage); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usage.CreatedAt, nil
+}
+
+func (r *usageRollupRepository) GetGlobalStats(ctx context.Context, from, to time.Time) ([]models.UsageStats, error) {
+	group := bson.M{
+		"_id":           "$_id.service_name",
+		"total_calls":   bson.M{"$sum": "$total_calls"},
+		"success_calls": bson.M{"$sum": "$success_calls"},
+		"failed_calls":  bson.M{"$sum": "$failed_calls"},
+		"total_credits": bson.M{"$sum": "$total_credits"},
+	}
+
+	var stats []models.UsageStats
+	err := r.aggregateRange(ctx, from, to, group, func(cursor *mongo.Cursor) error {
+		var part []models.UsageStats
+		if err := cursor.All(ctx, &part); err != nil {
+			return err
+		}
+		stats = append(stats, part...)
+		return nil
+	})
+	return stats, err
+}
+
+func (r *usageRollupRepository) GetUserStats(ctx context.Context, from, to time.Time) ([]models.UserUsageStats, error) {
+	group := bson.M{
+		"_id":           "$_id.user_id",
+		"email":         bson.M{"$first": "$email"},
+		"total_calls":   bson.M{"$sum": "$total_calls"},
+		"success_calls": bson.M{"$sum": "$success_calls"},
+		"failed_calls":  bson.M{"$sum": "$failed_calls"},
+		"total_credits": bson.M{"$sum": "$total_credits"},
+	}
+
+	var stats []models.UserUsageStats
+	err := r.aggregateRange(ctx, from, to, group, func(cursor *mongo.Cursor) error {
+		var part []models.UserUsageStats
+		if err := cursor.All(ctx, &part); err != nil {
+			return err
+		}
+		stats = append(stats, part...)
+		return nil
+	})
+	return stats, err
+}
+
+// aggregateRange runs the same $group over the daily rollups for the whole days inside
+// [from, to) and over the hourly rollups for the hours left at either end. Each collection
+// yields partial results keyed the same way, which the caller merges.
+func (r *usageRollupRepository) aggregateRange(ctx context.Context, from, to time.Time, group bson.M, collect func(*mongo.Cursor) error) error {
+	firstDay := from.Truncate(24 * time.Hour)
+	if firstDay.Before(from) {
+		firstDay = firstDay.Add(24 * time.Hour)
+	}
+	lastDay := to.Truncate(24 * time.Hour)
+
+	type segment struct {
+		collection *mongo.Collection
+		match      bson.M
+	}
+	var segments []segment
+
+	if firstDay.Before(lastDay) {
+		segments = append(segments,
+			segment{r.daily, bson.M{"_id.bucket": bson.M{"$gte": firstDay, "$lt": lastDay}}},
+			segment{r.hourly, bson.M{"$or": []bson.M{
+				{"_id.bucket": bson.M{"$gte": from, "$lt": firstDay}},
+				{"_id.bucket": bson.M{"$gte": lastDay, "$lt": to}},
+			}}},
+		)
+	} else {
+		segments = append(segments, segment{r.hourly, bson.M{"_id.bucket": bson.M{"$gte": from, "$lt": to}}})
+	}
+
+	for _, seg := range segments {
+		cursor, err := seg.collection.Aggregate(ctx, []bson.M{
+			{"$match": seg.match},
+			{"$group": group},
+		})
+		if err != nil {
+			return err
+		}
+		err = collect(cursor)
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,9 +4,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"regexp"
+	"strings"
+	"time"
 
 	"chi-mongo-backend/internal/models"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -26,6 +30,7 @@ func NewUserRepository(collection *mongo.Collection) UserRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	user.EmailLower = strings.ToLower(user.Email)
 	result, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -81,6 +86,169 @@ func (r *userRepository) GetAll(ctx context.Context) ([]models.User, error) {
 	return users, nil
 }
 
+// adminUserSortFields maps the search sort keys to fields of the user document
+var adminUserSortFields = map[string]string{
+	models.UserSortCreatedAt: "createdAt",
+	models.UserSortCredits:   "credits",
+	models.UserSortUsage:     "totalCalls",
+}
+
+// adminUserAPIKeyLookup joins whether the user holds a usable API key. A key counts while it
+// is active and not past its expiry.
+func adminUserAPIKeyLookup() []bson.M {
+	now := time.Now()
+	return []bson.M{
+		{"$lookup": bson.M{"from": "api_keys", "localField": "userId", "foreignField": "userId", "as": "apiKeys"}},
+		{"$addFields": bson.M{"hasApiKey": bson.M{"$anyElementTrue": []interface{}{bson.M{"$map": bson.M{
+			"input": "$apiKeys",
+			"as":    "key",
+			"in": bson.M{"$and": []interface{}{
+				"$$key.isActive",
+				bson.M{"$or": []interface{}{
+					bson.M{"$eq": []interface{}{bson.M{"$ifNull": []interface{}{"$$key.expiresAt", nil}}, nil}},
+					bson.M{"$gt": []interface{}{"$$key.expiresAt", now}},
+				}},
+			}},
+		}}}}}},
+	}
+}
+
+func (r *userRepository) Search(ctx context.Context, filter *models.AdminUserFilter, page *pagination.Params) ([]models.AdminUser, error) {
+	sortField := adminUserSortFields[filter.SortBy]
+	if sortField == "" {
+		sortField = adminUserSortFields[models.UserSortCreatedAt]
+	}
+
+	// Everything but the API key filter is matched and sorted on the user document, so the
+	// indexes do the narrowing and the sort
+	pipeline := []bson.M{{"$match": userSearchFilter(filter)}}
+	if filter.HasAPIKey != nil {
+		pipeline = append(pipeline, adminUserAPIKeyLookup()...)
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"hasApiKey": *filter.HasAPIKey}})
+	}
+
+	pipeline = append(pipeline,
+		bson.M{"$match": page.SeekFilter(bson.M{}, sortField, filter.Ascending)},
+		bson.M{"$sort": pagination.SortBy(sortField, filter.Ascending)},
+		bson.M{"$limit": page.Limit + 1},
+	)
+
+	// Otherwise the lookup only runs for the users on the page
+	if filter.HasAPIKey == nil {
+		pipeline = append(pipeline, adminUserAPIKeyLookup()...)
+	}
+
+	pipeline = append(pipeline, bson.M{"$project": bson.M{
+		"_id":          1,
+		"userId":       1,
+		"email":        1,
+		"credits":      1,
+		"createdAt":    1,
+		"updatedAt":    1,
+		"hasApiKey":    1,
+		"lastActiveAt": 1,
+		"totalCalls":   1,
+	}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var adminUsers []models.AdminUser
+	if err = cursor.All(ctx, &adminUsers); err != nil {
+		return nil, err
+	}
+	return adminUsers, nil
+}
+
+// userSearchFilter builds the part of the search that applies to the user document itself.
+// Email searches run against the lowercased copy, so a prefix search is an anchored,
+// case-sensitive regex that can use the emailLower index.
+func userSearchFilter(filter *models.AdminUserFilter) bson.M {
+	var conditions []bson.M
+	if filter.EmailPrefix != "" {
+		conditions = append(conditions, bson.M{"emailLower": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(strings.ToLower(filter.EmailPrefix))}})
+	}
+	if filter.EmailContains != "" {
+		conditions = append(conditions, bson.M{"emailLower": primitive.Regex{Pattern: regexp.QuoteMeta(strings.ToLower(filter.EmailContains))}})
+	}
+	if filter.MinCredits != nil || filter.MaxCredits != nil {
+		credits := bson.M{}
+		if filter.MinCredits != nil {
+			credits["$gte"] = *filter.MinCredits
+		}
+		if filter.MaxCredits != nil {
+			credits["$lte"] = *filter.MaxCredits
+		}
+		conditions = append(conditions, bson.M{"credits": credits})
+	}
+	if filter.CreatedFrom != nil || filter.CreatedTo != nil {
+		createdAt := bson.M{}
+		if filter.CreatedFrom != nil {
+			createdAt["$gte"] = *filter.CreatedFrom
+		}
+		if filter.CreatedTo != nil {
+			createdAt["$lte"] = *filter.CreatedTo
+		}
+		conditions = append(conditions, bson.M{"createdAt": createdAt})
+	}
+	if filter.ActiveSince != nil {
+		conditions = append(conditions, bson.M{"lastActiveAt": bson.M{"$gte": *filter.ActiveSince}})
+	}
+
+	switch len(conditions) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return conditions[0]
+	}
+	return bson.M{"$and": conditions}
+}
+
+// BackfillSearchFields fills the search fields of users stored before they existed: the
+// lowercased email, the balance from the credits collection and a zero call count, which the
+// usage rollup sync replaces
+func (r *userRepository) BackfillSearchFields(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"emailLower": bson.M{"$exists": false}},
+		[]bson.M{{"$set": bson.M{"emailLower": bson.M{"$toLower": "$email"}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"totalCalls": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"totalCalls": 0}},
+	)
+	if err != nil {
+		return err
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"credits": bson.M{"$exists": false}}},
+		{"$lookup": bson.M{"from": "credits", "localField": "userId", "foreignField": "userId", "as": "balance"}},
+		{"$project": bson.M{
+			"credits":        bson.M{"$ifNull": []interface{}{bson.M{"$arrayElemAt": []interface{}{"$balance.credits", 0}}, 0}},
+			"creditsVersion": bson.M{"$ifNull": []interface{}{bson.M{"$arrayElemAt": []interface{}{"$balance.version", 0}}, 0}},
+		}},
+		{"$merge": bson.M{"into": r.collection.Name(), "on": "_id", "whenMatched": "merge", "whenNotMatched": "discard"}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *userRepository) TouchLastActive(ctx context.Context, userID string, at time.Time) error {
+	// $max keeps the newest time when tracking calls finish out of order
+	_, err := r.collection.UpdateOne(ctx, bson.M{"userId": userID}, bson.M{"$max": bson.M{"lastActiveAt": at}})
+	return err
+}
+
 func (r *userRepository) GetTotalCount(ctx context.Context) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{})
 }
\ No newline at end of file
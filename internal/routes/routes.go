@@ -4,6 +4,7 @@ package routes
 import (
 	"time"
 
+	"chi-mongo-backend/internal/config"
 	"chi-mongo-backend/internal/handlers"
 	"chi-mongo-backend/internal/middleware"
 	"chi-mongo-backend/internal/services"
@@ -15,6 +16,7 @@ type Handlers struct {
 	Health                *handlers.HealthHandler
 	User                  *handlers.UserHandler
 	Credits               *handlers.CreditsHandler
+	CreditAlert           *handlers.CreditAlertHandler
 	QRMasking             *handlers.QRMaskingHandler
 	QRExtraction          *handlers.QRExtractionHandler
 	IDCropping            *handlers.IDCroppingHandler
@@ -23,17 +25,25 @@ type Handlers struct {
 	FaceVerify            *handlers.FaceVerificationHandler
 	Debug                 *handlers.DebugHandler
 	Token                 *handlers.TokenHandler
+	Campaign              *handlers.CampaignHandler
 	APIKey                *handlers.APIKeyHandler
 	Usage                 *handlers.UsageHandler // Add usage handler
+	AdminAudit            *handlers.AdminAuditHandler
+	Result                *handlers.ResultHandler
 }
 
 // Services struct to hold required services for middleware
 type Services struct {
-	APIKeyService services.APIKeyService
-	UsageService  services.UsageService // Add usage service
+	APIKeyService      services.APIKeyService
+	UsageService       services.UsageService // Add usage service
+	IdempotencyService services.IdempotencyService
+	AdminDirectory     services.AdminDirectoryService
 }
 
-func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
+// requestTimeout bounds every request except usage exports
+const requestTimeout = 90 * time.Second
+
+func SetupRoutes(h *Handlers, s *Services, limits config.BodyLimitConfig) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middleware
@@ -41,42 +51,70 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 	r.Use(middleware.Recoverer())
 	r.Use(middleware.RequestID())
 	r.Use(middleware.RealIP())
-	r.Use(middleware.Timeout(90 * time.Second))
 	r.Use(middleware.CORS())
 
-	// Health check routes
-	r.Get("/", h.Health.HealthCheck)
-	r.Get("/health", h.Health.HealthCheck)
+	// Requests are cut off after requestTimeout, except usage exports, which stream for as
+	// long as the export takes and are mounted outside the timeout below
+
+	// Body limits are set per group rather than globally, because a global limit would
+	// reject large images by Content-Length before the image routes' own limits apply
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(requestTimeout))
+		r.Use(middleware.BodyLimit(limits.Default))
 
-	// Debug route (NO AUTH - for easy testing)
-	r.Get("/debug/token", h.Debug.ShowTokenData)
+		// Health check routes
+		r.Get("/", h.Health.HealthCheck)
+		r.Get("/health", h.Health.HealthCheck)
+
+		// Debug route (NO AUTH - for easy testing)
+		r.Get("/debug/token", h.Debug.ShowTokenData)
+	})
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware.Timeout(requestTimeout))
+
 		// Public routes (no authentication required)
 		r.Group(func(r chi.Router) {
+			r.Use(middleware.BodyLimit(limits.Default))
 			r.Post("/register", h.User.RegisterUser)
 		})
 
 		// Protected routes (JWT authentication required)
 		r.Group(func(r chi.Router) {
+			r.Use(middleware.BodyLimit(limits.Default))
 			r.Use(middleware.Auth())
+			r.Use(middleware.TrackAdmins(s.AdminDirectory))
+			// Replays responses to mutating requests retried with the same Idempotency-Key
+			r.Use(middleware.Idempotency(s.IdempotencyService))
 			
 			// Credits routes with different authorization levels
 			r.Route("/credits", func(r chi.Router) {
 				// GET balance - accessible to all authenticated users
 				r.Get("/balance", h.Credits.GetBalance)
 				
-				// POST deduct credits - accessible to all authenticated users
+				// POST deduct credits - users charge themselves, admins may charge any user
+				// Optional service, reason and idempotencyKey; each charge is recorded in the usage ledger
 				r.Post("/deduct", h.Credits.DeductCredits)
 				
 				// POST add credits - only accessible to admins
-				r.With(middleware.AdminOnly()).Post("/add", h.Credits.AddCredits)
+				r.With(middleware.AdminOnly(), middleware.StrictJSON()).Post("/add", h.Credits.AddCredits)
+
+				// Low-balance alert and auto top-up settings for the current user
+				r.Get("/alerts", h.CreditAlert.GetAlertSettings)
+				r.With(middleware.StrictJSON()).Put("/alerts", h.CreditAlert.UpdateAlertSettings)
 			})
 
 			r.Route("/tokens", func(r chi.Router) {
+				// Admin request bodies are strict: a misspelled field is rejected, not ignored
 				// POST generate token - only accessible to admins
-				r.With(middleware.AdminOnly()).Post("/generate", h.Token.GenerateToken)
+				r.With(middleware.AdminOnly(), middleware.StrictJSON()).Post("/generate", h.Token.GenerateToken)
+
+				// POST batch generate tokens under a campaign - only accessible to admins
+				r.With(middleware.AdminOnly(), middleware.StrictJSON()).Post("/batch", h.Campaign.GenerateTokenBatch)
+
+				// POST create multi-use promo code - only accessible to admins
+				r.With(middleware.AdminOnly(), middleware.StrictJSON()).Post("/promo", h.Token.CreatePromoCode)
 				
 				// POST redeem token - accessible to all authenticated users
 				r.Post("/redeem", h.Token.RedeemToken)
@@ -97,10 +135,42 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 					// GET unused tokens - see all tokens that haven't been redeemed yet
 					r.Get("/unused", h.Token.GetUnusedTokens)
 
+					// DELETE soft-revokes a token (reason via ?reason=)
 					r.Delete("/{tokenId}", h.Token.DeleteToken)
+
+					// POST revoke a token with a reason; POST transfer ownership to another admin
+					r.With(middleware.StrictJSON()).Post("/{tokenId}/revoke", h.Token.RevokeToken)
+					r.With(middleware.StrictJSON()).Post("/{tokenId}/transfer", h.Token.TransferToken)
+
+					// GET lifecycle audit trail of a token
+					r.Get("/{tokenId}/audit", h.Token.GetTokenAudit)
+
+					// GET redemptions of a token or promo code
+					r.Get("/{tokenId}/redemptions", h.Token.GetTokenRedemptions)
+
+					// Campaign routes - grouped batch tokens, analytics and CSV export
+					r.Get("/campaigns", h.Campaign.GetCampaigns)
+					r.Get("/campaigns/{campaignId}/analytics", h.Campaign.GetCampaignAnalytics)
+					r.Get("/campaigns/{campaignId}/export", h.Campaign.ExportCampaignTokens)
 				})
 			})
 
+			// Self-service usage routes - always scoped to the authenticated user
+			r.Route("/usage", func(r chi.Router) {
+				// GET /api/v1/usage/me?service=&api_key_id=&success=&start_date=&end_date=
+				r.Get("/me", h.Usage.GetMyUsage)
+
+				// GET /api/v1/usage/me/history?limit=50&cursor=
+				r.Get("/me/history", h.Usage.GetMyUsageHistory)
+
+				// GET /api/v1/usage/me/timeseries?interval=day
+				r.Get("/me/timeseries", h.Usage.GetMyTimeSeries)
+			})
+
+			// Result retention settings for the current user; results are kept only after opting in
+			r.Get("/results/retention", h.Result.GetRetentionSettings)
+			r.With(middleware.StrictJSON()).Put("/results/retention", h.Result.UpdateRetentionSettings)
+
 			// API Key management routes (JWT auth required)
 			r.Route("/api-keys", func(r chi.Router) {
 				// Create new API key (replaces any existing key)
@@ -134,7 +204,8 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 				
 				// User management endpoints
 				r.Route("/users", func(r chi.Router) {
-					// GET all users - list all users in the system
+					// GET users - search by email, credits, dates, API key and activity; sort by created date, credits or usage
+					// GET /api/v1/admin/users?email=acme&min_credits=10&has_api_key=true&sort=usage&order=desc
 					r.Get("/", h.User.GetAllUsers)
 					
 					// GET specific user - get user details by ID
@@ -163,15 +234,32 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 					// Service-specific user statistics
 					// GET /api/v1/admin/usage/services?service=signature-verification&start_date=2024-01-01
 					r.Get("/services", h.Usage.GetServiceUserStats)
+
+					// Bucketed usage time series for charts
+					// GET /api/v1/admin/usage/timeseries?interval=day&service=signature-verification&user=&start_date=2024-01-01
+					r.Get("/timeseries", h.Usage.GetTimeSeries)
+
+					// Latency percentiles and failures by error code per service
+					// GET /api/v1/admin/usage/performance?service=face-detection&start_date=2024-01-01
+					r.Get("/performance", h.Usage.GetServicePerformance)
 					
 					// Individual user's usage history
-					// GET /api/v1/admin/usage/user/{userId}/history?limit=50&skip=0
+					// GET /api/v1/admin/usage/user/{userId}/history?limit=50&cursor=
 					r.Get("/user/{userId}/history", h.Usage.GetUserUsageHistory)
 					
 					// Service-specific usage history
-					// GET /api/v1/admin/usage/service/{serviceName}/history?limit=50&skip=0
+					// GET /api/v1/admin/usage/service/{serviceName}/history?limit=50&cursor=
 					r.Get("/service/{serviceName}/history", h.Usage.GetServiceUsageHistory)
 				})
+
+				// Tamper-evident admin audit log
+				r.Route("/audit", func(r chi.Router) {
+					// GET /api/v1/admin/audit?actor=&action=credits.add&target_type=user&target_id=&start_date=&end_date=&limit=50&cursor=
+					r.Get("/", h.AdminAudit.GetAuditLog)
+
+					// Walk the hash chain and report the first entry that does not match
+					r.Get("/verify", h.AdminAudit.VerifyAuditChain)
+				})
 			})
 		})
 
@@ -180,13 +268,37 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 			r.Use(middleware.AuthOrAPIKey(s.APIKeyService)) // Pass the API key service
 			
 			// API processing routes - accessible with either JWT or API key
-			// These routes will automatically track usage via the handlers
-			r.Post("/qr-masking", h.QRMasking.ProcessQRMasking)
-			r.Post("/qr-extraction", h.QRExtraction.ProcessQRExtraction)
-			r.Post("/id-cropping", h.IDCropping.ProcessIDCropping)
-			r.Post("/signature-verification", h.SignatureVerification.ProcessSignatureVerification)
-			r.Post("/face-detect", h.FaceDetect.ProcessFaceDetection)
-			r.Post("/face-verification", h.FaceVerify.ProcessFaceVerification)
+			// These routes will automatically track usage via the handlers.
+			// Body limits go before Idempotency, which reads the whole body.
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.BodyLimit(limits.Image))
+				r.Use(middleware.Idempotency(s.IdempotencyService))
+
+				r.Post("/qr-masking", h.QRMasking.ProcessQRMasking)
+				r.Post("/qr-extraction", h.QRExtraction.ProcessQRExtraction)
+				r.Post("/id-cropping", h.IDCropping.ProcessIDCropping)
+				r.Post("/face-detect", h.FaceDetect.ProcessFaceDetection)
+			})
+
+			// Routes taking several images in one request
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.BodyLimit(limits.MultiImage))
+				r.Use(middleware.Idempotency(s.IdempotencyService))
+
+				r.Post("/signature-verification", h.SignatureVerification.ProcessSignatureVerification)
+				r.Post("/face-verification", h.FaceVerify.ProcessFaceVerification)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.BodyLimit(limits.Default))
+
+				// Stored result of a processing call, e.g. /api/v1/results/face-detection/{req_id}
+				r.Get("/results/{service}/{req_id}", h.Result.GetResult)
+
+				// Retained result of a processing call, for users who keep results
+				// GET /api/v1/results/retained?usage_id= or ?service=qr-masking&req_id=
+				r.Get("/results/retained", h.Result.GetRetainedResult)
+			})
 		})
 
 		// Optional: API-only routes (only accessible with API keys, not JWT)
@@ -202,5 +314,17 @@ func SetupRoutes(h *Handlers, s *Services) *chi.Mux {
 		*/
 	})
 
+	// Stream raw usage records for reconciliation (Admin only). Same authentication as the
+	// other admin routes, but no request timeout; the export sets its own deadline.
+	// GET /api/v1/admin/usage/export?format=csv&gzip=true&start_date=2024-01-01&end_date=2024-01-31
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.BodyLimit(limits.Default))
+		r.Use(middleware.Auth())
+		r.Use(middleware.TrackAdmins(s.AdminDirectory))
+		r.Use(middleware.AdminOnly())
+
+		r.Get("/api/v1/admin/usage/export", h.Usage.ExportUsage)
+	})
+
 	return r
 }
\ No newline at end of file
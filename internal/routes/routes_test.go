@@ -0,0 +1,79 @@
+// internal/routes/routes_test.go
+package routes
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"chi-mongo-backend/internal/config"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestBodyLimits checks that each route is held to its own limit only: an image larger than
+// the default limit must reach authentication on an image route, not be rejected up front
+func TestBodyLimits(t *testing.T) {
+	limits := config.BodyLimitConfig{Default: 1 << 20, Image: 4 << 20, MultiImage: 8 << 20}
+	router := SetupRoutes(&Handlers{}, &Services{}, limits)
+
+	tests := []struct {
+		name   string
+		path   string
+		size   int
+		status int
+	}{
+		{name: "image route under its limit", path: "/api/v1/qr-masking", size: 2 << 20, status: http.StatusUnauthorized},
+		{name: "multi-image route under its limit", path: "/api/v1/signature-verification", size: 6 << 20, status: http.StatusUnauthorized},
+		{name: "public route over the default limit", path: "/api/v1/register", size: 2 << 20, status: http.StatusRequestEntityTooLarge},
+		{name: "jwt route over the default limit", path: "/api/v1/credits/deduct", size: 2 << 20, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(make([]byte, tt.size)))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+// TestUsageExportHasNoTimeout checks that only the usage export escapes the request timeout
+func TestUsageExportHasNoTimeout(t *testing.T) {
+	router := SetupRoutes(&Handlers{}, &Services{}, config.BodyLimitConfig{})
+
+	const export = "GET /api/v1/admin/usage/export"
+	found := false
+	err := chi.Walk(router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		name := method + " " + strings.TrimSuffix(route, "/")
+		timeout := false
+		for _, mw := range middlewares {
+			if strings.Contains(runtime.FuncForPC(reflect.ValueOf(mw).Pointer()).Name(), "middleware.Timeout") {
+				timeout = true
+			}
+		}
+		if name == export {
+			found = true
+		}
+		if timeout == (name == export) {
+			t.Errorf("%s: request timeout = %v", name, timeout)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if !found {
+		t.Errorf("%s is not routed", export)
+	}
+}
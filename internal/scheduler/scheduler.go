@@ -0,0 +1,222 @@
+// internal/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+)
+
+// defaultJobTimeout bounds a job run when the job does not set its own timeout
+const defaultJobTimeout = 10 * time.Minute
+
+// Job is a unit of periodic work. Run returns a short human-readable result for the history.
+type Job struct {
+	Name    string
+	Spec    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) (string, error)
+}
+
+type scheduledJob struct {
+	job      Job
+	schedule Schedule
+}
+
+// Scheduler runs registered jobs on their schedules. Every replica runs a scheduler, and a
+// lock per job activation in Mongo makes sure only one of them executes it.
+type Scheduler struct {
+	lockRepo repository.JobLockRepository
+	runRepo  repository.JobRunRepository
+	owner    string
+
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+func New(lockRepo repository.JobLockRepository, runRepo repository.JobRunRepository) *Scheduler {
+	return &Scheduler{
+		lockRepo: lockRepo,
+		runRepo:  runRepo,
+		owner:    instanceID(),
+	}
+}
+
+// Register adds a job; it must be called before Start
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job name and run function are required")
+	}
+
+	schedule, err := ParseSchedule(job.Spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = defaultJobTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("job %s: scheduler already started", job.Name)
+	}
+	for _, existing := range s.jobs {
+		if existing.job.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, &scheduledJob{job: job, schedule: schedule})
+	return nil
+}
+
+// Start launches one goroutine per registered job
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, sj := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, sj)
+		log.Printf("⏰ Scheduled job %s (%s)", sj.job.Name, sj.job.Spec)
+	}
+}
+
+// Stop cancels running jobs and waits for them to finish or for ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, sj *scheduledJob) {
+	defer s.wg.Done()
+
+	for {
+		next := sj.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job %s has no future activations, stopping", sj.job.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runOnce(ctx, sj, next)
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, sj *scheduledJob, scheduledFor time.Time) {
+	job := sj.job
+
+	acquired, err := s.lockRepo.Acquire(ctx, job.Name, s.owner, scheduledFor, job.Timeout)
+	if err != nil {
+		log.Printf("Failed to acquire lock for job %s: %v", job.Name, err)
+		return
+	}
+	if !acquired {
+		// Another replica is running this activation
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	startedAt := time.Now()
+	result, runErr := safeRun(jobCtx, job)
+	finishedAt := time.Now()
+	cancel()
+
+	run := &models.JobRun{
+		JobName:      job.Name,
+		Owner:        s.owner,
+		ScheduledFor: scheduledFor,
+		StartedAt:    startedAt,
+		FinishedAt:   finishedAt,
+		DurationMs:   finishedAt.Sub(startedAt).Milliseconds(),
+		Status:       models.JobStatusSuccess,
+		Result:       result,
+	}
+	if runErr != nil {
+		run.Status = models.JobStatusFailed
+		run.Error = runErr.Error()
+		log.Printf("❌ Job %s failed: %v", job.Name, runErr)
+	}
+
+	// Bookkeeping uses a fresh context so it still happens when the job hit its timeout
+	bgCtx, bgCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer bgCancel()
+
+	if err := s.runRepo.Create(bgCtx, run); err != nil {
+		log.Printf("Failed to record run of job %s: %v", job.Name, err)
+	}
+	if err := s.lockRepo.Release(bgCtx, job.Name, s.owner); err != nil {
+		log.Printf("Failed to release lock for job %s: %v", job.Name, err)
+	}
+}
+
+// safeRun turns a panicking job into a failed run instead of crashing the server
+func safeRun(ctx context.Context, job Job) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// instanceID identifies this replica in locks and job history
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,46 @@
+// internal/services/activity_emitter.go
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+)
+
+// ActivityEmitter records domain events in the user activity log
+type ActivityEmitter interface {
+	Emit(ctx context.Context, activity *models.ActivityLog)
+}
+
+type activityEmitter struct {
+	activityRepo repository.ActivityRepository
+}
+
+func NewActivityEmitter(activityRepo repository.ActivityRepository) ActivityEmitter {
+	return &activityEmitter{
+		activityRepo: activityRepo,
+	}
+}
+
+// Emit writes the activity. The change it describes has already happened by the time this
+// runs, so a failed write is logged rather than failing the request.
+func (e *activityEmitter) Emit(ctx context.Context, activity *models.ActivityLog) {
+	if err := e.activityRepo.Create(ctx, activity); err != nil {
+		log.Printf("Failed to record %s activity for user %s: %v", activity.Action, activity.UserID, err)
+	}
+}
+
+// newActivity builds an activity for userID; actor is empty when the user acted themselves
+func newActivity(userID, action, description, actor string, metadata map[string]interface{}) *models.ActivityLog {
+	return &models.ActivityLog{
+		UserID:      userID,
+		Action:      action,
+		Description: description,
+		Actor:       actor,
+		Metadata:    metadata,
+		Timestamp:   time.Now(),
+	}
+}
@@ -0,0 +1,172 @@
+// internal/services/admin_audit_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+)
+
+// adminAuditAppendAttempts bounds the retries when concurrent writers race for the next sequence
+const adminAuditAppendAttempts = 10
+
+// errAuditChainBroken stops the verification walk at the first bad entry
+var errAuditChainBroken = errors.New("audit chain broken")
+
+type AdminAuditService interface {
+	Record(ctx context.Context, entry *models.AdminAuditEntry)
+	GetEntries(ctx context.Context, filter *models.AdminAuditFilter, page *pagination.Params) (*models.AdminAuditListResponse, error)
+	VerifyChain(ctx context.Context) (*models.AdminAuditVerifyResponse, error)
+}
+
+type adminAuditService struct {
+	auditRepo repository.AdminAuditRepository
+}
+
+func NewAdminAuditService(auditRepo repository.AdminAuditRepository) AdminAuditService {
+	return &adminAuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record appends the entry to the hash chain. The admin action has already happened by the
+// time this runs, so a failed write is logged rather than failing the request.
+func (s *adminAuditService) Record(ctx context.Context, entry *models.AdminAuditEntry) {
+	if err := s.appendEntry(ctx, entry); err != nil {
+		log.Printf("Failed to record admin audit %s by %s on %s %s: %v", entry.Action, entry.Actor, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+func (s *adminAuditService) appendEntry(ctx context.Context, entry *models.AdminAuditEntry) error {
+	// Store values the way they hash: a JSON round trip turns times into strings and numbers
+	// into floats, which read back from Mongo unchanged
+	before, err := normalizeAuditValues(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := normalizeAuditValues(entry.After)
+	if err != nil {
+		return err
+	}
+	entry.Before, entry.After = before, after
+
+	// Mongo keeps milliseconds
+	entry.Timestamp = time.Now().UTC().Truncate(time.Millisecond)
+
+	for attempt := 0; attempt < adminAuditAppendAttempts; attempt++ {
+		latest, err := s.auditRepo.GetLatest(ctx)
+		if err != nil {
+			return err
+		}
+
+		entry.Sequence, entry.PrevHash = 1, ""
+		if latest != nil {
+			entry.Sequence, entry.PrevHash = latest.Sequence+1, latest.Hash
+		}
+		entry.Hash = entry.ComputeHash()
+
+		err = s.auditRepo.Create(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		if !apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("sequence still contended after %d attempts", adminAuditAppendAttempts)
+}
+
+func normalizeAuditValues(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func (s *adminAuditService) GetEntries(ctx context.Context, filter *models.AdminAuditFilter, page *pagination.Params) (*models.AdminAuditListResponse, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	entries, err := s.auditRepo.GetFiltered(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, info := pagination.Page(entries, page, func(entry models.AdminAuditEntry) pagination.Cursor {
+		return pagination.Cursor{ID: entry.ID, Time: &entry.Timestamp}
+	})
+
+	return &models.AdminAuditListResponse{
+		Message:    "Admin audit log retrieved successfully",
+		Entries:    entries,
+		Count:      len(entries),
+		Pagination: info,
+	}, nil
+}
+
+// VerifyChain walks the log from the first entry and checks that sequences are contiguous,
+// every entry links to the hash of the one before it and every hash matches its content.
+// Entries removed from the end of the log cannot be detected this way; compare LastSequence
+// and LastHash with a previously recorded value for that.
+func (s *adminAuditService) VerifyChain(ctx context.Context) (*models.AdminAuditVerifyResponse, error) {
+	response := &models.AdminAuditVerifyResponse{
+		Message: "Admin audit chain verified",
+		Valid:   true,
+	}
+
+	var prev *models.AdminAuditEntry
+	err := s.auditRepo.StreamAll(ctx, func(entry *models.AdminAuditEntry) error {
+		reason := ""
+		switch {
+		case prev == nil && entry.Sequence != 1:
+			reason = fmt.Sprintf("chain starts at sequence %d", entry.Sequence)
+		case prev != nil && entry.Sequence != prev.Sequence+1:
+			reason = fmt.Sprintf("sequence jumps from %d to %d", prev.Sequence, entry.Sequence)
+		case prev == nil && entry.PrevHash != "":
+			reason = "first entry links to a previous hash"
+		case prev != nil && entry.PrevHash != prev.Hash:
+			reason = "previous hash does not match the entry before it"
+		case entry.Hash != entry.ComputeHash():
+			reason = "hash does not match the entry content"
+		}
+
+		if reason != "" {
+			sequence := entry.Sequence
+			response.Valid = false
+			response.BrokenAt = &sequence
+			response.Reason = reason
+			response.Message = "Admin audit chain is broken"
+			return errAuditChainBroken
+		}
+
+		response.Checked++
+		response.LastSequence = entry.Sequence
+		response.LastHash = entry.Hash
+		prev = entry
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,57 @@
+// internal/services/admin_directory_service.go
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"chi-mongo-backend/internal/repository"
+)
+
+// adminTouchInterval bounds how often one admin's lastSeenAt is written
+const adminTouchInterval = 10 * time.Minute
+
+// AdminDirectoryService keeps the directory of admins seen authenticating, so that checks made
+// without the admin's token, such as the target of a token transfer, can tell admins apart
+type AdminDirectoryService interface {
+	RecordAdmin(ctx context.Context, email string)
+	IsAdmin(ctx context.Context, email string) (bool, error)
+}
+
+type adminDirectoryService struct {
+	adminRepo repository.AdminRepository
+	// lastTouched maps a lowercased email to when it was last written
+	lastTouched sync.Map
+}
+
+func NewAdminDirectoryService(adminRepo repository.AdminRepository) AdminDirectoryService {
+	return &adminDirectoryService{
+		adminRepo: adminRepo,
+	}
+}
+
+// RecordAdmin is called on every admin request; writes are throttled per admin, and a failed
+// write is logged since the request itself must not fail over it
+func (s *adminDirectoryService) RecordAdmin(ctx context.Context, email string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return
+	}
+
+	now := time.Now()
+	if last, ok := s.lastTouched.Load(email); ok && now.Sub(last.(time.Time)) < adminTouchInterval {
+		return
+	}
+	if err := s.adminRepo.Touch(ctx, email, now); err != nil {
+		log.Printf("Failed to record admin %s: %v", email, err)
+		return
+	}
+	s.lastTouched.Store(email, now)
+}
+
+func (s *adminDirectoryService) IsAdmin(ctx context.Context, email string) (bool, error) {
+	return s.adminRepo.ExistsByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"time"
 
 	"chi-mongo-backend/internal/models"
@@ -24,24 +25,29 @@ type APIKeyService interface {
 	RevokeAPIKey(ctx context.Context, userID string) error // Removed keyID param
 	UpdateUsage(ctx context.Context, keyHash string) error
 	GetAPIKeyStats(ctx context.Context, userID string) (*models.APIKeyStatsResponse, error)
+	NotifyExpiringKeys(ctx context.Context, within time.Duration) (int, error)
 }
 
 type apiKeyService struct {
-	apiKeyRepo repository.APIKeyRepository
-	userRepo   repository.UserRepository
+	apiKeyRepo          repository.APIKeyRepository
+	userRepo            repository.UserRepository
+	notificationService NotificationService
+	activity            ActivityEmitter
 }
 
-func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, notificationService NotificationService, activity ActivityEmitter) APIKeyService {
 	return &apiKeyService{
-		apiKeyRepo: apiKeyRepo,
-		userRepo:   userRepo,
+		apiKeyRepo:          apiKeyRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		activity:            activity,
 	}
 }
 
 func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID, email string, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
 	}
 
 	// Check if user exists
@@ -50,6 +56,9 @@ func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID, email string,
 		return nil, err
 	}
 
+	// Creating over an existing key rotates it; a lookup error just records a creation
+	existingKey, _ := s.apiKeyRepo.GetByUserID(ctx, userID)
+
 	// Delete any existing API key for this user
 	if err := s.apiKeyRepo.DeleteByUserID(ctx, userID); err != nil {
 		// Log the error but don't fail the creation if no existing key found
@@ -86,6 +95,20 @@ func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID, email string,
 		return nil, err
 	}
 
+	metadata := map[string]interface{}{
+		"keyName":   req.KeyName,
+		"keyPrefix": keyPrefix,
+	}
+	if req.ExpiresAt != nil {
+		metadata["expiresAt"] = *req.ExpiresAt
+	}
+	if existingKey != nil {
+		metadata["previousKeyPrefix"] = existingKey.KeyPrefix
+		s.activity.Emit(ctx, newActivity(userID, models.ActivityAPIKeyRotated, "API key rotated", "", metadata))
+	} else {
+		s.activity.Emit(ctx, newActivity(userID, models.ActivityAPIKeyCreated, "API key created", "", metadata))
+	}
+
 	return &models.CreateAPIKeyResponse{
 		Message:   "API key created successfully",
 		APIKey:    apiKey, // Return full key only once
@@ -136,7 +159,7 @@ func (s *apiKeyService) GetUserAPIKey(ctx context.Context, userID string) (*mode
 func (s *apiKeyService) UpdateAPIKey(ctx context.Context, userID string, req *models.UpdateAPIKeyRequest) error {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return apperrors.NewValidationError(err)
 	}
 
 	// Get existing key to verify ownership and get ID
@@ -158,18 +181,68 @@ func (s *apiKeyService) UpdateAPIKey(ctx context.Context, userID string, req *mo
 		return apperrors.NewAppError(apperrors.ErrValidation, 400, "no fields to update")
 	}
 
-	return s.apiKeyRepo.Update(ctx, existingKey.ID, update)
+	if err := s.apiKeyRepo.Update(ctx, existingKey.ID, update); err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{"keyPrefix": existingKey.KeyPrefix}
+	for field, value := range update {
+		metadata[field] = value
+	}
+	s.activity.Emit(ctx, newActivity(userID, models.ActivityAPIKeyUpdated, "API key updated", "", metadata))
+	return nil
 }
 
 // RevokeAPIKey revokes the user's single API key (removed keyID parameter)
 func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID string) error {
-	return s.apiKeyRepo.DeleteByUserID(ctx, userID)
+	existingKey, _ := s.apiKeyRepo.GetByUserID(ctx, userID)
+
+	if err := s.apiKeyRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	// Revoking when there is no key is a no-op and leaves nothing to record
+	if existingKey != nil {
+		s.activity.Emit(ctx, newActivity(userID, models.ActivityAPIKeyRevoked, "API key revoked", "", map[string]interface{}{
+			"keyName":   existingKey.KeyName,
+			"keyPrefix": existingKey.KeyPrefix,
+		}))
+	}
+	return nil
 }
 
 func (s *apiKeyService) UpdateUsage(ctx context.Context, keyHash string) error {
 	return s.apiKeyRepo.UpdateLastUsed(ctx, keyHash)
 }
 
+// NotifyExpiringKeys emails the owner of every active key that expires within the window.
+// Each key is notified at most once.
+func (s *apiKeyService) NotifyExpiringKeys(ctx context.Context, within time.Duration) (int, error) {
+	apiKeys, err := s.apiKeyRepo.GetExpiringUnnotified(ctx, time.Now().Add(within))
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for _, apiKey := range apiKeys {
+		// Claim the notice first so concurrent runs never email twice
+		claimed, err := s.apiKeyRepo.MarkExpiryNotified(ctx, apiKey.ID)
+		if err != nil {
+			return notified, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.notificationService.SendEmail(ctx, apiKey.Email, "Your API key is expiring soon", apiKeyExpiryEmailBody(apiKey)); err != nil {
+			log.Printf("Failed to send API key expiry notice to %s: %v", apiKey.Email, err)
+			continue
+		}
+		notified++
+	}
+	return notified, nil
+}
+
 // GetAPIKeyStats returns statistics for user's API key (now singular)
 func (s *apiKeyService) GetAPIKeyStats(ctx context.Context, userID string) (*models.APIKeyStatsResponse, error) {
 	// Get user's API key
@@ -0,0 +1,182 @@
+// internal/services/campaign_service.go
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CampaignService interface {
+	GenerateTokenBatch(ctx context.Context, req *models.GenerateTokenBatchRequest, createdBy string) (*models.TokenBatchResponse, error)
+	GetCampaigns(ctx context.Context, page *pagination.Params) (*models.CampaignListResponse, error)
+	GetCampaignAnalytics(ctx context.Context, campaignID string) (*models.CampaignAnalyticsResponse, error)
+	GetCampaignTokens(ctx context.Context, campaignID string) (*models.Campaign, []*models.CreditToken, error)
+}
+
+type campaignService struct {
+	campaignRepo repository.CampaignRepository
+	tokenRepo    repository.TokenRepository
+	auditRepo    repository.TokenAuditRepository
+}
+
+func NewCampaignService(campaignRepo repository.CampaignRepository, tokenRepo repository.TokenRepository, auditRepo repository.TokenAuditRepository) CampaignService {
+	return &campaignService{
+		campaignRepo: campaignRepo,
+		tokenRepo:    tokenRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+func (s *campaignService) GenerateTokenBatch(ctx context.Context, req *models.GenerateTokenBatchRequest, createdBy string) (*models.TokenBatchResponse, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	// Default to the same 30-day expiry as single tokens
+	now := time.Now()
+	expiresAt := now.Add(30 * 24 * time.Hour)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	campaign := &models.Campaign{
+		Name:            req.CampaignName,
+		Description:     req.Description,
+		CreatedBy:       createdBy,
+		TokenCount:      req.Count,
+		CreditsPerToken: req.Credits,
+		ExpiresAt:       expiresAt,
+		CreatedAt:       now,
+	}
+
+	if err := s.campaignRepo.Create(ctx, campaign); err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to create campaign")
+	}
+
+	tokens := make([]*models.CreditToken, 0, req.Count)
+	codes := make([]string, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		tokenStr, err := models.GenerateToken()
+		if err != nil {
+			s.rollbackCampaign(campaign.ID)
+			return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to generate token")
+		}
+
+		tokens = append(tokens, &models.CreditToken{
+			Token:       tokenStr,
+			Credits:     req.Credits,
+			CreatedBy:   createdBy,
+			CreatedAt:   now,
+			ExpiresAt:   expiresAt,
+			IsUsed:      false,
+			Description: req.Description,
+			CampaignID:  &campaign.ID,
+		})
+		codes = append(codes, models.FormatTokenCode(tokenStr))
+	}
+
+	if err := s.tokenRepo.CreateMany(ctx, tokens); err != nil {
+		s.rollbackCampaign(campaign.ID)
+		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to create tokens")
+	}
+
+	events := make([]*models.TokenAuditEvent, 0, len(tokens))
+	for _, token := range tokens {
+		events = append(events, newTokenAuditEvent(token, models.TokenEventCreated, createdBy, map[string]interface{}{
+			"credits":    token.Credits,
+			"campaignId": campaign.ID.Hex(),
+		}))
+	}
+	if err := s.auditRepo.CreateMany(ctx, events); err != nil {
+		log.Printf("Failed to record created events for campaign %s: %v", campaign.ID.Hex(), err)
+	}
+
+	return &models.TokenBatchResponse{
+		Message:  "Token batch generated successfully",
+		Campaign: campaign,
+		Tokens:   codes,
+	}, nil
+}
+
+func (s *campaignService) GetCampaigns(ctx context.Context, page *pagination.Params) (*models.CampaignListResponse, error) {
+	campaigns, err := s.campaignRepo.GetAll(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns, info := pagination.Page(campaigns, page, func(c models.Campaign) pagination.Cursor {
+		return pagination.Cursor{ID: c.ID, Time: &c.CreatedAt}
+	})
+
+	return &models.CampaignListResponse{
+		Message:    "Campaigns retrieved successfully",
+		Campaigns:  campaigns,
+		Total:      len(campaigns),
+		Pagination: info,
+	}, nil
+}
+
+func (s *campaignService) GetCampaignAnalytics(ctx context.Context, campaignID string) (*models.CampaignAnalyticsResponse, error) {
+	campaign, err := s.getCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics, err := s.tokenRepo.GetCampaignAnalytics(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CampaignAnalyticsResponse{
+		Message:   "Campaign analytics retrieved successfully",
+		Campaign:  campaign,
+		Analytics: *analytics,
+	}, nil
+}
+
+func (s *campaignService) GetCampaignTokens(ctx context.Context, campaignID string) (*models.Campaign, []*models.CreditToken, error) {
+	campaign, err := s.getCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.tokenRepo.GetByCampaign(ctx, campaign.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return campaign, tokens, nil
+}
+
+func (s *campaignService) getCampaign(ctx context.Context, campaignID string) (*models.Campaign, error) {
+	objID, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "invalid campaign ID format")
+	}
+	return s.campaignRepo.GetByID(ctx, objID)
+}
+
+// rollbackCampaign removes a campaign and any tokens inserted before a batch failed
+func (s *campaignService) rollbackCampaign(id primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.tokenRepo.DeleteByCampaign(ctx, id); err != nil {
+		log.Printf("Failed to rollback campaign tokens: %v", err)
+	}
+	if err := s.campaignRepo.Delete(ctx, id); err != nil {
+		log.Printf("Failed to rollback campaign creation: %v", err)
+	}
+}
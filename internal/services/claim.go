@@ -0,0 +1,21 @@
+// internal/services/claim.go
+package services
+
+// claimOrLoad runs the claim step shared by Idempotency-Key requests and req_id results:
+// claim tries to take the key and, when it is taken, load returns the stored record for the
+// caller to check. A second pass covers a record that expired between the claim and the
+// read. claimed is false and record nil when the key stayed held by another request.
+func claimOrLoad[T any](claim func() (bool, error), load func() (*T, error)) (record *T, claimed bool, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := claim()
+		if err != nil || claimed {
+			return nil, claimed, err
+		}
+
+		record, err := load()
+		if err != nil || record != nil {
+			return record, false, err
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,265 @@
+// internal/services/credit_alert_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/validation"
+)
+
+const lowBalanceEvent = "credits.low_balance"
+
+type CreditAlertService interface {
+	GetSettings(ctx context.Context, userID string) (*models.CreditAlertResponse, error)
+	UpdateSettings(ctx context.Context, userID string, req *models.UpdateCreditAlertRequest) (*models.CreditAlertResponse, error)
+	// OnBalanceChanged satisfies repository.BalanceListener
+	OnBalanceChanged(ctx context.Context, userID string, previousBalance, currentBalance int)
+}
+
+type creditAlertService struct {
+	alertRepo           repository.CreditAlertRepository
+	creditsRepo         repository.CreditsRepository
+	notificationService NotificationService
+	paymentService      PaymentService
+	activity            ActivityEmitter
+	userLocks           userLocks
+}
+
+// userLocks serializes work per user. Entries are removed once nobody holds or waits for
+// them, so the map only holds users with alerts in flight.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock blocks until the user's lock is held and returns the function that releases it
+func (l *userLocks) lock(userID string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*userLock)
+	}
+	entry, ok := l.locks[userID]
+	if !ok {
+		entry = &userLock{}
+		l.locks[userID] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func NewCreditAlertService(
+	alertRepo repository.CreditAlertRepository,
+	creditsRepo repository.CreditsRepository,
+	notificationService NotificationService,
+	paymentService PaymentService,
+	activity ActivityEmitter,
+) CreditAlertService {
+	return &creditAlertService{
+		alertRepo:           alertRepo,
+		creditsRepo:         creditsRepo,
+		notificationService: notificationService,
+		paymentService:      paymentService,
+		activity:            activity,
+	}
+}
+
+func (s *creditAlertService) GetSettings(ctx context.Context, userID string) (*models.CreditAlertResponse, error) {
+	settings, err := s.alertRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if !apperrors.IsErrorType(err, apperrors.ErrNotFound) {
+			return nil, err
+		}
+		// No settings stored yet - alerts are disabled by default
+		settings = &models.CreditAlertSettings{UserID: userID}
+	}
+
+	return &models.CreditAlertResponse{
+		Message:  "Credit alert settings retrieved successfully",
+		Settings: settings,
+	}, nil
+}
+
+func (s *creditAlertService) UpdateSettings(ctx context.Context, userID string, req *models.UpdateCreditAlertRequest) (*models.CreditAlertResponse, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	if req.AutoTopUpEnabled && !s.paymentService.IsConfigured() {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "auto top-up is not available")
+	}
+
+	var fields apperrors.FieldErrors
+	if req.WebhookURL != "" {
+		if err := s.notificationService.CheckWebhookURL(ctx, req.WebhookURL); err != nil {
+			fields.Add("webhookUrl", validation.CodeURL, err.Error())
+		}
+	}
+
+	// The payment method must be one the provider stores for this user, since auto top-up charges it
+	if req.AutoTopUpEnabled {
+		err := s.paymentService.VerifyPaymentMethod(ctx, userID, req.PaymentMethodID)
+		if errors.Is(err, errPaymentMethodNotFound) {
+			fields.Add("paymentMethodId", validation.CodeInvalid, "paymentMethodId is not a payment method stored for this account")
+		} else if err != nil {
+			log.Printf("Failed to verify payment method for user %s: %v", userID, err)
+			return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to verify payment method")
+		}
+	}
+	if err := fields.Err(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	settings := &models.CreditAlertSettings{
+		UserID:           userID,
+		Enabled:          req.Enabled,
+		Threshold:        req.Threshold,
+		NotifyEmail:      req.NotifyEmail,
+		WebhookURL:       req.WebhookURL,
+		AutoTopUpEnabled: req.AutoTopUpEnabled,
+		AutoTopUpAmount:  req.AutoTopUpAmount,
+		PaymentMethodID:  req.PaymentMethodID,
+		Triggered:        false, // Re-arm so the new threshold can fire
+	}
+
+	if err := s.alertRepo.Upsert(ctx, settings); err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to save credit alert settings")
+	}
+
+	return &models.CreditAlertResponse{
+		Message:  "Credit alert settings updated successfully",
+		Settings: settings,
+	}, nil
+}
+
+func (s *creditAlertService) OnBalanceChanged(ctx context.Context, userID string, previousBalance, currentBalance int) {
+	// Run asynchronously so alert delivery never blocks the credit mutation. Checks for one
+	// user run one at a time, so a reset and a trigger cannot overtake each other.
+	go func() {
+		unlock := s.userLocks.lock(userID)
+		defer unlock()
+
+		alertCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.checkBalance(alertCtx, userID, previousBalance, currentBalance); err != nil {
+			log.Printf("Failed to process low-balance alert for user %s: %v", userID, err)
+		}
+	}()
+}
+
+func (s *creditAlertService) checkBalance(ctx context.Context, userID string, previousBalance, currentBalance int) error {
+	settings, err := s.alertRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !settings.Enabled {
+		return nil
+	}
+
+	// Checks can run in a different order from the changes that queued them, so compare the
+	// threshold against the balance in the store rather than the one this change left
+	credits, err := s.creditsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	balance := credits.Credits
+
+	// Balance is back above the threshold - re-arm for the next crossing
+	if balance >= settings.Threshold {
+		if settings.Triggered {
+			return s.alertRepo.ResetTriggered(ctx, userID)
+		}
+		return nil
+	}
+
+	// Only balance decreases can cross the threshold
+	if currentBalance >= previousBalance {
+		return nil
+	}
+
+	won, err := s.alertRepo.MarkTriggered(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !won {
+		return nil // Already alerted for this crossing
+	}
+
+	event := &models.LowBalanceEvent{
+		Event:           lowBalanceEvent,
+		UserID:          userID,
+		Threshold:       settings.Threshold,
+		PreviousBalance: previousBalance,
+		CurrentBalance:  balance,
+		OccurredAt:      time.Now(),
+	}
+
+	if settings.AutoTopUpEnabled && settings.PaymentMethodID != "" {
+		if err := s.autoTopUp(ctx, settings); err != nil {
+			log.Printf("Auto top-up failed for user %s: %v", userID, err)
+		} else {
+			event.AutoTopUpAmount = settings.AutoTopUpAmount
+		}
+	}
+
+	s.notify(ctx, settings, event)
+	return nil
+}
+
+func (s *creditAlertService) autoTopUp(ctx context.Context, settings *models.CreditAlertSettings) error {
+	if err := s.paymentService.ChargeCredits(ctx, settings.UserID, settings.PaymentMethodID, settings.AutoTopUpAmount); err != nil {
+		return err
+	}
+	if err := s.creditsRepo.UpdateCredits(ctx, settings.UserID, settings.AutoTopUpAmount); err != nil {
+		return fmt.Errorf("payment succeeded but failed to add credits: %w", err)
+	}
+
+	s.activity.Emit(ctx, newActivity(settings.UserID, models.ActivityCreditsAdded, "Credits added by auto top-up", "system", map[string]interface{}{
+		"amount":          settings.AutoTopUpAmount,
+		"paymentMethodId": settings.PaymentMethodID,
+	}))
+	return nil
+}
+
+func (s *creditAlertService) notify(ctx context.Context, settings *models.CreditAlertSettings, event *models.LowBalanceEvent) {
+	if settings.NotifyEmail != "" {
+		subject := fmt.Sprintf("Low credit balance: %d credits remaining", event.CurrentBalance)
+		if err := s.notificationService.SendEmail(ctx, settings.NotifyEmail, subject, lowBalanceEmailBody(event)); err != nil {
+			log.Printf("Failed to send low-balance email to %s: %v", settings.NotifyEmail, err)
+		}
+	}
+
+	if settings.WebhookURL != "" {
+		if err := s.notificationService.SendWebhook(ctx, settings.WebhookURL, lowBalanceEvent, event); err != nil {
+			log.Printf("Failed to deliver low-balance webhook for user %s: %v", settings.UserID, err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package services
 
 import (
 	"context"
+	"log"
+	"time"
 
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/repository"
@@ -12,19 +14,28 @@ import (
 type CreditsService interface {
 	GetBalance(ctx context.Context, userID string) (*models.CreditsResponse, error)
 	GetBalanceByEmail(ctx context.Context, email string) (*models.CreditsResponse, error)
-	AddCredits(ctx context.Context, req *models.AddCreditsRequest) (*models.CreditsResponse, error)
+	AddCredits(ctx context.Context, req *models.AddCreditsRequest, adminEmail string) (*models.CreditsResponse, error)
 	DeductCredits(ctx context.Context, req *models.DeductCreditsRequest) (*models.CreditsResponse, error)
+	// RefundCredits returns credits deducted up front for service calls that were not made
+	RefundCredits(ctx context.Context, userID string, amount int) (*models.CreditsResponse, error)
+	ChargeCredits(ctx context.Context, req *models.DeductCreditsRequest, adminEmail string, authMethod string) (*models.CreditsResponse, error)
+	// SyncUserSearchFields repairs the balances and other admin search fields kept on users
+	SyncUserSearchFields(ctx context.Context) error
 }
 
 type creditsService struct {
 	creditsRepo repository.CreditsRepository
 	userRepo    repository.UserRepository
+	usageRepo   repository.UsageRepository
+	activity    ActivityEmitter
 }
 
-func NewCreditsService(creditsRepo repository.CreditsRepository, userRepo repository.UserRepository) CreditsService {
+func NewCreditsService(creditsRepo repository.CreditsRepository, userRepo repository.UserRepository, usageRepo repository.UsageRepository, activity ActivityEmitter) CreditsService {
 	return &creditsService{
 		creditsRepo: creditsRepo,
 		userRepo:    userRepo,
+		usageRepo:   usageRepo,
+		activity:    activity,
 	}
 }
 
@@ -52,10 +63,10 @@ func (s *creditsService) GetBalanceByEmail(ctx context.Context, email string) (*
 	return s.GetBalance(ctx, user.UserID)
 }
 
-func (s *creditsService) AddCredits(ctx context.Context, req *models.AddCreditsRequest) (*models.CreditsResponse, error) {
+func (s *creditsService) AddCredits(ctx context.Context, req *models.AddCreditsRequest, adminEmail string) (*models.CreditsResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
 	}
 
 	// Get current credits to return updated balance
@@ -69,18 +80,25 @@ func (s *creditsService) AddCredits(ctx context.Context, req *models.AddCreditsR
 		return nil, err
 	}
 
+	balance := currentCredits.Credits + req.Amount
+	s.activity.Emit(ctx, newActivity(req.UserID, models.ActivityCreditsAdded, "Credits added by an admin", adminEmail, map[string]interface{}{
+		"amount":  req.Amount,
+		"balance": balance,
+	}))
+
 	return &models.CreditsResponse{
 		Message: "Credits added successfully",
 		UserID:  req.UserID,
-		Credits: currentCredits.Credits + req.Amount,
+		Credits: balance,
 	}, nil
 }
 
-// internal/services/credits_service.go
+// DeductCredits charges for service calls, which the usage log already records per call,
+// so it does not emit an activity.
 func (s *creditsService) DeductCredits(ctx context.Context, req *models.DeductCreditsRequest) (*models.CreditsResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
 	}
 
 	// Get current credits to return updated balance
@@ -99,4 +117,139 @@ func (s *creditsService) DeductCredits(ctx context.Context, req *models.DeductCr
 		UserID:  req.UserID,
 		Credits: currentCredits.Credits - req.Amount,
 	}, nil
-}
\ No newline at end of file
+}
+
+// RefundCredits is the undo of DeductCredits for calls that were paid for but never made,
+// so like DeductCredits it does not emit an activity.
+func (s *creditsService) RefundCredits(ctx context.Context, userID string, amount int) (*models.CreditsResponse, error) {
+	if err := s.creditsRepo.UpdateCredits(ctx, userID, amount); err != nil {
+		return nil, err
+	}
+
+	credits, err := s.creditsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreditsResponse{
+		Message: "Credits refunded successfully",
+		UserID:  userID,
+		Credits: credits.Credits,
+	}, nil
+}
+
+// ChargeCredits deducts a direct charge and records it in the usage ledger. With an
+// idempotency key a retried charge returns the original result instead of charging twice.
+// adminEmail is set when an admin charges another user; authMethod is how the caller
+// authenticated and is recorded in the ledger.
+func (s *creditsService) ChargeCredits(ctx context.Context, req *models.DeductCreditsRequest, adminEmail string, authMethod string) (*models.CreditsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	service := req.ChargeService()
+
+	if req.IdempotencyKey != "" {
+		existing, err := s.usageRepo.GetByIdempotencyKey(ctx, req.UserID, req.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return s.replayCharge(ctx, existing, req, service)
+		}
+	}
+
+	// Write the ledger record first so a concurrent retry with the same key loses on the
+	// unique index before it can charge
+	charge := &models.ServiceUsage{
+		UserID:         req.UserID,
+		Email:          user.Email,
+		ServiceName:    service,
+		Endpoint:       "/api/v1/credits/deduct",
+		Method:         "POST",
+		Success:        true,
+		CreditsUsed:    req.Amount,
+		AuthMethod:     authMethod,
+		IdempotencyKey: req.IdempotencyKey,
+		Reason:         req.Reason,
+		ChargedBy:      adminEmail,
+	}
+	if err := s.usageRepo.CreateUsage(ctx, charge); err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			existing, getErr := s.usageRepo.GetByIdempotencyKey(ctx, req.UserID, req.IdempotencyKey)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if existing != nil {
+				return s.replayCharge(ctx, existing, req, service)
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.creditsRepo.DeductCredits(ctx, req.UserID, req.Amount); err != nil {
+		// Nothing was charged, so drop the ledger record and free the key for a retry
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if deleteErr := s.usageRepo.DeleteUsage(rollbackCtx, charge.ID); deleteErr != nil {
+			log.Printf("Failed to roll back charge %s for user %s: %v", charge.ID.Hex(), req.UserID, deleteErr)
+		}
+		return nil, err
+	}
+
+	credits, err := s.creditsRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"amount":   req.Amount,
+		"balance":  credits.Credits,
+		"service":  service,
+		"chargeId": charge.ID.Hex(),
+	}
+	if req.Reason != "" {
+		metadata["reason"] = req.Reason
+	}
+	s.activity.Emit(ctx, newActivity(req.UserID, models.ActivityCreditsCharged, "Credits charged", adminEmail, metadata))
+
+	return &models.CreditsResponse{
+		Message:  "Credits deducted successfully",
+		UserID:   req.UserID,
+		Credits:  credits.Credits,
+		ChargeID: charge.ID.Hex(),
+	}, nil
+}
+
+// replayCharge answers a retried charge with the current balance. Reusing a key for a
+// different charge is rejected rather than silently ignored.
+func (s *creditsService) replayCharge(ctx context.Context, existing *models.ServiceUsage, req *models.DeductCreditsRequest, service string) (*models.CreditsResponse, error) {
+	if existing.CreditsUsed != req.Amount || existing.ServiceName != service {
+		return nil, apperrors.NewAppError(apperrors.ErrConflict, 409, "idempotency key was already used for a different charge")
+	}
+
+	credits, err := s.creditsRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreditsResponse{
+		Message:  "Credits already deducted for this idempotency key",
+		UserID:   req.UserID,
+		Credits:  credits.Credits,
+		ChargeID: existing.ID.Hex(),
+		Replayed: true,
+	}, nil
+}
+
+func (s *creditsService) SyncUserSearchFields(ctx context.Context) error {
+	if err := s.userRepo.BackfillSearchFields(ctx); err != nil {
+		return err
+	}
+	return s.creditsRepo.SyncUserBalances(ctx)
+}
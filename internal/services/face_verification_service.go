@@ -2,11 +2,9 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"time"
@@ -37,31 +35,23 @@ func NewFaceVerificationAPIService() FaceVerificationAPIService {
 }
 
 func (s *faceVerificationAPIService) ProcessFaceVerification(ctx context.Context, req *models.FaceVerificationRequest) (*models.FaceVerificationResult, error) {
-	// Prepare the request payload exactly as expected by the API
-	payload := map[string]interface{}{
-		"req_id":       req.ReqID,
-		"doc_base64_1": req.DocBase64_1,
-		"doc_base64_2": req.DocBase64_2,
-		"doc_type":     req.DocType,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
-	}
+	// Prepare the request payload exactly as expected by the API; images are
+	// base64-encoded while the request is sent
+	payload := newJSONPayload().
+		Field("req_id", req.ReqID).
+		Image("doc_base64_1", req.Doc1).
+		Image("doc_base64_2", req.Doc2).
+		Field("doc_type", req.DocType)
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonData))
+	httpReq, err := payload.NewRequest(ctx, s.apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Log the request for debugging
 	log.Printf("Making Face Verification API request to: %s", s.apiURL)
-	log.Printf("Request payload: %s", string(jsonData))
+	log.Printf("Request ReqID: %s, payload size: %d bytes", req.ReqID, payload.Len())
 
 	// Make the API call
 	resp, err := s.httpClient.Do(httpReq)
@@ -71,14 +61,13 @@ func (s *faceVerificationAPIService) ProcessFaceVerification(ctx context.Context
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readUpstreamBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	// Log the response for debugging
-	log.Printf("Face Verification API response status: %d", resp.StatusCode)
-	log.Printf("Face Verification API response body: %s", string(body))
+	// Log the response for debugging (without the body, which can hold image data)
+	log.Printf("Face Verification API response status: %d, body size: %d bytes", resp.StatusCode, len(body))
 
 	// Parse the raw response to preserve original structure
 	var rawResponse map[string]interface{}
@@ -109,7 +98,7 @@ func (s *faceVerificationAPIService) ProcessFaceVerification(ctx context.Context
 
 	// Check for HTTP errors first
 	if resp.StatusCode != http.StatusOK {
-		errorMsg := fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(body))
+		errorMsg := fmt.Sprintf("HTTP %d: %s", resp.StatusCode, bodySnippet(body))
 		return nil, apperrors.NewAPIErrorWithOriginalResponse(
 			s.errorMapper,
 			errorMsg,
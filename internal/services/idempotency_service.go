@@ -0,0 +1,74 @@
+// internal/services/idempotency_service.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+)
+
+// idempotencyLockTTL outlives the request timeout, so a live request never loses its claim
+const idempotencyLockTTL = 2 * time.Minute
+
+type IdempotencyService interface {
+	// Begin claims key for the request identified by fingerprint. It returns nil when the
+	// request should run, or the stored record when a finished response should be replayed.
+	Begin(ctx context.Context, scope, key, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, response *models.IdempotentResponse) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+type idempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepository
+	ttl             time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		ttl:             ttl,
+	}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	id := idempotencyRecordID(scope, key)
+	record, claimed, err := claimOrLoad(
+		func() (bool, error) {
+			return s.idempotencyRepo.Claim(ctx, id, scope, fingerprint, idempotencyLockTTL, s.ttl)
+		},
+		func() (*models.IdempotencyRecord, error) { return s.idempotencyRepo.Get(ctx, id) },
+	)
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	switch {
+	case record == nil || (record.Fingerprint == fingerprint && record.State != models.ClaimStateCompleted):
+		return nil, apperrors.NewAppError(apperrors.ErrConflict, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+	case record.Fingerprint != fingerprint:
+		return nil, apperrors.NewAppError(apperrors.ErrConflict, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	case record.BodyOmitted:
+		return nil, apperrors.NewAppError(apperrors.ErrConflict, http.StatusConflict, "the response to this Idempotency-Key was too large to store and cannot be replayed")
+	}
+	return record, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, scope, key string, response *models.IdempotentResponse) error {
+	return s.idempotencyRepo.Complete(ctx, idempotencyRecordID(scope, key), response, s.ttl)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.idempotencyRepo.Release(ctx, idempotencyRecordID(scope, key))
+}
+
+// idempotencyRecordID keys records by caller so two callers never share a key
+func idempotencyRecordID(scope, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+// internal/services/image_preprocessor.go
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"chi-mongo-backend/internal/models"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/imageprep"
+)
+
+// ImagePreprocessor checks images before any credits are reserved for them, so malformed or
+// oversized input fails with a mapped error code instead of an upstream error
+type ImagePreprocessor interface {
+	// Prepare decodes an image sent as base64 or as an upload, checks it against the limits
+	// and returns it normalized; it satisfies models.ImagePreparer
+	Prepare(field, inline string, upload *models.Upload) (*models.Upload, error)
+	// PrepareDocument is Prepare for services that also take PDFs, which are passed through
+	// for ExtractPages
+	PrepareDocument(field, inline string, upload *models.Upload) (*models.Upload, error)
+	// ExtractPages returns the checked, normalized images of the selected pages of a PDF
+	ExtractPages(document *models.Upload, selection string) ([]*models.DocumentPage, error)
+}
+
+type imagePreprocessor struct {
+	limits      imageprep.Limits
+	maxPages    int
+	errorMapper *apperrors.APIErrorMapper
+}
+
+// NewImagePreprocessor checks images against limits; at most maxPages pages of a PDF are
+// extracted for one request
+func NewImagePreprocessor(limits imageprep.Limits, maxPages int) ImagePreprocessor {
+	limits.AllowPDF = false
+	return &imagePreprocessor{
+		limits:      limits,
+		maxPages:    maxPages,
+		errorMapper: apperrors.NewAPIErrorMapper(),
+	}
+}
+
+func (p *imagePreprocessor) Prepare(field, inline string, upload *models.Upload) (*models.Upload, error) {
+	return p.prepare(field, inline, upload, p.limits)
+}
+
+func (p *imagePreprocessor) PrepareDocument(field, inline string, upload *models.Upload) (*models.Upload, error) {
+	limits := p.limits
+	limits.AllowPDF = true
+	return p.prepare(field, inline, upload, limits)
+}
+
+func (p *imagePreprocessor) prepare(field, inline string, upload *models.Upload, limits imageprep.Limits) (*models.Upload, error) {
+	var data []byte
+	var filename string
+	if upload != nil {
+		data, filename = upload.Data, upload.Filename
+	} else {
+		decoded, err := imageprep.DecodeBase64(inline)
+		if err != nil {
+			return nil, p.reject(field, err)
+		}
+		data = decoded
+	}
+
+	result, err := imageprep.Prepare(data, limits)
+	if err != nil {
+		return nil, p.reject(field, err)
+	}
+
+	return &models.Upload{
+		Filename:    filename,
+		ContentType: result.ContentType(),
+		Data:        result.Data,
+	}, nil
+}
+
+func (p *imagePreprocessor) ExtractPages(document *models.Upload, selection string) ([]*models.DocumentPage, error) {
+	pdf, err := imageprep.OpenPDF(document.Data)
+	if err != nil {
+		return nil, p.reject("document", err)
+	}
+	numbers, err := imageprep.ParsePageSelection(selection, pdf.PageCount(), p.maxPages)
+	if err != nil {
+		return nil, p.reject("pages", err)
+	}
+	extracted, err := pdf.ExtractPages(numbers, p.limits)
+	if err != nil {
+		return nil, p.reject("document", err)
+	}
+
+	// A page is held to the same limits as an uploaded image
+	pages := make([]*models.DocumentPage, len(extracted))
+	for i, page := range extracted {
+		image, err := p.Prepare(fmt.Sprintf("page %d", page.Number), "", &models.Upload{Data: page.Data})
+		if err != nil {
+			return nil, err
+		}
+		pages[i] = &models.DocumentPage{Number: page.Number, Image: image}
+	}
+	return pages, nil
+}
+
+// reject maps an imageprep error to its error code; the details say which image failed
+func (p *imagePreprocessor) reject(field string, err error) error {
+	var prepErr *imageprep.Error
+	if !errors.As(err, &prepErr) {
+		return err
+	}
+
+	appErr := apperrors.NewAPIError(p.errorMapper, prepErr.Reason)
+	appErr.Details = field + ": " + prepErr.Error()
+	return appErr
+}
@@ -0,0 +1,137 @@
+// internal/services/notification_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+)
+
+// NotificationService delivers account notifications by email or webhook
+type NotificationService interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+	SendWebhook(ctx context.Context, webhookURL, event string, payload interface{}) error
+	// CheckWebhookURL rejects webhook URLs SendWebhook would refuse to deliver to
+	CheckWebhookURL(ctx context.Context, webhookURL string) error
+}
+
+type notificationService struct {
+	httpClient *http.Client
+	// allowPrivateWebhooks lets webhooks reach loopback and private networks, for local development
+	allowPrivateWebhooks bool
+	smtpHost             string
+	smtpPort             string
+	smtpUser             string
+	smtpPass             string
+	smtpFrom             string
+}
+
+// NewNotificationService reads SMTP settings from the environment; email is skipped when SMTP_HOST is unset.
+// Webhooks are only delivered to public addresses unless WEBHOOK_ALLOW_PRIVATE is "true".
+func NewNotificationService() NotificationService {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	allowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+
+	return &notificationService{
+		httpClient:           newWebhookClient(10*time.Second, allowPrivate),
+		allowPrivateWebhooks: allowPrivate,
+		smtpHost:             os.Getenv("SMTP_HOST"),
+		smtpPort:             port,
+		smtpUser:             os.Getenv("SMTP_USERNAME"),
+		smtpPass:             os.Getenv("SMTP_PASSWORD"),
+		smtpFrom:             os.Getenv("SMTP_FROM"),
+	}
+}
+
+func (s *notificationService) SendEmail(ctx context.Context, to, subject, body string) error {
+	if s.smtpHost == "" {
+		log.Printf("SMTP_HOST not configured, skipping email to %s: %s", to, subject)
+		return nil
+	}
+
+	from := s.smtpFrom
+	if from == "" {
+		from = s.smtpUser
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", from, to, subject, body)
+
+	var auth smtp.Auth
+	if s.smtpUser != "" {
+		auth = smtp.PlainAuth("", s.smtpUser, s.smtpPass, s.smtpHost)
+	}
+
+	if err := smtp.SendMail(s.smtpHost+":"+s.smtpPort, auth, from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationService) CheckWebhookURL(ctx context.Context, webhookURL string) error {
+	return checkWebhookURL(ctx, net.DefaultResolver, webhookURL, s.allowPrivateWebhooks)
+}
+
+func (s *notificationService) SendWebhook(ctx context.Context, webhookURL, event string, payload interface{}) error {
+	// The URL was checked when it was saved; check again, since DNS may have changed since
+	if err := s.CheckWebhookURL(ctx, webhookURL); err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Webhook-Event", event)
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned non-success status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// lowBalanceEmailBody renders the plain text email sent for a low-balance alert
+func lowBalanceEmailBody(event *models.LowBalanceEvent) string {
+	body := fmt.Sprintf(
+		"Your credit balance has dropped to %d, below your alert threshold of %d.",
+		event.CurrentBalance,
+		event.Threshold,
+	)
+	if event.AutoTopUpAmount > 0 {
+		body += fmt.Sprintf("\n\nAuto top-up added %d credits to your account.", event.AutoTopUpAmount)
+	}
+	return body
+}
+
+// apiKeyExpiryEmailBody renders the plain text email sent before an API key expires
+func apiKeyExpiryEmailBody(apiKey *models.APIKey) string {
+	return fmt.Sprintf(
+		"Your API key %q (%s...) expires on %s.\n\nCreate a new key before then to avoid failed requests.",
+		apiKey.KeyName,
+		apiKey.KeyPrefix,
+		apiKey.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"),
+	)
+}
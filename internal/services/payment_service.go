@@ -0,0 +1,114 @@
+// internal/services/payment_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// errPaymentMethodNotFound means the provider has no such payment method for the user
+var errPaymentMethodNotFound = errors.New("payment method not found")
+
+// PaymentService charges a user's stored payment method for credit purchases
+type PaymentService interface {
+	IsConfigured() bool
+	// VerifyPaymentMethod checks with the provider that paymentMethodID is stored for userID;
+	// it returns errPaymentMethodNotFound when it is not
+	VerifyPaymentMethod(ctx context.Context, userID, paymentMethodID string) error
+	ChargeCredits(ctx context.Context, userID, paymentMethodID string, credits int) error
+}
+
+type paymentService struct {
+	httpClient *http.Client
+	apiURL     string
+	methodsURL string
+}
+
+// NewPaymentService charges through PAYMENT_API_URL and looks payment methods up under
+// PAYMENT_METHODS_API_URL; auto top-up is unavailable unless both are set
+func NewPaymentService() PaymentService {
+	return &paymentService{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		apiURL:     os.Getenv("PAYMENT_API_URL"),
+		methodsURL: strings.TrimSuffix(os.Getenv("PAYMENT_METHODS_API_URL"), "/"),
+	}
+}
+
+func (s *paymentService) IsConfigured() bool {
+	return s.apiURL != "" && s.methodsURL != ""
+}
+
+// VerifyPaymentMethod asks the provider for GET {methodsURL}/{paymentMethodID}?user_id={userID},
+// which answers 200 only for a method stored for that user
+func (s *paymentService) VerifyPaymentMethod(ctx context.Context, userID, paymentMethodID string) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("payment provider is not configured")
+	}
+
+	methodURL := s.methodsURL + "/" + url.PathEscape(paymentMethodID) + "?" + url.Values{"user_id": {userID}}.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", methodURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create payment method request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call payment API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusForbidden:
+		return errPaymentMethodNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	return fmt.Errorf("payment API returned non-OK status %d: %s", resp.StatusCode, string(body))
+}
+
+func (s *paymentService) ChargeCredits(ctx context.Context, userID, paymentMethodID string, credits int) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("payment provider is not configured")
+	}
+
+	payload := map[string]interface{}{
+		"user_id":           userID,
+		"payment_method_id": paymentMethodID,
+		"credits":           credits,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create payment request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call payment API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("payment API returned non-OK status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
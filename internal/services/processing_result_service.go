@@ -0,0 +1,135 @@
+// internal/services/processing_result_service.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+)
+
+// processingLockTTL outlives the request timeout, so a live request never loses its claim
+const processingLockTTL = 2 * time.Minute
+
+type ProcessingResultService interface {
+	// Claim reserves req_id for a new call of service and reports whether it did. It returns
+	// the stored result when the user already sent the same request. Only users who enabled
+	// deduplication in their result retention settings are deduplicated; for everyone else
+	// nothing is claimed or stored.
+	Claim(ctx context.Context, userID, service, reqID string, request interface{}) (previous *models.ProcessingResult, claimed bool, err error)
+	Complete(ctx context.Context, userID, service, reqID string, result interface{}, creditsUsed int) error
+	Release(ctx context.Context, userID, service, reqID string) error
+	GetResult(ctx context.Context, userID, service, reqID string) (*models.ProcessingResultResponse, error)
+}
+
+type processingResultService struct {
+	resultRepo    repository.ProcessingResultRepository
+	retentionRepo repository.ResultRetentionRepository
+	window        time.Duration
+}
+
+// NewProcessingResultService keeps the results of opted-in users for window; a zero window
+// turns deduplication off for everyone
+func NewProcessingResultService(resultRepo repository.ProcessingResultRepository, retentionRepo repository.ResultRetentionRepository, window time.Duration) ProcessingResultService {
+	return &processingResultService{
+		resultRepo:    resultRepo,
+		retentionRepo: retentionRepo,
+		window:        window,
+	}
+}
+
+func (s *processingResultService) Claim(ctx context.Context, userID, service, reqID string, request interface{}) (*models.ProcessingResult, bool, error) {
+	if s.window <= 0 {
+		return nil, false, nil
+	}
+
+	// Deduplication stores full results, so it only runs for users who asked for it
+	settings, err := s.retentionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if settings == nil || !settings.DedupEnabled {
+		return nil, false, nil
+	}
+
+	fingerprint, err := requestFingerprint(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, claimed, err := claimOrLoad(
+		func() (bool, error) {
+			return s.resultRepo.Claim(ctx, userID, service, reqID, fingerprint, processingLockTTL, s.window)
+		},
+		func() (*models.ProcessingResult, error) { return s.resultRepo.Get(ctx, userID, service, reqID) },
+	)
+	if err != nil || claimed {
+		return nil, claimed, err
+	}
+
+	switch {
+	case existing == nil || (existing.Fingerprint == fingerprint && existing.State != models.ClaimStateCompleted):
+		return nil, false, apperrors.NewAppError(apperrors.ErrConflict, http.StatusConflict, "a request with this req_id is still being processed")
+	case existing.Fingerprint != fingerprint:
+		return nil, false, apperrors.NewAppError(apperrors.ErrConflict, http.StatusConflict, "req_id was already used for a different request")
+	}
+	return existing, false, nil
+}
+
+// requestFingerprint hashes the request's JSON and the content of any uploaded files
+func requestFingerprint(request interface{}) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write(body)
+	if carrier, ok := request.(models.UploadCarrier); ok {
+		// Mark every slot, so a file moved to another field changes the fingerprint
+		for _, upload := range carrier.Uploads() {
+			if upload == nil {
+				hash.Write([]byte{0})
+				continue
+			}
+			sum := sha256.Sum256(upload.Data)
+			hash.Write([]byte{1})
+			hash.Write(sum[:])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Complete and Release are only called for requests that Claim claimed
+func (s *processingResultService) Complete(ctx context.Context, userID, service, reqID string, result interface{}, creditsUsed int) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.resultRepo.Complete(ctx, userID, service, reqID, data, creditsUsed, s.window)
+}
+
+func (s *processingResultService) Release(ctx context.Context, userID, service, reqID string) error {
+	return s.resultRepo.Release(ctx, userID, service, reqID)
+}
+
+func (s *processingResultService) GetResult(ctx context.Context, userID, service, reqID string) (*models.ProcessingResultResponse, error) {
+	result, err := s.resultRepo.Get(ctx, userID, service, reqID)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound, http.StatusNotFound, "no result for this req_id")
+	}
+
+	return &models.ProcessingResultResponse{
+		Message: "Result retrieved successfully",
+		Result:  result,
+	}, nil
+}
@@ -2,11 +2,9 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"time"
@@ -34,29 +32,21 @@ func NewQRExtractionAPIService() QRExtractionAPIService {
 }
 
 func (s *qrExtractionAPIService) ProcessQRExtraction(ctx context.Context, req *models.QRExtractionRequest) (*models.QRExtractionResult, error) {
-	// Prepare the request payload exactly as expected by the API
-	payload := map[string]interface{}{
-		"req_id":     req.ReqID,
-		"doc_base64": req.DocBase64,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
-	}
+	// Prepare the request payload exactly as expected by the API; images are
+	// base64-encoded while the request is sent
+	payload := newJSONPayload().
+		Field("req_id", req.ReqID).
+		Image("doc_base64", req.Doc)
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonData))
+	httpReq, err := payload.NewRequest(ctx, s.apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Log the request for debugging
 	log.Printf("Making QR Extraction API request to: %s", s.apiURL)
-	log.Printf("Request payload: %s", string(jsonData))
+	log.Printf("Request ReqID: %s, payload size: %d bytes", req.ReqID, payload.Len())
 
 	// Make the API call
 	resp, err := s.httpClient.Do(httpReq)
@@ -66,14 +56,13 @@ func (s *qrExtractionAPIService) ProcessQRExtraction(ctx context.Context, req *m
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readUpstreamBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	// Log the response for debugging
-	log.Printf("QR Extraction API response status: %d", resp.StatusCode)
-	log.Printf("QR Extraction API response body: %s", string(body))
+	// Log the response for debugging (without the body, which can hold image data)
+	log.Printf("QR Extraction API response status: %d, body size: %d bytes", resp.StatusCode, len(body))
 
 	// Handle non-OK HTTP status codes
 	if resp.StatusCode != http.StatusOK {
@@ -107,7 +96,7 @@ func (s *qrExtractionAPIService) ProcessQRExtraction(ctx context.Context, req *m
 			ReqID:   req.ReqID,
 			Success: false,
 			Status:  "failed",
-			Message: fmt.Sprintf("API returned status %d: %s", resp.StatusCode, string(body)),
+			Message: fmt.Sprintf("API returned status %d: %s", resp.StatusCode, bodySnippet(body)),
 		}, nil
 	}
 
@@ -2,11 +2,9 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"time"
@@ -35,29 +33,21 @@ func NewQRMaskingAPIService() QRMaskingAPIService {
 }
 
 func (s *qrMaskingAPIService) ProcessQRMasking(ctx context.Context, req *models.QRMaskingRequest) (*models.QRMaskingResult, error) {
-	// Prepare the request payload exactly as expected by the API
-	payload := map[string]interface{}{
-		"req_id":     req.ReqID,
-		"base64_str": req.Base64Str,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
-	}
+	// Prepare the request payload exactly as expected by the API; images are
+	// base64-encoded while the request is sent
+	payload := newJSONPayload().
+		Field("req_id", req.ReqID).
+		Image("base64_str", req.Image)
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonData))
+	httpReq, err := payload.NewRequest(ctx, s.apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Log the request for debugging
 	log.Printf("Making QR API request to: %s", s.apiURL)
-	log.Printf("Request payload: %s", string(jsonData))
+	log.Printf("Request ReqID: %s, payload size: %d bytes", req.ReqID, payload.Len())
 
 	// Make the API call
 	resp, err := s.httpClient.Do(httpReq)
@@ -67,18 +57,17 @@ func (s *qrMaskingAPIService) ProcessQRMasking(ctx context.Context, req *models.
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readUpstreamBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	// Log the response for debugging
-	log.Printf("QR API response status: %d", resp.StatusCode)
-	log.Printf("QR API response body: %s", string(body))
+	// Log the response for debugging (without the body, which can hold image data)
+	log.Printf("QR API response status: %d, body size: %d bytes", resp.StatusCode, len(body))
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("QR masking API returned non-OK status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("QR masking API returned non-OK status %d: %s", resp.StatusCode, bodySnippet(body))
 	}
 
 	// Parse the response - exact format from API specification
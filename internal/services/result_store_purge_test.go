@@ -0,0 +1,189 @@
+// internal/services/result_store_purge_test.go
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/services"
+	"chi-mongo-backend/pkg/blobstore"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryResultRepo keeps retained result records in memory
+type memoryResultRepo struct {
+	mu      sync.Mutex
+	records map[primitive.ObjectID]models.RetainedResult
+}
+
+func newMemoryResultRepo() *memoryResultRepo {
+	return &memoryResultRepo{records: map[primitive.ObjectID]models.RetainedResult{}}
+}
+
+func (r *memoryResultRepo) Create(ctx context.Context, result *models.RetainedResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[result.ID] = *result
+	return nil
+}
+
+func (r *memoryResultRepo) GetByID(ctx context.Context, userID string, id primitive.ObjectID) (*models.RetainedResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || record.UserID != userID {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (r *memoryResultRepo) GetLatestByReqID(ctx context.Context, userID, service, reqID string) (*models.RetainedResult, error) {
+	return nil, errors.New("not used by the purge")
+}
+
+func (r *memoryResultRepo) GetExpired(ctx context.Context, before time.Time, limit int) ([]models.RetainedResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []models.RetainedResult
+	for _, record := range r.records {
+		if record.ExpiresAt.Before(before) {
+			expired = append(expired, record)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (r *memoryResultRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, id)
+	return nil
+}
+
+// failingDeleteStore fails deletes of one key
+type failingDeleteStore struct {
+	blobstore.BlobStore
+	key string
+}
+
+func (s *failingDeleteStore) Delete(ctx context.Context, key string) error {
+	if key == s.key {
+		return errors.New("store unavailable")
+	}
+	return s.BlobStore.Delete(ctx, key)
+}
+
+// putResult stores a blob and its record expiring at expiresAt
+func putResult(t *testing.T, store blobstore.BlobStore, repo *memoryResultRepo, expiresAt time.Time) models.RetainedResult {
+	t.Helper()
+	record := models.RetainedResult{
+		ID:          primitive.NewObjectID(),
+		UserID:      "user-1",
+		ServiceName: "qr-masking",
+		ExpiresAt:   expiresAt,
+	}
+	record.BlobKey = fmt.Sprintf("qr-masking/%s.json", record.ID.Hex())
+
+	ctx := context.Background()
+	if err := store.Put(ctx, record.BlobKey, []byte(`{}`), "application/json"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := repo.Create(ctx, &record); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return record
+}
+
+func newLocalStore(t *testing.T) *blobstore.LocalStore {
+	t.Helper()
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// TestPurgeExpiredResults checks that the purge removes every expired blob and record across
+// several batches and keeps unexpired results
+func TestPurgeExpiredResults(t *testing.T) {
+	store := newLocalStore(t)
+	repo := newMemoryResultRepo()
+	service := services.NewResultStoreService(store, repo, nil, nil, 30)
+
+	now := time.Now()
+	var expired []models.RetainedResult
+	for i := 0; i < 250; i++ {
+		expired = append(expired, putResult(t, store, repo, now.Add(-time.Duration(i+1)*time.Minute)))
+	}
+	kept := putResult(t, store, repo, now.Add(time.Hour))
+
+	ctx := context.Background()
+	purged, err := service.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	if purged != len(expired) {
+		t.Errorf("purged %d results, want %d", purged, len(expired))
+	}
+
+	for _, record := range expired {
+		if _, err := store.Get(ctx, record.BlobKey); !errors.Is(err, blobstore.ErrNotFound) {
+			t.Fatalf("blob %s of an expired result was kept: %v", record.BlobKey, err)
+		}
+	}
+	if len(repo.records) != 1 {
+		t.Errorf("%d records left, want only the unexpired one", len(repo.records))
+	}
+	if _, err := store.Get(ctx, kept.BlobKey); err != nil {
+		t.Errorf("blob of an unexpired result was removed: %v", err)
+	}
+}
+
+// TestPurgeExpiredResultsKeepsRecordOnFailedDelete checks that a blob that could not be
+// deleted keeps its record, so the next run retries it
+func TestPurgeExpiredResultsKeepsRecordOnFailedDelete(t *testing.T) {
+	local := newLocalStore(t)
+	repo := newMemoryResultRepo()
+
+	now := time.Now()
+	first := putResult(t, local, repo, now.Add(-2*time.Hour))
+	stuck := putResult(t, local, repo, now.Add(-time.Hour))
+
+	store := &failingDeleteStore{BlobStore: local, key: stuck.BlobKey}
+	service := services.NewResultStoreService(store, repo, nil, nil, 30)
+
+	ctx := context.Background()
+	purged, err := service.PurgeExpired(ctx)
+	if err == nil {
+		t.Fatal("PurgeExpired succeeded, want the delete error")
+	}
+	if purged != 1 {
+		t.Errorf("purged %d results, want 1", purged)
+	}
+	if _, ok := repo.records[first.ID]; ok {
+		t.Error("record of the deleted blob was kept")
+	}
+	if _, ok := repo.records[stuck.ID]; !ok {
+		t.Error("record of the blob that failed to delete was removed")
+	}
+
+	// Once the store recovers the next run finishes the purge
+	service = services.NewResultStoreService(local, repo, nil, nil, 30)
+	if purged, err := service.PurgeExpired(ctx); err != nil || purged != 1 {
+		t.Fatalf("retried PurgeExpired = %d, %v; want 1, nil", purged, err)
+	}
+	if len(repo.records) != 0 {
+		t.Errorf("%d records left after the retry, want none", len(repo.records))
+	}
+}
@@ -0,0 +1,244 @@
+// internal/services/result_store_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	"chi-mongo-backend/pkg/blobstore"
+	apperrors "chi-mongo-backend/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// resultPurgeBatch is how many expired results one purge pass loads at a time
+const resultPurgeBatch = 100
+
+type ResultStoreService interface {
+	// Retain keeps the result when the user opted in and returns its ID; it returns "" when
+	// the user keeps no results or no store is configured
+	Retain(ctx context.Context, userID, service, reqID string, result interface{}) (string, error)
+	GetByUsageID(ctx context.Context, userID, usageID string) (*models.RetainedResultResponse, error)
+	GetByReqID(ctx context.Context, userID, service, reqID string) (*models.RetainedResultResponse, error)
+	GetSettings(ctx context.Context, userID string) (*models.ResultRetentionResponse, error)
+	UpdateSettings(ctx context.Context, userID string, req *models.UpdateResultRetentionRequest) (*models.ResultRetentionResponse, error)
+	// PurgeExpired deletes results whose retention period has passed and returns how many
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+type resultStoreService struct {
+	store            blobstore.BlobStore
+	resultRepo       repository.RetainedResultRepository
+	retentionRepo    repository.ResultRetentionRepository
+	usageRepo        repository.UsageRepository
+	maxRetentionDays int
+}
+
+// NewResultStoreService keeps results in store for at most maxRetentionDays; a nil store
+// turns retention off
+func NewResultStoreService(
+	store blobstore.BlobStore,
+	resultRepo repository.RetainedResultRepository,
+	retentionRepo repository.ResultRetentionRepository,
+	usageRepo repository.UsageRepository,
+	maxRetentionDays int,
+) ResultStoreService {
+	return &resultStoreService{
+		store:            store,
+		resultRepo:       resultRepo,
+		retentionRepo:    retentionRepo,
+		usageRepo:        usageRepo,
+		maxRetentionDays: maxRetentionDays,
+	}
+}
+
+func (s *resultStoreService) Retain(ctx context.Context, userID, service, reqID string, result interface{}) (string, error) {
+	if s.store == nil {
+		return "", nil
+	}
+
+	settings, err := s.retentionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if settings == nil || !settings.Enabled {
+		return "", nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	// A lowered limit also applies to users who chose a longer period before
+	days := settings.RetentionDays
+	if days > s.maxRetentionDays {
+		days = s.maxRetentionDays
+	}
+
+	now := time.Now()
+	record := &models.RetainedResult{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		ServiceName: service,
+		ReqID:       reqID,
+		ContentType: "application/json",
+		Size:        int64(len(data)),
+		CreatedAt:   now,
+		ExpiresAt:   now.AddDate(0, 0, days),
+	}
+	record.BlobKey = fmt.Sprintf("%s/%s.json", service, record.ID.Hex())
+
+	if err := s.store.Put(ctx, record.BlobKey, data, record.ContentType); err != nil {
+		return "", err
+	}
+	if err := s.resultRepo.Create(ctx, record); err != nil {
+		// Without its record the blob would never be purged
+		if delErr := s.store.Delete(ctx, record.BlobKey); delErr != nil {
+			log.Printf("Failed to remove orphaned result blob %s: %v", record.BlobKey, delErr)
+		}
+		return "", err
+	}
+
+	return record.ID.Hex(), nil
+}
+
+func (s *resultStoreService) GetByUsageID(ctx context.Context, userID, usageID string) (*models.RetainedResultResponse, error) {
+	id, err := primitive.ObjectIDFromHex(usageID)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation, http.StatusBadRequest, "invalid usage_id")
+	}
+
+	usage, err := s.usageRepo.GetUsageByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil || usage.UserID != userID || usage.ResultID == "" {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound, http.StatusNotFound, "no retained result for this usage record")
+	}
+
+	resultID, err := primitive.ObjectIDFromHex(usage.ResultID)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.resultRepo.GetByID(ctx, userID, resultID)
+	if err != nil {
+		return nil, err
+	}
+	return s.load(ctx, record)
+}
+
+func (s *resultStoreService) GetByReqID(ctx context.Context, userID, service, reqID string) (*models.RetainedResultResponse, error) {
+	record, err := s.resultRepo.GetLatestByReqID(ctx, userID, service, reqID)
+	if err != nil {
+		return nil, err
+	}
+	return s.load(ctx, record)
+}
+
+// load reads the blob of a record; a nil record means there is nothing to return
+func (s *resultStoreService) load(ctx context.Context, record *models.RetainedResult) (*models.RetainedResultResponse, error) {
+	if record == nil || s.store == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound, http.StatusNotFound, "retained result not found or expired")
+	}
+
+	data, err := s.store.Get(ctx, record.BlobKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, apperrors.NewAppError(apperrors.ErrNotFound, http.StatusNotFound, "retained result not found or expired")
+		}
+		return nil, err
+	}
+
+	return &models.RetainedResultResponse{
+		Message: "Retained result retrieved successfully",
+		Record:  record,
+		Result:  data,
+	}, nil
+}
+
+func (s *resultStoreService) GetSettings(ctx context.Context, userID string) (*models.ResultRetentionResponse, error) {
+	settings, err := s.retentionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		// Nothing stored yet - results are not kept by default
+		settings = &models.ResultRetentionSettings{UserID: userID}
+	}
+
+	return &models.ResultRetentionResponse{
+		Message:          "Result retention settings retrieved successfully",
+		Settings:         settings,
+		MaxRetentionDays: s.maxRetentionDays,
+	}, nil
+}
+
+// UpdateSettings changes retention for results kept from now on; results already kept
+// keep their expiry
+func (s *resultStoreService) UpdateSettings(ctx context.Context, userID string, req *models.UpdateResultRetentionRequest) (*models.ResultRetentionResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+	if req.Enabled && s.store == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "result retention is not available")
+	}
+	if req.RetentionDays > s.maxRetentionDays {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed",
+			fmt.Sprintf("retentionDays must be at most %d", s.maxRetentionDays))
+	}
+
+	settings := &models.ResultRetentionSettings{
+		UserID:        userID,
+		Enabled:       req.Enabled,
+		RetentionDays: req.RetentionDays,
+		DedupEnabled:  req.DedupEnabled,
+	}
+	if err := s.retentionRepo.Upsert(ctx, settings); err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to save result retention settings")
+	}
+
+	return &models.ResultRetentionResponse{
+		Message:          "Result retention settings updated successfully",
+		Settings:         settings,
+		MaxRetentionDays: s.maxRetentionDays,
+	}, nil
+}
+
+// PurgeExpired removes each expired blob before its record, so a failed delete leaves the
+// record in place to be retried on the next run
+func (s *resultStoreService) PurgeExpired(ctx context.Context) (int, error) {
+	if s.store == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	purged := 0
+	for {
+		expired, err := s.resultRepo.GetExpired(ctx, now, resultPurgeBatch)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, record := range expired {
+			if err := s.store.Delete(ctx, record.BlobKey); err != nil {
+				return purged, fmt.Errorf("delete result blob %s: %w", record.BlobKey, err)
+			}
+			if err := s.resultRepo.Delete(ctx, record.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(expired) < resultPurgeBatch {
+			return purged, nil
+		}
+	}
+}
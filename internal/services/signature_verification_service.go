@@ -2,11 +2,9 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 	"log"
@@ -33,29 +31,21 @@ func NewSignatureVerificationAPIService() SignatureVerificationAPIService {
 }
 
 func (s *signatureVerificationAPIService) ProcessSignatureVerification(ctx context.Context, req *models.SignatureVerificationRequest) (*models.SignatureVerificationResult, error) {
-	// Prepare the request payload exactly as expected by the API
-	payload := map[string]interface{}{
-		"req_id":     req.ReqID,
-		"doc_base64": req.DocBase64,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
-	}
+	// Prepare the request payload exactly as expected by the API; images are
+	// base64-encoded while the request is sent
+	payload := newJSONPayload().
+		Field("req_id", req.ReqID).
+		Images("doc_base64", req.Docs)
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonData))
+	httpReq, err := payload.NewRequest(ctx, s.apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// Log the request for debugging (without full base64 data)
+	// Log the request for debugging (without the image data)
 	log.Printf("Making Signature Verification API request to: %s", s.apiURL)
-	log.Printf("Request ReqID: %s, DocBase64 count: %d", req.ReqID, len(req.DocBase64))
+	log.Printf("Request ReqID: %s, DocBase64 count: %d, payload size: %d bytes", req.ReqID, max(len(req.Docs), len(req.DocBase64)), payload.Len())
 
 	// Make the API call
 	resp, err := s.httpClient.Do(httpReq)
@@ -65,18 +55,17 @@ func (s *signatureVerificationAPIService) ProcessSignatureVerification(ctx conte
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readUpstreamBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	// Log the response for debugging
-	log.Printf("Signature Verification API response status: %d", resp.StatusCode)
-	log.Printf("Signature Verification API response body: %s", string(body))
+	// Log the response for debugging (without the body, which can hold image data)
+	log.Printf("Signature Verification API response status: %d, body size: %d bytes", resp.StatusCode, len(body))
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("signature verification API returned non-OK status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("signature verification API returned non-OK status %d: %s", resp.StatusCode, bodySnippet(body))
 	}
 
 	// Parse the response - exact format from API specification
@@ -0,0 +1,266 @@
+// internal/services/token_redeem_race_test.go
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"chi-mongo-backend/internal/database"
+	"chi-mongo-backend/internal/models"
+	"chi-mongo-backend/internal/repository"
+	"chi-mongo-backend/internal/services"
+	"chi-mongo-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	raceWorkers        = 64
+	raceInitialCredits = 10
+	raceTokenCredits   = 100
+)
+
+// raceHarness runs the real token service and repositories against a scratch database
+type raceHarness struct {
+	tokenService   services.CreditTokenService
+	tokenRepo      repository.TokenRepository
+	creditsRepo    repository.CreditsRepository
+	redemptionRepo repository.RedemptionRepository
+}
+
+// newRaceHarness connects to MONGODB_URI and creates a scratch database with the production
+// indexes, which the promo per-user limit relies on. The test is skipped without MongoDB.
+func newRaceHarness(t *testing.T) *raceHarness {
+	t.Helper()
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := client.Database(fmt.Sprintf("redeemrace_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Drop(ctx); err != nil {
+			t.Errorf("failed to drop scratch database %s: %v", db.Name(), err)
+		}
+		client.Disconnect(ctx)
+	})
+
+	mongoDB := &database.MongoDB{Client: client, Database: db}
+	if err := mongoDB.CreateIndexes(ctx); err != nil {
+		t.Fatalf("failed to create indexes: %v", err)
+	}
+
+	tokenRepo := repository.NewTokenRepository(db.Collection("tokens"))
+	creditsRepo := repository.NewCreditsRepository(db.Collection("credits"), db.Collection("users"))
+	redemptionRepo := repository.NewRedemptionRepository(db.Collection("token_redemptions"))
+	tokenService := services.NewCreditTokenService(
+		tokenRepo,
+		creditsRepo,
+		redemptionRepo,
+		repository.NewUserRepository(db.Collection("users")),
+		repository.NewTokenAuditRepository(db.Collection("token_audit")),
+		services.NewAdminDirectoryService(repository.NewAdminRepository(db.Collection("admins"))),
+		services.NewActivityEmitter(repository.NewActivityRepository(db.Collection("activities"))),
+	)
+	return &raceHarness{tokenService: tokenService, tokenRepo: tokenRepo, creditsRepo: creditsRepo, redemptionRepo: redemptionRepo}
+}
+
+// createToken stores a fresh token; maxRedemptions > 0 makes it a promo code
+func (h *raceHarness) createToken(t *testing.T, maxRedemptions, maxPerUser int) string {
+	t.Helper()
+	code, err := models.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	err = h.tokenRepo.Create(context.Background(), &models.CreditToken{
+		Token:          code,
+		Credits:        raceTokenCredits,
+		CreatedBy:      "redeemrace",
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(time.Hour),
+		MaxRedemptions: maxRedemptions,
+		MaxPerUser:     maxPerUser,
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return code
+}
+
+// createUsers gives each user an account with raceInitialCredits
+func (h *raceHarness) createUsers(t *testing.T, prefix string, n int) []string {
+	t.Helper()
+	userIDs := make([]string, n)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprintf("%s-%d", prefix, i)
+		if err := h.creditsRepo.Create(context.Background(), &models.Credits{UserID: userIDs[i], Credits: raceInitialCredits}); err != nil {
+			t.Fatalf("failed to create credits: %v", err)
+		}
+	}
+	return userIDs
+}
+
+// race releases one redeemer per entry in userIDs at once and returns how many succeeded
+func (h *raceHarness) race(code string, userIDs []string) int {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successes := 0
+	for _, userID := range userIDs {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			<-start
+			if _, err := h.tokenService.RedeemToken(context.Background(), &models.RedeemTokenRequest{Token: code}, userID); err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}(userID)
+	}
+	close(start)
+	wg.Wait()
+	return successes
+}
+
+// credited returns the credits granted to each user beyond raceInitialCredits
+func (h *raceHarness) credited(t *testing.T, userIDs []string) map[string]int {
+	t.Helper()
+	granted := make(map[string]int, len(userIDs))
+	for _, userID := range userIDs {
+		credits, err := h.creditsRepo.GetByUserID(context.Background(), userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		granted[userID] = credits.Credits - raceInitialCredits
+	}
+	return granted
+}
+
+func sumCredited(granted map[string]int) int {
+	total := 0
+	for _, credits := range granted {
+		total += credits
+	}
+	return total
+}
+
+func TestRedeemSingleUseTokenRace(t *testing.T) {
+	h := newRaceHarness(t)
+
+	for round := 0; round < 10; round++ {
+		code := h.createToken(t, 0, 0)
+		userIDs := h.createUsers(t, fmt.Sprintf("single-%d", round), raceWorkers)
+
+		successes := h.race(code, userIDs)
+		total := sumCredited(h.credited(t, userIDs))
+		if successes != 1 || total != raceTokenCredits {
+			t.Fatalf("round %d: expected 1 success and %d credits granted, got %d successes and %d credits",
+				round, raceTokenCredits, successes, total)
+		}
+	}
+}
+
+func TestRedeemPromoCodeTotalLimitRace(t *testing.T) {
+	h := newRaceHarness(t)
+	const maxRedemptions = 5
+
+	code := h.createToken(t, maxRedemptions, 1)
+	userIDs := h.createUsers(t, "promo-total", raceWorkers)
+
+	successes := h.race(code, userIDs)
+	total := sumCredited(h.credited(t, userIDs))
+	if successes != maxRedemptions || total != maxRedemptions*raceTokenCredits {
+		t.Fatalf("expected %d successes and %d credits granted, got %d successes and %d credits",
+			maxRedemptions, maxRedemptions*raceTokenCredits, successes, total)
+	}
+}
+
+func TestRedeemPromoCodePerUserLimitRace(t *testing.T) {
+	h := newRaceHarness(t)
+	const maxPerUser = 3
+
+	code := h.createToken(t, raceWorkers, maxPerUser)
+	userIDs := h.createUsers(t, "promo-user", 4)
+
+	// Every user redeems from many goroutines at once
+	var attempts []string
+	for i := 0; i < raceWorkers/len(userIDs); i++ {
+		attempts = append(attempts, userIDs...)
+	}
+
+	successes := h.race(code, attempts)
+	if successes != maxPerUser*len(userIDs) {
+		t.Fatalf("expected %d successes, got %d", maxPerUser*len(userIDs), successes)
+	}
+	for userID, credits := range h.credited(t, userIDs) {
+		if credits != maxPerUser*raceTokenCredits {
+			t.Errorf("user %s: expected %d credits granted, got %d", userID, maxPerUser*raceTokenCredits, credits)
+		}
+	}
+}
+
+// TestRedeemPromoCodeAfterRollback removes a middle redemption, the way a rollback or the stale
+// redemption release does, and checks the freed sequence can be claimed again
+func TestRedeemPromoCodeAfterRollback(t *testing.T) {
+	h := newRaceHarness(t)
+	const maxPerUser = 3
+	ctx := context.Background()
+
+	code := h.createToken(t, raceWorkers, maxPerUser)
+	userID := h.createUsers(t, "promo-gap", 1)[0]
+	redeem := func() error {
+		_, err := h.tokenService.RedeemToken(ctx, &models.RedeemTokenRequest{Token: code}, userID)
+		return err
+	}
+	for i := 0; i < maxPerUser; i++ {
+		if err := redeem(); err != nil {
+			t.Fatalf("redemption %d failed: %v", i+1, err)
+		}
+	}
+
+	token, err := h.tokenRepo.GetByToken(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redemptions, err := h.redemptionRepo.GetByTokenID(ctx, token.ID, &pagination.Params{Limit: maxPerUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, redemption := range redemptions {
+		if redemption.Sequence != 2 {
+			continue
+		}
+		if err := h.redemptionRepo.Delete(ctx, redemption.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.tokenRepo.ReleaseRedemption(ctx, token.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := redeem(); err != nil {
+		t.Fatalf("redeeming into the freed sequence failed: %v", err)
+	}
+	if err := redeem(); err == nil {
+		t.Fatal("redeemed past the per-user limit")
+	}
+	if credits := h.credited(t, []string{userID})[userID]; credits != (maxPerUser+1)*raceTokenCredits {
+		t.Errorf("expected %d credits granted, got %d", (maxPerUser+1)*raceTokenCredits, credits)
+	}
+}
@@ -3,11 +3,14 @@ package services
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/repository"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
+	"chi-mongo-backend/pkg/validation"
 	
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -15,28 +18,54 @@ import (
 type CreditTokenService interface {
 	GenerateToken(ctx context.Context, req *models.GenerateTokenRequest, createdBy string) (*models.TokenResponse, error)
 	RedeemToken(ctx context.Context, req *models.RedeemTokenRequest, userID string) (*models.TokenResponse, error)
-	GetTokensByCreatedBy(ctx context.Context, createdBy string) ([]*models.CreditToken, error)
-	GetAllTokens(ctx context.Context) ([]*models.CreditToken, error)
-	GetTokensByStatus(ctx context.Context, isUsed bool) ([]*models.CreditToken, error)
-	DeleteToken(ctx context.Context, tokenID string, adminEmail string) (*models.TokenResponse, error)
+	GetTokensByCreatedBy(ctx context.Context, createdBy string, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error)
+	GetAllTokens(ctx context.Context, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error)
+	GetTokensByStatus(ctx context.Context, isUsed bool, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error)
+	// Revocation, ownership and audit methods
+	RevokeToken(ctx context.Context, tokenID string, req *models.RevokeTokenRequest, adminEmail string, isSuperAdmin bool) (*models.TokenResponse, error)
+	TransferToken(ctx context.Context, tokenID string, req *models.TransferTokenRequest, adminEmail string, isSuperAdmin bool) (*models.TokenResponse, error)
+	GetTokenAudit(ctx context.Context, tokenID string) (*models.TokenAuditResponse, error)
+	CleanupExpiredTokens(ctx context.Context) (int, error)
+	ReleaseStaleRedemptions(ctx context.Context, olderThan time.Duration) (int, error)
+	// Promo code methods
+	CreatePromoCode(ctx context.Context, req *models.CreatePromoCodeRequest, createdBy string) (*models.TokenResponse, error)
+	GetTokenRedemptions(ctx context.Context, tokenID string, page *pagination.Params) (*models.TokenRedemptionsResponse, error)
 }
 
 type creditTokenService struct {
-	tokenRepo   repository.TokenRepository
-	creditsRepo repository.CreditsRepository
+	tokenRepo      repository.TokenRepository
+	creditsRepo    repository.CreditsRepository
+	redemptionRepo repository.RedemptionRepository
+	userRepo       repository.UserRepository
+	auditRepo      repository.TokenAuditRepository
+	adminDirectory AdminDirectoryService
+	activity       ActivityEmitter
 }
 
-func NewCreditTokenService(tokenRepo repository.TokenRepository, creditsRepo repository.CreditsRepository) CreditTokenService {
+func NewCreditTokenService(
+	tokenRepo repository.TokenRepository,
+	creditsRepo repository.CreditsRepository,
+	redemptionRepo repository.RedemptionRepository,
+	userRepo repository.UserRepository,
+	auditRepo repository.TokenAuditRepository,
+	adminDirectory AdminDirectoryService,
+	activity ActivityEmitter,
+) CreditTokenService {
 	return &creditTokenService{
-		tokenRepo:   tokenRepo,
-		creditsRepo: creditsRepo,
+		tokenRepo:      tokenRepo,
+		creditsRepo:    creditsRepo,
+		redemptionRepo: redemptionRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		adminDirectory: adminDirectory,
+		activity:       activity,
 	}
 }
 
 func (s *creditTokenService) GenerateToken(ctx context.Context, req *models.GenerateTokenRequest, createdBy string) (*models.TokenResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
 	}
 
 	// Generate unique token
@@ -64,9 +93,14 @@ func (s *creditTokenService) GenerateToken(ctx context.Context, req *models.Gene
 		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to create token")
 	}
 
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(token, models.TokenEventCreated, createdBy, map[string]interface{}{
+		"credits": token.Credits,
+	}))
+
 	return &models.TokenResponse{
 		Message:     "Token generated successfully",
-		Token:       tokenStr,
+		ID:          token.ID.Hex(),
+		Token:       models.FormatTokenCode(tokenStr),
 		Credits:     req.Credits,
 		ExpiresAt:   expiresAt,
 		Description: req.Description,
@@ -76,102 +110,579 @@ func (s *creditTokenService) GenerateToken(ctx context.Context, req *models.Gene
 func (s *creditTokenService) RedeemToken(ctx context.Context, req *models.RedeemTokenRequest, userID string) (*models.TokenResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	// Normalize the code and reject typos before touching the database
+	code, err := models.NormalizeTokenCode(req.Token)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "invalid token code", "check the code for typos and try again")
 	}
 
 	// Get token from database
-	token, err := s.tokenRepo.GetByToken(ctx, req.Token)
+	token, err := s.tokenRepo.GetByToken(ctx, code)
+	if variant, ok := models.LegacyHexVariant(code); ok && apperrors.IsErrorType(err, apperrors.ErrNotFound) {
+		token, err = s.tokenRepo.GetByToken(ctx, variant)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// Multi-use promo codes are tracked in the redemptions collection
+	if token.IsPromo() {
+		return s.redeemPromoCode(ctx, token, userID)
+	}
+
 	// Check if token is already used
 	if token.IsUsed {
 		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has already been used")
 	}
 
+	// Check if token was revoked
+	if token.IsRevoked {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has been revoked")
+	}
+
 	// Check if token is expired
 	if token.IsExpired() {
 		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has expired")
 	}
 
-	// Add credits to user
-	if err := s.creditsRepo.UpdateCredits(ctx, userID, token.Credits); err != nil {
+	// Claim the token with a single conditional update so that only one of several
+	// concurrent redeemers can ever win; the losers never reach the credit increment.
+	claimed, err := s.tokenRepo.ClaimUnused(ctx, token.Token, userID)
+	if err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has already been used")
+		}
 		return nil, err
 	}
 
-	// Mark token as used
-	if err := s.tokenRepo.MarkAsUsed(ctx, req.Token, userID); err != nil {
+	// Add credits to user
+	if err := s.creditsRepo.UpdateCredits(ctx, userID, claimed.Credits); err != nil {
+		s.releaseClaim(claimed.Token, userID)
 		return nil, err
 	}
 
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(claimed, models.TokenEventRedeemed, userID, map[string]interface{}{
+		"credits": claimed.Credits,
+	}))
+	s.activity.Emit(ctx, newActivity(userID, models.ActivityTokenRedeemed, "Credit token redeemed", "", map[string]interface{}{
+		"tokenId": claimed.ID.Hex(),
+		"credits": claimed.Credits,
+	}))
+
 	// Get updated credits balance after redeeming
 	userCredits, err := s.creditsRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
 	return &models.TokenResponse{
 		Message:          "Token redeemed successfully",
-		Credits:          token.Credits,
+		Credits:          claimed.Credits,
 		RemainingCredits: userCredits.Credits, // Include remaining credits
-		UsedAt:           &now,
-		Description:      token.Description,
+		UsedAt:           claimed.UsedAt,
+		Description:      claimed.Description,
 	}, nil
 }
 
-func (s *creditTokenService) GetTokensByCreatedBy(ctx context.Context, createdBy string) ([]*models.CreditToken, error) {
-	return s.tokenRepo.GetByCreatedBy(ctx, createdBy)
+// releaseClaim hands a claimed token back when the credit increment fails
+func (s *creditTokenService) releaseClaim(token string, userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.tokenRepo.ReleaseClaim(ctx, token, userID); err != nil {
+		log.Printf("Failed to release token claim: %v", err)
+	}
+}
+
+func (s *creditTokenService) GetTokensByCreatedBy(ctx context.Context, createdBy string, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error) {
+	tokens, err := s.tokenRepo.GetByCreatedBy(ctx, createdBy, page)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	tokens, info := pagination.Page(tokens, page, tokenCursor)
+	return tokens, info, nil
+}
+
+func (s *creditTokenService) GetAllTokens(ctx context.Context, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error) {
+	tokens, err := s.tokenRepo.GetAll(ctx, page)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	tokens, info := pagination.Page(tokens, page, tokenCursor)
+	return tokens, info, nil
 }
 
-func (s *creditTokenService) GetAllTokens(ctx context.Context) ([]*models.CreditToken, error) {
-	return s.tokenRepo.GetAll(ctx)
+func (s *creditTokenService) GetTokensByStatus(ctx context.Context, isUsed bool, page *pagination.Params) ([]*models.CreditToken, pagination.Info, error) {
+	tokens, err := s.tokenRepo.GetByStatus(ctx, isUsed, page)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	tokens, info := pagination.Page(tokens, page, tokenCursor)
+	return tokens, info, nil
 }
 
-func (s *creditTokenService) GetTokensByStatus(ctx context.Context, isUsed bool) ([]*models.CreditToken, error) {
-	return s.tokenRepo.GetByStatus(ctx, isUsed)
+// tokenCursor keys token lists, which are ordered by creation time
+func tokenCursor(token *models.CreditToken) pagination.Cursor {
+	return pagination.Cursor{ID: token.ID, Time: &token.CreatedAt}
 }
 
-func (s *creditTokenService) DeleteToken(ctx context.Context, tokenID string, adminEmail string) (*models.TokenResponse, error) {
-	// Validate tokenID format (ObjectID)
+func (s *creditTokenService) RevokeToken(ctx context.Context, tokenID string, req *models.RevokeTokenRequest, adminEmail string, isSuperAdmin bool) (*models.TokenResponse, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	token, err := s.getOwnedToken(ctx, tokenID, adminEmail, isSuperAdmin, "you can only revoke tokens you created")
+	if err != nil {
+		return nil, err
+	}
+
+	if token.IsUsed {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "cannot revoke used tokens")
+	}
+	if token.IsRevoked {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has already been revoked")
+	}
+
+	// Revoked tokens are kept so the revocation reason stays on record
+	revoked, err := s.tokenRepo.Revoke(ctx, token.ID, adminEmail, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(revoked, models.TokenEventRevoked, adminEmail, map[string]interface{}{
+		"reason":     req.Reason,
+		"createdBy":  revoked.CreatedBy,
+		"superAdmin": isSuperAdmin && revoked.CreatedBy != adminEmail,
+	}))
+
+	return &models.TokenResponse{
+		Message:     "Token revoked successfully",
+		Token:       models.FormatTokenCode(revoked.Token),
+		Credits:     revoked.Credits,
+		ExpiresAt:   revoked.ExpiresAt,
+		Description: revoked.Description,
+	}, nil
+}
+
+func (s *creditTokenService) TransferToken(ctx context.Context, tokenID string, req *models.TransferTokenRequest, adminEmail string, isSuperAdmin bool) (*models.TokenResponse, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	token, err := s.getOwnedToken(ctx, tokenID, adminEmail, isSuperAdmin, "you can only transfer tokens you created")
+	if err != nil {
+		return nil, err
+	}
+
+	if token.CreatedBy == req.ToAdmin {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token is already owned by this admin")
+	}
+
+	// Tokens may only be handed to someone who has signed in as an admin
+	isAdmin, err := s.adminDirectory.IsAdmin(ctx, req.ToAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		var fields apperrors.FieldErrors
+		fields.Add("toAdmin", validation.CodeInvalid, "toAdmin is not a known admin")
+		return nil, apperrors.NewValidationError(fields.Err())
+	}
+
+	transferred, err := s.tokenRepo.TransferOwnership(ctx, token.ID, token.CreatedBy, req.ToAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(transferred, models.TokenEventTransferred, adminEmail, map[string]interface{}{
+		"from":   token.CreatedBy,
+		"to":     req.ToAdmin,
+		"reason": req.Reason,
+	}))
+
+	return &models.TokenResponse{
+		Message:     "Token ownership transferred successfully",
+		Token:       models.FormatTokenCode(transferred.Token),
+		Credits:     transferred.Credits,
+		ExpiresAt:   transferred.ExpiresAt,
+		Description: transferred.Description,
+	}, nil
+}
+
+func (s *creditTokenService) GetTokenAudit(ctx context.Context, tokenID string) (*models.TokenAuditResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "invalid token ID format")
+	}
+
+	// Tokens removed by the expiry cleanup keep their audit trail, so no token lookup here
+	events, err := s.auditRepo.GetByTokenID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenAuditResponse{
+		Message: "Token audit trail retrieved successfully",
+		TokenID: tokenID,
+		Events:  events,
+		Count:   len(events),
+	}, nil
+}
+
+// CleanupExpiredTokens records an expired event for each unredeemed expired token outside a
+// campaign and then removes them. It returns the number of tokens expired.
+func (s *creditTokenService) CleanupExpiredTokens(ctx context.Context) (int, error) {
+	tokens, err := s.tokenRepo.GetExpiredUnused(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(tokens))
+	events := make([]*models.TokenAuditEvent, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+		events = append(events, newTokenAuditEvent(token, models.TokenEventExpired, "system", map[string]interface{}{
+			"expiresAt": token.ExpiresAt,
+		}))
+	}
+
+	// Only delete once the trail is written, otherwise the tokens would vanish unrecorded.
+	// Deleting by ID removes exactly the tokens that were recorded, not ones that expired since.
+	if err := s.auditRepo.CreateMany(ctx, events); err != nil {
+		return 0, err
+	}
+	if err := s.tokenRepo.DeleteExpiredByIDs(ctx, ids); err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// getOwnedToken loads a token and checks that the admin created it, unless the caller is a super-admin
+func (s *creditTokenService) getOwnedToken(ctx context.Context, tokenID string, adminEmail string, isSuperAdmin bool, forbiddenMsg string) (*models.CreditToken, error) {
 	objID, err := primitive.ObjectIDFromHex(tokenID)
 	if err != nil {
 		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "invalid token ID format")
 	}
 
-	// Get the token first to check ownership and get details
 	token, err := s.tokenRepo.GetByID(ctx, objID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if the admin is the creator of the token (optional security check)
-	if token.CreatedBy != adminEmail {
-		return nil, apperrors.NewAppError(
-			apperrors.ErrForbidden, 
-			403, 
-			"you can only delete tokens you created",
-		)
+	if token.CreatedBy != adminEmail && !isSuperAdmin {
+		return nil, apperrors.NewAppError(apperrors.ErrForbidden, 403, forbiddenMsg)
 	}
+	return token, nil
+}
 
-	// Check if token is already used (optional business rule)
-	if token.IsUsed {
-		return nil, apperrors.NewAppError(
-			apperrors.ErrBadRequest, 
-			400, 
-			"cannot delete used tokens",
-		)
+func (s *creditTokenService) CreatePromoCode(ctx context.Context, req *models.CreatePromoCodeRequest, createdBy string) (*models.TokenResponse, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	code := req.Code
+	if code == "" {
+		generated, err := models.GenerateToken()
+		if err != nil {
+			return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to generate token")
+		}
+		code = generated
+	}
+
+	maxPerUser := req.MaxPerUser
+	if maxPerUser == 0 {
+		maxPerUser = 1
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(30 * 24 * time.Hour)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	token := &models.CreditToken{
+		Token:           code,
+		Credits:         req.Credits,
+		CreatedBy:       createdBy,
+		CreatedAt:       now,
+		ExpiresAt:       expiresAt,
+		IsUsed:          false,
+		Description:     req.Description,
+		MaxRedemptions:  req.MaxRedemptions,
+		MaxPerUser:      maxPerUser,
+		StartsAt:        req.StartsAt,
+		NewUsersOnly:    req.NewUsersOnly,
+		RedemptionCount: 0,
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		if apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		return nil, apperrors.NewAppError(apperrors.ErrInternalServer, 500, "failed to create promo code")
+	}
+
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(token, models.TokenEventCreated, createdBy, map[string]interface{}{
+		"credits":        token.Credits,
+		"promo":          true,
+		"maxRedemptions": token.MaxRedemptions,
+		"maxPerUser":     token.MaxPerUser,
+	}))
+
+	return &models.TokenResponse{
+		Message:     "Promo code created successfully",
+		ID:          token.ID.Hex(),
+		Token:       models.FormatTokenCode(code),
+		Credits:     req.Credits,
+		ExpiresAt:   expiresAt,
+		Description: req.Description,
+	}, nil
+}
+
+func (s *creditTokenService) GetTokenRedemptions(ctx context.Context, tokenID string, page *pagination.Params) (*models.TokenRedemptionsResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "invalid token ID format")
 	}
 
-	// Delete the token
-	if err := s.tokenRepo.Delete(ctx, objID); err != nil {
+	token, err := s.tokenRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, err := s.redemptionRepo.GetByTokenID(ctx, objID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, info := pagination.Page(redemptions, page, func(r models.TokenRedemption) pagination.Cursor {
+		return pagination.Cursor{ID: r.ID, Time: &r.RedeemedAt}
+	})
+
+	return &models.TokenRedemptionsResponse{
+		Message:     "Token redemptions retrieved successfully",
+		Token:       token,
+		Redemptions: redemptions,
+		Count:       len(redemptions),
+		Pagination:  info,
+	}, nil
+}
+
+// redeemPromoCode enforces promo limits without a read-then-write race: the total limit is
+// claimed with a conditional increment on the token, and the per-user limit is claimed by
+// inserting a redemption whose (token, user, sequence) key is unique.
+func (s *creditTokenService) redeemPromoCode(ctx context.Context, token *models.CreditToken, userID string) (*models.TokenResponse, error) {
+	if token.IsRevoked {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has been revoked")
+	}
+	if !token.HasStarted() {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "promo code is not active yet")
+	}
+	if token.IsExpired() {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "token has expired")
+	}
+
+	if token.NewUsersOnly {
+		user, err := s.userRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		// A new user is one who registered after the promo code became available
+		availableFrom := token.CreatedAt
+		if token.StartsAt != nil {
+			availableFrom = *token.StartsAt
+		}
+		if user.CreatedAt.Before(availableFrom) {
+			return nil, apperrors.NewAppError(apperrors.ErrForbidden, 403, "promo code is only available to new users")
+		}
+	}
+
+	maxPerUser := token.MaxPerUser
+	if maxPerUser == 0 {
+		maxPerUser = 1
+	}
+
+	// Claim a per-user slot first; a unique index rejects concurrent claims of the same slot.
+	// Take the lowest free sequence, since a rolled back redemption can leave a gap below the
+	// highest one in use.
+	var redemption *models.TokenRedemption
+	for attempt := 0; attempt < maxPerUser; attempt++ {
+		used, err := s.redemptionRepo.UsedSequences(ctx, token.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		sequence := lowestFreeSequence(used, maxPerUser)
+		if sequence == 0 {
+			break
+		}
+
+		candidate := &models.TokenRedemption{
+			TokenID:    token.ID,
+			Token:      token.Token,
+			UserID:     userID,
+			Sequence:   sequence,
+			Credits:    token.Credits,
+			RedeemedAt: time.Now(),
+			Status:     models.RedemptionStatusPending,
+		}
+		err = s.redemptionRepo.Create(ctx, candidate)
+		if err == nil {
+			redemption = candidate
+			break
+		}
+		if !apperrors.IsErrorType(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+	}
+	if redemption == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrBadRequest, 400, "promo code redemption limit reached for this user")
+	}
+
+	// Claim a slot against the total redemption limit
+	if _, err := s.tokenRepo.ReserveRedemption(ctx, token.ID); err != nil {
+		s.rollbackRedemption(redemption, false)
+		return nil, err
+	}
+	s.setRedemptionStatus(ctx, redemption, models.RedemptionStatusReserved)
+
+	// Mark the credit step before taking it: from here on the user may have been credited, so
+	// ReleaseStaleRedemptions must never hand these slots back
+	if err := s.redemptionRepo.UpdateStatus(ctx, redemption.ID, models.RedemptionStatusCrediting); err != nil {
+		s.rollbackRedemption(redemption, true)
+		return nil, err
+	}
+	redemption.Status = models.RedemptionStatusCrediting
+
+	// Add credits to user
+	if err := s.creditsRepo.UpdateCredits(ctx, userID, token.Credits); err != nil {
+		s.rollbackRedemption(redemption, true)
+		return nil, err
+	}
+
+	s.setRedemptionStatus(ctx, redemption, "")
+
+	recordTokenEvent(ctx, s.auditRepo, newTokenAuditEvent(token, models.TokenEventRedeemed, userID, map[string]interface{}{
+		"credits":  token.Credits,
+		"sequence": redemption.Sequence,
+	}))
+	s.activity.Emit(ctx, newActivity(userID, models.ActivityTokenRedeemed, "Promo code redeemed", "", map[string]interface{}{
+		"tokenId":  token.ID.Hex(),
+		"credits":  token.Credits,
+		"promo":    true,
+		"sequence": redemption.Sequence,
+	}))
+
+	userCredits, err := s.creditsRepo.GetByUserID(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
 
 	return &models.TokenResponse{
-		Message:     "Token deleted successfully",
-		Credits:     token.Credits,
-		Description: token.Description,
+		Message:          "Promo code redeemed successfully",
+		Credits:          token.Credits,
+		RemainingCredits: userCredits.Credits,
+		UsedAt:           &redemption.RedeemedAt,
+		Description:      token.Description,
 	}, nil
-}
\ No newline at end of file
+}
+
+// setRedemptionStatus records redemption progress so an interrupted redemption can be
+// found and released by ReleaseStaleRedemptions
+func (s *creditTokenService) setRedemptionStatus(ctx context.Context, redemption *models.TokenRedemption, status string) {
+	if err := s.redemptionRepo.UpdateStatus(ctx, redemption.ID, status); err != nil {
+		log.Printf("Failed to update promo redemption status: %v", err)
+		return
+	}
+	redemption.Status = status
+}
+
+// ReleaseStaleRedemptions frees the slots of promo redemptions that were interrupted, for
+// example by a crash, before the credit step began. Redemptions left in the crediting
+// status are kept, holding their slots, because the user may already have been credited.
+func (s *creditTokenService) ReleaseStaleRedemptions(ctx context.Context, olderThan time.Duration) (int, error) {
+	stale, err := s.redemptionRepo.GetStale(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, redemption := range stale {
+		// The conditional delete stops a concurrent cleanup from releasing the same slot twice
+		deleted, err := s.redemptionRepo.DeleteIfStatus(ctx, redemption.ID, redemption.Status)
+		if err != nil {
+			return released, err
+		}
+		if !deleted {
+			continue
+		}
+		if redemption.Status == models.RedemptionStatusReserved {
+			if err := s.tokenRepo.ReleaseRedemption(ctx, redemption.TokenID); err != nil {
+				return released, err
+			}
+		}
+		released++
+	}
+	return released, nil
+}
+
+// lowestFreeSequence returns the lowest sequence in 1..maxPerUser not in used, or 0 when the
+// user has reached the limit
+func lowestFreeSequence(used []int, maxPerUser int) int {
+	if len(used) >= maxPerUser {
+		return 0
+	}
+	taken := make(map[int]bool, len(used))
+	for _, sequence := range used {
+		taken[sequence] = true
+	}
+	for sequence := 1; sequence <= maxPerUser; sequence++ {
+		if !taken[sequence] {
+			return sequence
+		}
+	}
+	return 0
+}
+
+// rollbackRedemption undoes the slots claimed by a failed promo redemption
+func (s *creditTokenService) rollbackRedemption(redemption *models.TokenRedemption, releaseToken bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.redemptionRepo.Delete(ctx, redemption.ID); err != nil {
+		log.Printf("Failed to rollback promo redemption: %v", err)
+	}
+	if releaseToken {
+		if err := s.tokenRepo.ReleaseRedemption(ctx, redemption.TokenID); err != nil {
+			log.Printf("Failed to release promo redemption slot: %v", err)
+		}
+	}
+}
+
+// newTokenAuditEvent builds an audit event for a token lifecycle change
+func newTokenAuditEvent(token *models.CreditToken, event string, actor string, metadata map[string]interface{}) *models.TokenAuditEvent {
+	return &models.TokenAuditEvent{
+		TokenID:   token.ID,
+		Token:     token.Token,
+		Event:     event,
+		Actor:     actor,
+		Metadata:  metadata,
+		Timestamp: time.Now(),
+	}
+}
+
+// recordTokenEvent writes an audit event. The lifecycle change has already happened by the
+// time this runs, so a failed write is logged rather than failing the request.
+func recordTokenEvent(ctx context.Context, auditRepo repository.TokenAuditRepository, event *models.TokenAuditEvent) {
+	if err := auditRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record token %s event for %s: %v", event.Event, event.TokenID.Hex(), err)
+	}
+}
@@ -0,0 +1,193 @@
+// internal/services/upload.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"chi-mongo-backend/internal/models"
+)
+
+// uploadChunk is how much of an image is base64-encoded at a time; a multiple of 3, so
+// chunks encode without padding
+const uploadChunk = 48 << 10
+
+// Upstream responses can carry a processed image as base64, so they are read up to
+// maxUpstreamResponse and only maxBodySnippet bytes of one ever reach a log or an error
+const (
+	maxUpstreamResponse = 64 << 20
+	maxBodySnippet      = 512
+)
+
+// jsonPayload is a flat JSON object sent to an upstream API. Images are base64-encoded
+// while the request body is read, so neither the encoded images nor the whole payload are
+// ever held in memory.
+type jsonPayload struct {
+	parts []payloadPart
+	err   error
+}
+
+// payloadPart is either JSON text or an image to send base64-encoded as a JSON string
+type payloadPart struct {
+	text  []byte
+	image []byte
+}
+
+func newJSONPayload() *jsonPayload {
+	return &jsonPayload{}
+}
+
+func (p *jsonPayload) key(name string) {
+	sep := ","
+	if len(p.parts) == 0 {
+		sep = "{"
+	}
+	key, _ := json.Marshal(name)
+	p.parts = append(p.parts, payloadPart{text: []byte(sep + string(key) + ":")})
+}
+
+func (p *jsonPayload) text(text []byte) {
+	p.parts = append(p.parts, payloadPart{text: text})
+}
+
+// Field adds a value encoded as regular JSON
+func (p *jsonPayload) Field(name string, value interface{}) *jsonPayload {
+	data, err := json.Marshal(value)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	p.key(name)
+	p.text(data)
+	return p
+}
+
+// Image adds an image the way the upstream APIs take it: base64-encoded as a JSON string.
+// PrepareImages turns every image a client sends into an upload, base64 bodies included.
+func (p *jsonPayload) Image(name string, upload *models.Upload) *jsonPayload {
+	p.key(name)
+	p.image(name, upload)
+	return p
+}
+
+// Images adds an array of images
+func (p *jsonPayload) Images(name string, uploads []*models.Upload) *jsonPayload {
+	p.key(name)
+	p.text([]byte("["))
+	for i, upload := range uploads {
+		if i > 0 {
+			p.text([]byte(","))
+		}
+		p.image(name, upload)
+	}
+	p.text([]byte("]"))
+	return p
+}
+
+func (p *jsonPayload) image(name string, upload *models.Upload) {
+	if upload == nil {
+		if p.err == nil {
+			p.err = fmt.Errorf("image %s was not prepared", name)
+		}
+		return
+	}
+	p.text([]byte(`"`))
+	p.parts = append(p.parts, payloadPart{image: upload.Data})
+	p.text([]byte(`"`))
+}
+
+// Len is the exact size of the encoded payload
+func (p *jsonPayload) Len() int64 {
+	n := int64(1) // closing brace
+	for _, part := range p.parts {
+		if part.image != nil {
+			n += int64(base64.StdEncoding.EncodedLen(len(part.image)))
+		} else {
+			n += int64(len(part.text))
+		}
+	}
+	return n
+}
+
+// Reader returns the payload as a stream; every call starts from the beginning
+func (p *jsonPayload) Reader() io.Reader {
+	readers := make([]io.Reader, 0, len(p.parts)+1)
+	for _, part := range p.parts {
+		if part.image != nil {
+			readers = append(readers, &base64Reader{src: part.image})
+		} else {
+			readers = append(readers, bytes.NewReader(part.text))
+		}
+	}
+	if len(p.parts) == 0 {
+		readers = append(readers, bytes.NewReader([]byte("{")))
+	}
+	readers = append(readers, bytes.NewReader([]byte("}")))
+	return io.MultiReader(readers...)
+}
+
+// NewRequest builds a POST of the payload. The length is known up front, so the body is
+// not sent chunked, and it can be replayed on redirects.
+func (p *jsonPayload) NewRequest(ctx context.Context, url string) (*http.Request, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, p.Reader())
+	if err != nil {
+		return nil, err
+	}
+	httpReq.ContentLength = p.Len()
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(p.Reader()), nil
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// readUpstreamBody reads an upstream API response up to maxUpstreamResponse
+func readUpstreamBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamResponse+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > maxUpstreamResponse {
+		return nil, fmt.Errorf("response body is larger than %d bytes", maxUpstreamResponse)
+	}
+	return body, nil
+}
+
+// bodySnippet is the start of a response body, short enough for a log line or an error.
+// Processed images come back in the body, so it is never logged whole.
+func bodySnippet(body []byte) string {
+	if len(body) <= maxBodySnippet {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", body[:maxBodySnippet], len(body))
+}
+
+// base64Reader base64-encodes src as it is read
+type base64Reader struct {
+	src []byte
+	buf []byte
+}
+
+func (r *base64Reader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if len(r.src) == 0 {
+			return 0, io.EOF
+		}
+		chunk := r.src[:min(len(r.src), uploadChunk)]
+		r.src = r.src[len(chunk):]
+		r.buf = make([]byte, base64.StdEncoding.EncodedLen(len(chunk)))
+		base64.StdEncoding.Encode(r.buf, chunk)
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
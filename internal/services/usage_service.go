@@ -4,11 +4,16 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
 	// "net/http"
+	"sort"
 	"time"
 
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/repository"
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 )
 
 type UsageService interface {
@@ -16,17 +21,34 @@ type UsageService interface {
 	GetGlobalStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UsageStats, error)
 	GetUserStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UserUsageStats, error)
 	GetServiceUserStats(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServiceUserStats, error)
-	GetUserUsageHistory(ctx context.Context, userID string, limit, skip int) ([]models.ServiceUsage, error)
-	GetServiceUsageHistory(ctx context.Context, serviceName string, limit, skip int) ([]models.ServiceUsage, error)
+	GetUserUsageHistory(ctx context.Context, userID string, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error)
+	GetServiceUsageHistory(ctx context.Context, serviceName string, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error)
+	GetTimeSeries(ctx context.Context, filter *models.UsageTimeSeriesFilter) ([]models.UsageTimeSeriesPoint, error)
+	GetServicePerformance(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServicePerformanceStats, error)
+	// Self-service methods; the filter must carry the caller's userId
+	GetUsageSummary(ctx context.Context, filter *models.UsageFilter) (*models.UsageSummary, error)
+	GetUsageHistory(ctx context.Context, filter *models.UsageFilter, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error)
+	// ExportUsage streams every matching raw record to fn, oldest first
+	ExportUsage(ctx context.Context, filter *models.UsageFilter, fn func(*models.ServiceUsage) error) error
+	// CompactRollups refreshes the hourly and daily rollups and returns the window it covered
+	CompactRollups(ctx context.Context) (from, to time.Time, err error)
+	// SyncUserActivity reconciles users' lastActiveAt and totalCalls with the rollups
+	SyncUserActivity(ctx context.Context) error
 }
 
 type usageService struct {
-	usageRepo repository.UsageRepository
+	usageRepo   repository.UsageRepository
+	rollupRepo  repository.UsageRollupRepository
+	userRepo    repository.UserRepository
+	errorMapper *apperrors.APIErrorMapper
 }
 
-func NewUsageService(usageRepo repository.UsageRepository) UsageService {
+func NewUsageService(usageRepo repository.UsageRepository, rollupRepo repository.UsageRollupRepository, userRepo repository.UserRepository) UsageService {
 	return &usageService{
-		usageRepo: usageRepo,
+		usageRepo:   usageRepo,
+		rollupRepo:  rollupRepo,
+		userRepo:    userRepo,
+		errorMapper: apperrors.NewAPIErrorMapper(),
 	}
 }
 
@@ -39,33 +61,380 @@ func (s *usageService) TrackUsage(ctx context.Context, req *models.UsageTracking
 		Method:      req.Method,
 		Success:     req.Success,
 		ErrorMsg:    req.ErrorMsg,
+		ErrorCode:   req.ErrorCode,
 		CreditsUsed: req.CreditsUsed,
 		RequestID:   req.RequestID,
 		IPAddress:   req.IPAddress,
 		UserAgent:   req.UserAgent,
 		AuthMethod:  req.AuthMethod,
+		APIKeyID:    req.APIKeyID,
 		ProcessTime: req.ProcessTime,
+		ResultID:    req.ResultID,
 	}
 
-	return s.usageRepo.CreateUsage(ctx, usage)
+	// Store the code the caller would have seen so failures can be grouped without re-mapping
+	if !usage.Success && usage.ErrorCode == "" && usage.ErrorMsg != "" {
+		usage.ErrorCode = s.errorMapper.MapError(usage.ErrorMsg).ErrorCode
+	}
+
+	if err := s.usageRepo.CreateUsage(ctx, usage); err != nil {
+		return err
+	}
+
+	// The record is what matters; the nightly sync repairs a missed lastActiveAt
+	if err := s.userRepo.TouchLastActive(ctx, usage.UserID, usage.CreatedAt); err != nil {
+		log.Printf("Failed to update last activity of user %s: %v", usage.UserID, err)
+	}
+	return nil
+}
+
+func (s *usageService) SyncUserActivity(ctx context.Context) error {
+	if err := s.rollupRepo.SyncLastActive(ctx); err != nil {
+		return err
+	}
+	return s.rollupRepo.SyncTotalCalls(ctx)
 }
 
 func (s *usageService) GetGlobalStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UsageStats, error) {
-	return s.usageRepo.GetGlobalStats(ctx, startDate, endDate)
+	plan, err := s.planRollupRead(ctx, startDate, endDate)
+	if err != nil || plan == nil {
+		if err != nil {
+			log.Printf("Falling back to raw usage stats: %v", err)
+		}
+		return s.usageRepo.GetGlobalStats(ctx, startDate, endDate)
+	}
+
+	parts, err := s.rollupRepo.GetGlobalStats(ctx, plan.rollupFrom, plan.rollupTo)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range plan.rawRanges {
+		rawStats, err := s.usageRepo.GetGlobalStats(ctx, raw.start, raw.end)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rawStats...)
+	}
+
+	return mergeUsageStats(parts), nil
 }
 
 func (s *usageService) GetUserStats(ctx context.Context, startDate, endDate *time.Time) ([]models.UserUsageStats, error) {
-	return s.usageRepo.GetUserStats(ctx, startDate, endDate)
+	plan, err := s.planRollupRead(ctx, startDate, endDate)
+	if err != nil || plan == nil {
+		if err != nil {
+			log.Printf("Falling back to raw user usage stats: %v", err)
+		}
+		return s.usageRepo.GetUserStats(ctx, startDate, endDate)
+	}
+
+	parts, err := s.rollupRepo.GetUserStats(ctx, plan.rollupFrom, plan.rollupTo)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range plan.rawRanges {
+		rawStats, err := s.usageRepo.GetUserStats(ctx, raw.start, raw.end)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rawStats...)
+	}
+
+	return mergeUserUsageStats(parts), nil
+}
+
+// GetServicePerformance returns latency percentiles per service, with failures broken
+// down by the ErrorCode APIErrorMapper assigns
+func (s *usageService) GetServicePerformance(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServicePerformanceStats, error) {
+	stats, err := s.usageRepo.GetServicePerformance(ctx, serviceName, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.usageRepo.GetErrorGroups(ctx, serviceName, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records tracked before codes were stored are mapped from their message here
+	counts := make(map[string]map[string]int)
+	for _, group := range groups {
+		code := group.ErrorCode
+		if code == "" {
+			code = s.errorMapper.MapError(group.ErrorMsg).ErrorCode
+		}
+		if counts[group.ServiceName] == nil {
+			counts[group.ServiceName] = make(map[string]int)
+		}
+		counts[group.ServiceName][code] += group.Count
+	}
+
+	for i := range stats {
+		errorCounts := make([]models.ErrorCodeCount, 0, len(counts[stats[i].ServiceName]))
+		for code, count := range counts[stats[i].ServiceName] {
+			errorCounts = append(errorCounts, models.ErrorCodeCount{ErrorCode: code, Count: count})
+		}
+		sort.Slice(errorCounts, func(a, b int) bool {
+			if errorCounts[a].Count != errorCounts[b].Count {
+				return errorCounts[a].Count > errorCounts[b].Count
+			}
+			return errorCounts[a].ErrorCode < errorCounts[b].ErrorCode
+		})
+		stats[i].Errors = errorCounts
+	}
+
+	if stats == nil {
+		stats = []models.ServicePerformanceStats{}
+	}
+	return stats, nil
+}
+
+func (s *usageService) GetUsageSummary(ctx context.Context, filter *models.UsageFilter) (*models.UsageSummary, error) {
+	stats, err := s.usageRepo.GetFilteredStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.UsageSummary{Services: stats}
+	if summary.Services == nil {
+		summary.Services = []models.UsageStats{}
+	}
+	for _, stat := range stats {
+		summary.TotalCalls += stat.TotalCalls
+		summary.SuccessCalls += stat.SuccessCalls
+		summary.FailedCalls += stat.FailedCalls
+		summary.TotalCredits += stat.TotalCredits
+	}
+	return summary, nil
+}
+
+func (s *usageService) GetUsageHistory(ctx context.Context, filter *models.UsageFilter, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, pagination.Info{}, apperrors.NewValidationError(err)
+	}
+
+	usage, err := s.usageRepo.GetFilteredHistory(ctx, filter, page)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	usage, info := pagination.Page(usage, page, func(u models.ServiceUsage) pagination.Cursor {
+		return pagination.Cursor{ID: u.ID, Time: &u.CreatedAt}
+	})
+	return usage, info, nil
+}
+
+func (s *usageService) ExportUsage(ctx context.Context, filter *models.UsageFilter, fn func(*models.ServiceUsage) error) error {
+	if err := filter.Validate(); err != nil {
+		return apperrors.NewValidationError(err)
+	}
+	return s.usageRepo.StreamFiltered(ctx, filter, fn)
+}
+
+// Default look-back and maximum span per time-series interval, keeping the bucket count chartable
+var timeSeriesSpans = map[string]struct{ defaultSpan, maxSpan time.Duration }{
+	models.UsageIntervalHour: {48 * time.Hour, 31 * 24 * time.Hour},
+	models.UsageIntervalDay:  {30 * 24 * time.Hour, 366 * 24 * time.Hour},
+	models.UsageIntervalWeek: {26 * 7 * 24 * time.Hour, 3 * 366 * 24 * time.Hour},
+}
+
+func (s *usageService) GetTimeSeries(ctx context.Context, filter *models.UsageTimeSeriesFilter) ([]models.UsageTimeSeriesPoint, error) {
+	if filter.Interval == "" {
+		filter.Interval = models.UsageIntervalDay
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	spans := timeSeriesSpans[filter.Interval]
+	end := time.Now()
+	if filter.EndDate != nil {
+		end = *filter.EndDate
+	}
+	if filter.StartDate == nil {
+		start := end.Add(-spans.defaultSpan)
+		filter.StartDate = &start
+	}
+	if end.Sub(*filter.StartDate) > spans.maxSpan {
+		return nil, apperrors.NewAppError(
+			apperrors.ErrValidation,
+			400,
+			"validation failed",
+			fmt.Sprintf("date range is too long for %s buckets (max %d days)", filter.Interval, int(spans.maxSpan.Hours()/24)),
+		)
+	}
+
+	points, err := s.usageRepo.GetTimeSeries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []models.UsageTimeSeriesPoint{}
+	}
+	return points, nil
+}
+
+// dateRange is an inclusive range in the form the raw usage queries take
+type dateRange struct {
+	start, end *time.Time
+}
+
+// rollupReadPlan splits a requested range into a middle part answered from the rollups and
+// the ragged ends, plus anything newer than the last compaction, answered from raw usage
+type rollupReadPlan struct {
+	rollupFrom, rollupTo time.Time
+	rawRanges            []dateRange
+}
+
+// planRollupRead returns nil when the rollups cannot answer any whole hour of the range
+func (s *usageService) planRollupRead(ctx context.Context, startDate, endDate *time.Time) (*rollupReadPlan, error) {
+	// Buckets before the newest one are complete; the newest one may still be filling up
+	watermark, err := s.rollupRepo.LatestHourlyBucket(ctx)
+	if err != nil || watermark == nil {
+		return nil, err
+	}
+
+	// The compactor backfills from the oldest record, so an open start is fully covered
+	var from time.Time
+	if startDate != nil {
+		from = startDate.UTC().Truncate(time.Hour)
+		if from.Before(*startDate) {
+			from = from.Add(time.Hour)
+		}
+	}
+
+	to := watermark.UTC()
+	if endDate != nil {
+		// endDate is inclusive, so an end exactly on an hour boundary leaves that instant to the raw tail
+		if end := endDate.UTC().Truncate(time.Hour); end.Before(to) {
+			to = end
+		}
+	}
+
+	if !from.Before(to) {
+		return nil, nil
+	}
+
+	plan := &rollupReadPlan{rollupFrom: from, rollupTo: to}
+	if startDate != nil && startDate.Before(from) {
+		// Mongo dates have millisecond precision, so this keeps the head range exclusive of from
+		headEnd := from.Add(-time.Millisecond)
+		plan.rawRanges = append(plan.rawRanges, dateRange{start: startDate, end: &headEnd})
+	}
+	tailStart := to
+	plan.rawRanges = append(plan.rawRanges, dateRange{start: &tailStart, end: endDate})
+
+	return plan, nil
+}
+
+// mergeUsageStats sums partial per-service stats and orders them like the raw query
+func mergeUsageStats(parts []models.UsageStats) []models.UsageStats {
+	index := make(map[string]int)
+	merged := make([]models.UsageStats, 0, len(parts))
+	for _, part := range parts {
+		i, ok := index[part.ServiceName]
+		if !ok {
+			index[part.ServiceName] = len(merged)
+			merged = append(merged, part)
+			continue
+		}
+		merged[i].TotalCalls += part.TotalCalls
+		merged[i].SuccessCalls += part.SuccessCalls
+		merged[i].FailedCalls += part.FailedCalls
+		merged[i].TotalCredits += part.TotalCredits
+	}
+
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].TotalCalls > merged[b].TotalCalls
+	})
+	return merged
+}
+
+// mergeUserUsageStats sums partial per-user stats and orders them like the raw query
+func mergeUserUsageStats(parts []models.UserUsageStats) []models.UserUsageStats {
+	index := make(map[string]int)
+	merged := make([]models.UserUsageStats, 0, len(parts))
+	for _, part := range parts {
+		i, ok := index[part.UserID]
+		if !ok {
+			index[part.UserID] = len(merged)
+			merged = append(merged, part)
+			continue
+		}
+		if merged[i].Email == "" {
+			merged[i].Email = part.Email
+		}
+		merged[i].TotalCalls += part.TotalCalls
+		merged[i].SuccessCalls += part.SuccessCalls
+		merged[i].FailedCalls += part.FailedCalls
+		merged[i].TotalCredits += part.TotalCredits
+	}
+
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].TotalCalls > merged[b].TotalCalls
+	})
+	return merged
 }
 
 func (s *usageService) GetServiceUserStats(ctx context.Context, serviceName string, startDate, endDate *time.Time) ([]models.ServiceUserStats, error) {
 	return s.usageRepo.GetServiceUserStats(ctx, serviceName, startDate, endDate)
 }
 
-func (s *usageService) GetUserUsageHistory(ctx context.Context, userID string, limit, skip int) ([]models.ServiceUsage, error) {
-	return s.usageRepo.GetUserUsageHistory(ctx, userID, limit, skip)
+func (s *usageService) GetUserUsageHistory(ctx context.Context, userID string, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error) {
+	return s.GetUsageHistory(ctx, &models.UsageFilter{UserID: userID}, page)
 }
 
-func (s *usageService) GetServiceUsageHistory(ctx context.Context, serviceName string, limit, skip int) ([]models.ServiceUsage, error) {
-	return s.usageRepo.GetServiceUsageHistory(ctx, serviceName, limit, skip)
-}
\ No newline at end of file
+func (s *usageService) GetServiceUsageHistory(ctx context.Context, serviceName string, page *pagination.Params) ([]models.ServiceUsage, pagination.Info, error) {
+	return s.GetUsageHistory(ctx, &models.UsageFilter{ServiceName: serviceName}, page)
+}
+
+// maxRollupWindow caps how much raw usage a single aggregation reads; a large backlog is
+// worked off window by window
+const maxRollupWindow = 7 * 24 * time.Hour
+
+func (s *usageService) CompactRollups(ctx context.Context) (time.Time, time.Time, error) {
+	latest, err := s.rollupRepo.LatestHourlyBucket(ctx)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	var from time.Time
+	if latest != nil {
+		// Recompute the previous hour too, so records written late still land in their bucket
+		from = latest.Add(-time.Hour)
+	} else {
+		earliest, err := s.rollupRepo.EarliestUsage(ctx)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if earliest == nil {
+			return time.Time{}, time.Time{}, nil
+		}
+		from = *earliest
+	}
+	from = from.UTC().Truncate(time.Hour)
+	now := time.Now().UTC()
+
+	for start := from; start.Before(now); start = start.Add(maxRollupWindow) {
+		if err := ctx.Err(); err != nil {
+			return from, start, err
+		}
+
+		end := start.Add(maxRollupWindow)
+		if end.After(now) {
+			end = now
+		}
+		if err := s.rollupRepo.CompactHourly(ctx, start, end); err != nil {
+			return from, start, err
+		}
+
+		// Daily buckets are rebuilt from the hourly rollups for every day the window touched
+		dayStart := start.Truncate(24 * time.Hour)
+		dayEnd := end.Truncate(24 * time.Hour).Add(24 * time.Hour)
+		if err := s.rollupRepo.CompactDaily(ctx, dayStart, dayEnd); err != nil {
+			return from, start, err
+		}
+	}
+
+	return from, now, nil
+}
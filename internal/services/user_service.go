@@ -9,6 +9,7 @@ import (
 	"chi-mongo-backend/internal/models"
 	"chi-mongo-backend/internal/repository"
 	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/pagination"
 )
 
 type UserService interface {
@@ -16,10 +17,10 @@ type UserService interface {
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetOrCreateUser(ctx context.Context, email string) (*models.User, error)
 	// Add these new admin methods
-	GetAllUsers(ctx context.Context) (*models.AdminUserListResponse, error)
+	GetAllUsers(ctx context.Context, filter *models.AdminUserFilter, page *pagination.Params) (*models.AdminUserListResponse, error)
 	GetUserByID(ctx context.Context, userID string) (*models.AdminUserDetailResponse, error)
 	GetUserStats(ctx context.Context) (*models.UserStatsResponse, error)
-	GetUserActivity(ctx context.Context, userID string) (*models.UserActivityResponse, error)
+	GetUserActivity(ctx context.Context, userID string, page *pagination.Params) (*models.UserActivityResponse, error)
 	GetUserCredits(ctx context.Context, userID string) (*models.UserCreditsResponse, error)
 }
 
@@ -27,20 +28,22 @@ type userService struct {
 	userRepo     repository.UserRepository
 	creditsRepo  repository.CreditsRepository
 	activityRepo repository.ActivityRepository // Add this
+	activity     ActivityEmitter
 }
 
-func NewUserService(userRepo repository.UserRepository, creditsRepo repository.CreditsRepository, activityRepo repository.ActivityRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, creditsRepo repository.CreditsRepository, activityRepo repository.ActivityRepository, activity ActivityEmitter) UserService {
 	return &userService{
 		userRepo:     userRepo,
 		creditsRepo:  creditsRepo,
 		activityRepo: activityRepo,
+		activity:     activity,
 	}
 }
 
 func (s *userService) RegisterUser(ctx context.Context, req *models.RegisterUserRequest) (*models.RegisterUserResponse, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return nil, apperrors.NewAppError(apperrors.ErrValidation, 400, "validation failed", err.Error())
+		return nil, apperrors.NewValidationError(err)
 	}
 
 	// Check if user already exists
@@ -84,6 +87,12 @@ func (s *userService) RegisterUser(ctx context.Context, req *models.RegisterUser
 		return nil, err
 	}
 
+	s.activity.Emit(ctx, newActivity(user.UserID, models.ActivityUserRegistered, "User registered", "", map[string]interface{}{
+		"email":   user.Email,
+		"credits": initialCredits,
+		"source":  "register",
+	}))
+
 	return &models.RegisterUserResponse{
 		Message: "User registered successfully",
 		User:    *user,
@@ -141,25 +150,56 @@ func (s *userService) GetOrCreateUser(ctx context.Context, email string) (*model
 		return nil, err
 	}
 
+	s.activity.Emit(ctx, newActivity(userID, models.ActivityUserRegistered, "User created on first sign-in", "", map[string]interface{}{
+		"email":   email,
+		"credits": initialCredits,
+		"source":  "auto",
+	}))
+
 	return newUser, nil
 }
 
 // Admin methods
 
-func (s *userService) GetAllUsers(ctx context.Context) (*models.AdminUserListResponse, error) {
-	// Get users with their credits using aggregation
-	adminUsers, err := s.creditsRepo.GetAllWithUsers(ctx)
+func (s *userService) GetAllUsers(ctx context.Context, filter *models.AdminUserFilter, page *pagination.Params) (*models.AdminUserListResponse, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, apperrors.NewValidationError(err)
+	}
+
+	// Get users with their credits and activity using aggregation
+	adminUsers, err := s.userRepo.Search(ctx, filter, page)
 	if err != nil {
 		return nil, err
 	}
 
+	adminUsers, info := pagination.Page(adminUsers, page, func(u models.AdminUser) pagination.Cursor {
+		return adminUserCursor(u, filter.SortBy)
+	})
+
 	return &models.AdminUserListResponse{
-		Message: "Users retrieved successfully",
-		Users:   adminUsers,
-		Total:   len(adminUsers),
+		Message:    "Users retrieved successfully",
+		Users:      adminUsers,
+		Total:      len(adminUsers),
+		Pagination: info,
 	}, nil
 }
 
+// adminUserCursor keys the user list by the field it is sorted on
+func adminUserCursor(user models.AdminUser, sortBy string) pagination.Cursor {
+	cursor := pagination.Cursor{ID: user.ID}
+	switch sortBy {
+	case models.UserSortCredits:
+		value := int64(user.Credits)
+		cursor.Value = &value
+	case models.UserSortUsage:
+		value := int64(user.TotalCalls)
+		cursor.Value = &value
+	default:
+		cursor.Time = &user.CreatedAt
+	}
+	return cursor
+}
+
 func (s *userService) GetUserByID(ctx context.Context, userID string) (*models.AdminUserDetailResponse, error) {
 	// Get user details
 	user, err := s.userRepo.GetByUserID(ctx, userID)
@@ -216,7 +256,7 @@ func (s *userService) GetUserStats(ctx context.Context) (*models.UserStatsRespon
 	}, nil
 }
 
-func (s *userService) GetUserActivity(ctx context.Context, userID string) (*models.UserActivityResponse, error) {
+func (s *userService) GetUserActivity(ctx context.Context, userID string, page *pagination.Params) (*models.UserActivityResponse, error) {
 	// First verify user exists
 	_, err := s.userRepo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -224,15 +264,20 @@ func (s *userService) GetUserActivity(ctx context.Context, userID string) (*mode
 	}
 
 	// Get user activities
-	activities, err := s.activityRepo.GetByUserID(ctx, userID)
+	activities, err := s.activityRepo.GetByUserID(ctx, userID, page)
 	if err != nil {
 		return nil, err
 	}
 
+	activities, info := pagination.Page(activities, page, func(a models.ActivityLog) pagination.Cursor {
+		return pagination.Cursor{ID: a.ID, Time: &a.Timestamp}
+	})
+
 	return &models.UserActivityResponse{
 		Message:    "User activity retrieved successfully",
 		UserID:     userID,
 		Activities: activities,
+		Pagination: info,
 	}, nil
 }
 
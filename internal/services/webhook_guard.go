@@ -0,0 +1,111 @@
+// internal/services/webhook_guard.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errWebhookDestination rejects a webhook that points at a host the server must not reach
+var errWebhookDestination = errors.New("webhook destination is not allowed")
+
+// blockedWebhookPrefixes are ranges outside the IsPrivate/IsLoopback/IsLinkLocal checks that
+// still reach internal infrastructure
+var blockedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can embed any IPv4 address
+}
+
+// allowedWebhookIP reports whether a webhook may be delivered to ip: only public unicast
+// addresses pass, so loopback, RFC 1918, link-local (including cloud metadata endpoints)
+// and similar ranges are refused
+func allowedWebhookIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedWebhookPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newWebhookClient returns a client that only connects to public addresses. The check runs
+// on the address actually dialed, after DNS resolution, so a hostname cannot be re-pointed
+// at an internal address between validation and delivery. Redirects are not followed and
+// proxies are bypassed, since either would connect somewhere other than the checked address.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !allowedWebhookIP(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errWebhookDestination, address)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkWebhookURL rejects webhook URLs that are not http(s) or whose host resolves to an
+// address newWebhookClient would refuse, so users learn about it when saving settings
+func checkWebhookURL(ctx context.Context, resolver *net.Resolver, webhookURL string, allowPrivate bool) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: not an http or https URL", errWebhookDestination)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !allowedWebhookIP(ip) {
+			return fmt.Errorf("%w: %s is not a public address", errWebhookDestination, host)
+		}
+		return nil
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: %s does not resolve", errWebhookDestination, host)
+	}
+	for _, addr := range addrs {
+		if !allowedWebhookIP(addr) {
+			return fmt.Errorf("%w: %s resolves to %s, which is not a public address", errWebhookDestination, host, addr.Unmap())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// pkg/blobstore/blobstore.go
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no object is stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque objects under slash-separated keys such as "qr-masking/<id>.json".
+// Implementations must be safe for concurrent use.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get fails with ErrNotFound when the key holds no object
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete succeeds when the key already holds no object
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey rejects keys that could escape the store's root on disk or in the bucket
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("/-_.", c):
+		default:
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// pkg/blobstore/local_test.go
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "results"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "qr-masking/a.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Put(ctx, "qr-masking/a.json", []byte("first"), "application/json"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, "qr-masking/a.json", []byte("second"), "application/json"); err != nil {
+		t.Fatalf("Put over an existing object: %v", err)
+	}
+	data, err := store.Get(ctx, "qr-masking/a.json")
+	if err != nil || string(data) != "second" {
+		t.Fatalf("Get = %q, %v; want the latest object", data, err)
+	}
+
+	// The write-aside file must not be left next to the object
+	entries, err := os.ReadDir(filepath.Join(dir, "results", "qr-masking"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the object", len(entries))
+	}
+
+	if err := store.Delete(ctx, "qr-masking/a.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "qr-masking/a.json"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+	if _, err := store.Get(ctx, "qr-masking/a.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "results"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	for _, key := range []string{"", "/abs.json", "dir/", "../escape.json", "a/../../escape.json", "a//b.json", "a/./b.json", "a b.json"} {
+		if err := store.Put(context.Background(), key, []byte("x"), ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("an object was written outside the store: %v", err)
+	}
+}
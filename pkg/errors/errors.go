@@ -4,6 +4,7 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 )
 
@@ -20,6 +21,7 @@ const (
 	ErrConflict            = "CONFLICT"
 	ErrInternalServer      = "INTERNAL_SERVER_ERROR"
 	ErrBadRequest          = "BAD_REQUEST"
+	ErrPayloadTooLarge     = "PAYLOAD_TOO_LARGE"
 )
 
 // AppError represents a custom application error with user-friendly messaging
@@ -33,6 +35,50 @@ type AppError struct {
 	Suggestion       string      `json:"suggestion,omitempty"`
 	ErrorCode        string      `json:"error_code,omitempty"`
 	OriginalResponse interface{} `json:"original_response,omitempty"` // NEW: Store original backend response
+	Fields           FieldErrors `json:"fields,omitempty"`            // Every failing field of a validation error
+}
+
+// FieldError is one failing field of a request. Field is the JSON path, such as
+// "doc_base64" or "items[2].name"; Code is a stable identifier like "required" or "max".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors lists every failing field of a request
+type FieldErrors []FieldError
+
+// Error joins the messages of every failing field
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records a failing field
+func (e *FieldErrors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Has reports whether field already failed
+func (e FieldErrors) Has(field string) bool {
+	for _, fieldErr := range e {
+		if fieldErr.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns the list as an error, or nil when no field failed
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
 }
 
 // Error implements the error interface
@@ -129,6 +175,46 @@ func NewInsufficientCreditsError() *AppError {
 	return NewAppError(ErrInsufficientCredits, 400, "Insufficient credits")
 }
 
+// NewValidationError wraps an error returned by a Validate method. Field errors are listed
+// in the response, so clients see every failing field at once.
+func NewValidationError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	validationErr := NewAppError(ErrValidation, 400, "validation failed", err.Error())
+	validationErr.TechnicalMessage = err.Error()
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		validationErr.Fields = fields
+		validationErr.UserMessage = "Some fields are missing or invalid"
+		validationErr.Suggestion = "Correct the fields listed in fields and try again"
+		validationErr.ErrorCode = "VAL_001"
+	}
+	return validationErr
+}
+
+// NewPayloadTooLargeError reports a request body over the route's limit of limit bytes
+func NewPayloadTooLargeError(limit int64) *AppError {
+	appErr := NewAppError(ErrPayloadTooLarge, 413, "Request body too large", fmt.Sprintf("request body exceeds the limit of %d bytes", limit))
+	appErr.UserMessage = "The uploaded data is too large"
+	appErr.TechnicalMessage = appErr.Details
+	appErr.Suggestion = "Please upload smaller or fewer images"
+	appErr.ErrorCode = "REQ_413"
+	return appErr
+}
+
+// IsPayloadTooLarge reports whether reading a request body failed because it went over the
+// limit set with http.MaxBytesReader, and returns the limit
+func IsPayloadTooLarge(err error) (int64, bool) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return maxBytesErr.Limit, true
+	}
+	return 0, false
+}
+
 // =============================================================================
 // API Error Mapping System
 // =============================================================================
@@ -290,6 +376,86 @@ func NewAPIErrorMapper() *APIErrorMapper {
 				ErrorCode:        "SIG_002",
 			},
 			
+			// Image validation errors, raised before the image is sent for processing
+			"image data is empty": {
+				UserMessage:      "No image was provided",
+				TechnicalMessage: "Image data is empty",
+				Suggestion:       "Please upload an image and try again",
+				ErrorCode:        "IMG_001",
+			},
+			"image is not valid base64": {
+				UserMessage:      "Image data could not be read",
+				TechnicalMessage: "Image is not valid base64",
+				Suggestion:       "Please send the image as standard base64 or upload it as a file",
+				ErrorCode:        "IMG_002",
+			},
+			"unsupported image format": {
+				UserMessage:      "Image format is not supported",
+				TechnicalMessage: "Unsupported image format",
+				Suggestion:       "Please upload a JPEG, PNG or WebP image",
+				ErrorCode:        "IMG_003",
+			},
+			"image data is corrupted": {
+				UserMessage:      "Image could not be opened",
+				TechnicalMessage: "Image data is corrupted",
+				Suggestion:       "The file may be damaged or incomplete. Please upload it again",
+				ErrorCode:        "IMG_004",
+			},
+			"image exceeds the size limit": {
+				UserMessage:      "Image file is too large",
+				TechnicalMessage: "Image exceeds the size limit",
+				Suggestion:       "Please upload a smaller image file (under 10MB)",
+				ErrorCode:        "IMG_005",
+			},
+			"image dimensions exceed the limit": {
+				UserMessage:      "Image resolution is too high",
+				TechnicalMessage: "Image dimensions exceed the limit",
+				Suggestion:       "Please resize the image to a lower resolution and try again",
+				ErrorCode:        "IMG_006",
+			},
+			"image dimensions are below the minimum": {
+				UserMessage:      "Image resolution is too low",
+				TechnicalMessage: "Image dimensions are below the minimum",
+				Suggestion:       "Please upload a higher resolution image",
+				ErrorCode:        "IMG_007",
+			},
+			"pdf documents are not accepted by this service": {
+				UserMessage:      "PDF documents are not supported here",
+				TechnicalMessage: "PDF documents are not accepted by this service",
+				Suggestion:       "Please upload the document as a JPEG, PNG or WebP image",
+				ErrorCode:        "IMG_008",
+			},
+			"pdf document could not be read": {
+				UserMessage:      "PDF document could not be opened",
+				TechnicalMessage: "PDF document could not be read",
+				Suggestion:       "Please check the file is a valid, complete PDF or upload the pages as images",
+				ErrorCode:        "IMG_009",
+			},
+			"pdf document is encrypted": {
+				UserMessage:      "PDF document is password protected",
+				TechnicalMessage: "PDF document is encrypted",
+				Suggestion:       "Please remove the password protection and upload the document again",
+				ErrorCode:        "IMG_010",
+			},
+			"pdf page has no extractable image": {
+				UserMessage:      "No scanned image found on the PDF page",
+				TechnicalMessage: "PDF page has no extractable image",
+				Suggestion:       "Only scanned PDFs are supported. Please upload the page as an image instead",
+				ErrorCode:        "IMG_011",
+			},
+			"invalid page selection": {
+				UserMessage:      "Page selection is not valid",
+				TechnicalMessage: "Invalid page selection",
+				Suggestion:       "Use page numbers and ranges such as 1,3-5, or all",
+				ErrorCode:        "IMG_012",
+			},
+			"too many pages selected": {
+				UserMessage:      "Too many pages selected",
+				TechnicalMessage: "Too many pages selected",
+				Suggestion:       "Please select fewer pages or split the document into several requests",
+				ErrorCode:        "IMG_013",
+			},
+			
 			// Generic/Common errors
 			"processing failed": {
 				UserMessage:      "Processing failed",
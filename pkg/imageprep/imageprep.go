@@ -0,0 +1,268 @@
+// pkg/imageprep/imageprep.go
+package imageprep
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Formats recognised by Sniff
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatPDF  = "pdf"
+)
+
+// Reasons an input is rejected. They are stable strings, so callers can map them to error codes.
+const (
+	ReasonEmpty             = "image data is empty"
+	ReasonInvalidBase64     = "image is not valid base64"
+	ReasonUnsupportedFormat = "unsupported image format"
+	ReasonCorrupt           = "image data is corrupted"
+	ReasonTooManyBytes      = "image exceeds the size limit"
+	ReasonTooLarge          = "image dimensions exceed the limit"
+	ReasonTooSmall          = "image dimensions are below the minimum"
+	ReasonPDFNotAccepted    = "pdf documents are not accepted by this service"
+	ReasonPDFUnreadable     = "pdf document could not be read"
+	ReasonPDFEncrypted      = "pdf document is encrypted"
+	ReasonPDFNoImage        = "pdf page has no extractable image"
+	ReasonPageSelection     = "invalid page selection"
+	ReasonTooManyPages      = "too many pages selected"
+)
+
+// Error rejects an input; Reason is one of the Reason constants
+type Error struct {
+	Reason string
+	Detail string
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Reason + ": " + e.Detail
+	}
+	return e.Reason
+}
+
+func reject(reason, format string, args ...interface{}) *Error {
+	return &Error{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Limits bound what Prepare accepts. Zero values switch the matching check off.
+type Limits struct {
+	MaxBytes     int
+	MaxDimension int // Longest side, in pixels
+	MinDimension int // Shortest side, in pixels
+	MaxPixels    int // Checked before decoding, so oversized images are never held in memory
+	// DownscaleTo shrinks images whose longest side is larger to exactly this size
+	DownscaleTo int
+	JPEGQuality int
+	AllowPDF    bool
+}
+
+// Result is an input that passed every check. Data is the original bytes unless the image
+// had to be rotated or downscaled, in which case it was re-encoded.
+type Result struct {
+	Data       []byte
+	Format     string
+	Width      int
+	Height     int
+	Normalized bool
+}
+
+// ContentType returns the MIME type of the result
+func (r *Result) ContentType() string {
+	if r.Format == FormatPDF {
+		return "application/pdf"
+	}
+	return "image/" + r.Format
+}
+
+// DecodeBase64 decodes standard or URL-safe base64, padded or not. A data URI prefix and
+// line breaks are ignored.
+func DecodeBase64(encoded string) ([]byte, error) {
+	if strings.HasPrefix(encoded, "data:") {
+		if i := strings.Index(encoded, ","); i >= 0 {
+			encoded = encoded[i+1:]
+		}
+	}
+	encoded = strings.Map(func(r rune) rune {
+		switch r {
+		case '\n', '\r', ' ', '\t':
+			return -1
+		}
+		return r
+	}, encoded)
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(encoded); err == nil {
+			return data, nil
+		}
+	}
+	return nil, &Error{Reason: ReasonInvalidBase64}
+}
+
+// Sniff identifies the format from the leading bytes
+func Sniff(data []byte) (string, error) {
+	switch {
+	case len(data) == 0:
+		return "", &Error{Reason: ReasonEmpty}
+	case bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWebP, nil
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return FormatPDF, nil
+	}
+	return "", &Error{Reason: ReasonUnsupportedFormat, Detail: "expected JPEG, PNG, WebP or PDF"}
+}
+
+// Prepare checks an input against the limits, applies JPEG EXIF orientation and downscales
+// oversized images. PDFs are only sniffed and size-checked.
+func Prepare(data []byte, limits Limits) (*Result, error) {
+	if limits.MaxBytes > 0 && len(data) > limits.MaxBytes {
+		return nil, reject(ReasonTooManyBytes, "%d bytes, max %d", len(data), limits.MaxBytes)
+	}
+
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatPDF {
+		if !limits.AllowPDF {
+			return nil, &Error{Reason: ReasonPDFNotAccepted}
+		}
+		return &Result{Data: data, Format: format}, nil
+	}
+
+	// Check the size from the header before the pixels are decoded
+	config, err := decodeConfig(data, format)
+	if err != nil {
+		return nil, reject(ReasonCorrupt, "%v", err)
+	}
+	if err := checkDimensions(config.Width, config.Height, limits); err != nil {
+		return nil, err
+	}
+
+	img, err := decode(data, format)
+	if err != nil {
+		return nil, reject(ReasonCorrupt, "%v", err)
+	}
+
+	result := &Result{Data: data, Format: format, Width: config.Width, Height: config.Height}
+
+	if format == FormatJPEG {
+		if orientation := jpegOrientation(data); orientation > 1 && orientation <= 8 {
+			img = orient(img, orientation)
+			result.Normalized = true
+		}
+	}
+
+	bounds := img.Bounds()
+	if longest := max(bounds.Dx(), bounds.Dy()); limits.DownscaleTo > 0 && longest > limits.DownscaleTo {
+		img = downscale(img, limits.DownscaleTo)
+		result.Normalized = true
+	}
+
+	if result.Normalized {
+		if err := result.encode(img, limits.JPEGQuality); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func checkDimensions(width, height int, limits Limits) error {
+	if limits.MaxDimension > 0 && max(width, height) > limits.MaxDimension {
+		return reject(ReasonTooLarge, "%dx%d, max %d pixels per side", width, height, limits.MaxDimension)
+	}
+	if limits.MaxPixels > 0 && width*height > limits.MaxPixels {
+		return reject(ReasonTooLarge, "%dx%d, max %d pixels", width, height, limits.MaxPixels)
+	}
+	if limits.MinDimension > 0 && min(width, height) < limits.MinDimension {
+		return reject(ReasonTooSmall, "%dx%d, min %d pixels per side", width, height, limits.MinDimension)
+	}
+	return nil
+}
+
+func decodeConfig(data []byte, format string) (image.Config, error) {
+	switch format {
+	case FormatJPEG:
+		return jpeg.DecodeConfig(bytes.NewReader(data))
+	case FormatPNG:
+		return png.DecodeConfig(bytes.NewReader(data))
+	}
+	return webp.DecodeConfig(bytes.NewReader(data))
+}
+
+func decode(data []byte, format string) (image.Image, error) {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Decode(bytes.NewReader(data))
+	case FormatPNG:
+		return png.Decode(bytes.NewReader(data))
+	}
+	return webp.Decode(bytes.NewReader(data))
+}
+
+// encode re-encodes a normalized image. PNGs stay lossless; JPEGs and WebPs become JPEGs,
+// since there is no WebP encoder in Go.
+func (r *Result) encode(img image.Image, quality int) error {
+	var buf bytes.Buffer
+	if r.Format == FormatPNG {
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+	} else {
+		if quality <= 0 {
+			quality = 90
+		}
+		if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+			return err
+		}
+		r.Format = FormatJPEG
+	}
+
+	bounds := img.Bounds()
+	r.Data, r.Width, r.Height = buf.Bytes(), bounds.Dx(), bounds.Dy()
+	return nil
+}
+
+// downscale shrinks img so its longest side is size pixels
+func downscale(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width >= height {
+		width, height = size, max(1, height*size/width)
+	} else {
+		width, height = max(1, width*size/height), size
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// flatten draws transparent images onto white, because JPEG has no alpha channel
+func flatten(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); !ok || o.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,519 @@
+// pkg/imageprep/pdf.go
+package imageprep
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/ccitt"
+)
+
+// PDFs are supported the way scanners produce them: each page draws one scanned image,
+// which is extracted as it is. Nothing is rendered, so a page without an embedded image
+// (text or vector drawings only) cannot be used.
+
+// Page is the image extracted from one page of a PDF, encoded as JPEG or PNG
+type Page struct {
+	Number int // 1-based
+	Data   []byte
+}
+
+// maxPageTreeDepth bounds the page tree walk, which also stops reference cycles
+const maxPageTreeDepth = 32
+
+// maxContentSize bounds the decoded content stream of a page
+const maxContentSize = 16 << 20
+
+var drawOperator = regexp.MustCompile(`/([^\x00\t\n\f\r ()<>\[\]{}/%]+)[\x00\t\n\f\r ]*Do\b`)
+
+// PDF is a parsed document. Its pages are listed once, when it is opened, and the streams
+// of a document decode to at most maxDecodedSize bytes in total however they are used.
+// A PDF is not safe for concurrent use.
+type PDF struct {
+	doc   *pdfDoc
+	pages []pdfDict
+}
+
+// OpenPDF parses a PDF and lists its pages
+func OpenPDF(data []byte) (*PDF, error) {
+	doc, err := loadPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	pages := doc.pages()
+	if doc.budget <= 0 {
+		return nil, decodeBudgetError()
+	}
+	return &PDF{doc: doc, pages: pages}, nil
+}
+
+// PageCount returns the number of pages in the document
+func (p *PDF) PageCount() int {
+	return len(p.pages)
+}
+
+// ExtractPages returns the image of each requested page, in the order asked for. The
+// image dimensions are checked against limits before any pixels are decoded.
+func (p *PDF) ExtractPages(pages []int, limits Limits) ([]Page, error) {
+	extracted := make([]Page, 0, len(pages))
+	for _, number := range pages {
+		if number < 1 || number > len(p.pages) {
+			return nil, reject(ReasonPageSelection, "page %d does not exist, the document has %d", number, len(p.pages))
+		}
+		img, err := p.doc.pageImage(p.pages[number-1], limits)
+		// A page cut short by the budget would otherwise look like one without an image
+		if p.doc.budget <= 0 {
+			return nil, decodeBudgetError()
+		}
+		if err != nil {
+			if prepErr, ok := err.(*Error); ok {
+				detail := fmt.Sprintf("page %d", number)
+				if prepErr.Detail != "" {
+					detail += ": " + prepErr.Detail
+				}
+				return nil, &Error{Reason: prepErr.Reason, Detail: detail}
+			}
+			return nil, reject(ReasonPDFNoImage, "page %d: %v", number, err)
+		}
+		extracted = append(extracted, Page{Number: number, Data: img})
+	}
+	return extracted, nil
+}
+
+func decodeBudgetError() *Error {
+	return reject(ReasonPDFUnreadable, "the document decodes to more than %dMB", maxDecodedSize>>20)
+}
+
+// ParsePageSelection turns a selection such as "1,3-5" into page numbers. An empty
+// selection or "all" selects every page. At most limit pages may be selected.
+func ParsePageSelection(selection string, count, limit int) ([]int, error) {
+	selection = strings.TrimSpace(strings.ToLower(selection))
+	var pages []int
+	if selection == "" || selection == "all" {
+		for i := 1; i <= count; i++ {
+			pages = append(pages, i)
+		}
+	} else {
+		seen := make(map[int]bool)
+		for _, part := range strings.Split(selection, ",") {
+			part = strings.TrimSpace(part)
+			from, to, isRange := strings.Cut(part, "-")
+			first, err1 := strconv.Atoi(strings.TrimSpace(from))
+			last, err2 := first, error(nil)
+			if isRange {
+				last, err2 = strconv.Atoi(strings.TrimSpace(to))
+			}
+			if err1 != nil || err2 != nil || first < 1 || last < first {
+				return nil, reject(ReasonPageSelection, "%q is not a page number or range", part)
+			}
+			if last > count {
+				return nil, reject(ReasonPageSelection, "page %d does not exist, the document has %d", last, count)
+			}
+			for page := first; page <= last; page++ {
+				if !seen[page] {
+					seen[page] = true
+					pages = append(pages, page)
+				}
+				if limit > 0 && len(pages) > limit {
+					break
+				}
+			}
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, reject(ReasonPageSelection, "the document has no pages")
+	}
+	if limit > 0 && len(pages) > limit {
+		return nil, reject(ReasonTooManyPages, "%d pages selected, max %d", len(pages), limit)
+	}
+	return pages, nil
+}
+
+func loadPDF(data []byte) (*pdfDoc, error) {
+	if format, err := Sniff(data); err != nil || format != FormatPDF {
+		return nil, &Error{Reason: ReasonPDFUnreadable, Detail: "not a pdf document"}
+	}
+	doc, err := openPDF(data)
+	if err != nil {
+		return nil, &Error{Reason: ReasonPDFUnreadable}
+	}
+	if doc.trailer["Encrypt"] != nil {
+		return nil, &Error{Reason: ReasonPDFEncrypted}
+	}
+	if doc.catalog() == nil {
+		return nil, &Error{Reason: ReasonPDFUnreadable, Detail: "no document catalog"}
+	}
+	return doc, nil
+}
+
+// pages lists the page dictionaries in order. Resources are copied down from the page
+// tree, since pages inherit them.
+func (d *pdfDoc) pages() []pdfDict {
+	var pages []pdfDict
+	visited := make(map[int]bool)
+
+	var walk func(node interface{}, resources interface{}, depth int)
+	walk = func(node interface{}, resources interface{}, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict, ok := d.resolve(node).(pdfDict)
+		if !ok || depth > maxPageTreeDepth {
+			return
+		}
+		if own := dict["Resources"]; own != nil {
+			resources = own
+		}
+
+		if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok && dict["Type"] != pdfName("Page") {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+
+		page := pdfDict{}
+		for k, v := range dict {
+			page[k] = v
+		}
+		page["Resources"] = resources
+		pages = append(pages, page)
+	}
+
+	walk(d.catalog()["Pages"], nil, 0)
+	return pages
+}
+
+type pdfImage struct {
+	stream *pdfStream
+	width  int
+	height int
+}
+
+// pageImage extracts the largest image the page draws
+func (d *pdfDoc) pageImage(page pdfDict, limits Limits) ([]byte, error) {
+	images := d.drawnImages(page["Resources"], d.contents(page["Contents"]), 0)
+	if len(images) == 0 {
+		return nil, &Error{Reason: ReasonPDFNoImage}
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].width*images[i].height > images[j].width*images[j].height
+	})
+
+	img := images[0]
+	if err := checkDimensions(img.width, img.height, Limits{MaxDimension: limits.MaxDimension, MaxPixels: limits.MaxPixels}); err != nil {
+		return nil, err
+	}
+	return d.encodeImage(img)
+}
+
+// contents returns the decoded content stream of a page or form
+func (d *pdfDoc) contents(v interface{}) []byte {
+	var streams []*pdfStream
+	switch c := d.resolve(v).(type) {
+	case *pdfStream:
+		streams = []*pdfStream{c}
+	case pdfArray:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(*pdfStream); ok {
+				streams = append(streams, s)
+			}
+		}
+	}
+
+	var out []byte
+	for _, s := range streams {
+		if data, err := d.decodeStream(s, maxContentSize); err == nil {
+			out = append(append(out, data...), '\n')
+		}
+	}
+	return out
+}
+
+// drawnImages returns the image XObjects drawn by content, including those drawn through
+// form XObjects
+func (d *pdfDoc) drawnImages(resources interface{}, content []byte, depth int) []pdfImage {
+	res, _ := d.resolve(resources).(pdfDict)
+	xobjects, _ := d.resolve(res["XObject"]).(pdfDict)
+	if len(xobjects) == 0 || depth > 4 {
+		return nil
+	}
+
+	drawn := make(map[pdfName]bool)
+	for _, m := range drawOperator.FindAllSubmatch(content, -1) {
+		drawn[pdfName(decodeName(m[1]))] = true
+	}
+
+	// Sorted, so the same page always yields the same image
+	names := make([]string, 0, len(xobjects))
+	for name := range xobjects {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var images []pdfImage
+	for _, name := range names {
+		// Without a readable content stream, every image in the resources is a candidate
+		if content != nil && !drawn[pdfName(name)] {
+			continue
+		}
+		stream, ok := d.resolve(xobjects[pdfName(name)]).(*pdfStream)
+		if !ok {
+			continue
+		}
+
+		switch d.resolve(stream.dict["Subtype"]) {
+		case pdfName("Image"):
+			if mask, _ := d.resolve(stream.dict["ImageMask"]).(bool); mask {
+				continue
+			}
+			width, _ := d.resolve(stream.dict["Width"]).(int64)
+			height, _ := d.resolve(stream.dict["Height"]).(int64)
+			if width > 0 && height > 0 {
+				images = append(images, pdfImage{stream: stream, width: int(width), height: int(height)})
+			}
+		case pdfName("Form"):
+			formResources := stream.dict["Resources"]
+			if formResources == nil {
+				formResources = resources
+			}
+			formContent, err := d.decodeStream(stream, maxContentSize)
+			if err != nil {
+				formContent = nil
+			}
+			images = append(images, d.drawnImages(formResources, formContent, depth+1)...)
+		}
+	}
+	return images
+}
+
+// encodeImage returns an image XObject as JPEG, when it is stored as one, or as PNG
+func (d *pdfDoc) encodeImage(img pdfImage) ([]byte, error) {
+	// Raw samples never exceed 16 bits for each of 4 components, plus a predictor byte a row
+	limit := img.width*img.height*8 + img.height
+	data, rest, params, err := d.decodeStreamUntil(img.stream, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded image.Image
+	switch {
+	case len(rest) == 0:
+		decoded, err = d.rasterImage(img, data)
+	case rest[0] == "DCTDecode" || rest[0] == "DCT":
+		return data, nil
+	case rest[0] == "CCITTFaxDecode" || rest[0] == "CCF":
+		decoded, err = d.faxImage(img, data, params[0])
+	default:
+		return nil, fmt.Errorf("images compressed with %s are not supported", rest[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *pdfDoc) faxImage(img pdfImage, data []byte, params pdfDict) (image.Image, error) {
+	subFormat := ccitt.Group3
+	if d.intParam(params, "K", 0) < 0 {
+		subFormat = ccitt.Group4
+	} else if d.intParam(params, "K", 0) > 0 {
+		return nil, fmt.Errorf("mixed 1D/2D fax images are not supported")
+	}
+	align, _ := d.resolve(params["EncodedByteAlign"]).(bool)
+	blackIs1, _ := d.resolve(params["BlackIs1"]).(bool)
+
+	gray := image.NewGray(image.Rect(0, 0, img.width, img.height))
+	if err := ccitt.DecodeIntoGray(gray, bytes.NewReader(data), ccitt.MSB, subFormat, &ccitt.Options{Align: align}); err != nil {
+		return nil, err
+	}
+	if blackIs1 != d.invertedDecode(img.stream) {
+		for i := range gray.Pix {
+			gray.Pix[i] = 255 - gray.Pix[i]
+		}
+	}
+	return gray, nil
+}
+
+// invertedDecode reports a /Decode [1 0] array, which swaps black and white
+func (d *pdfDoc) invertedDecode(s *pdfStream) bool {
+	decode, ok := d.resolve(s.dict["Decode"]).(pdfArray)
+	if !ok || len(decode) < 2 {
+		return false
+	}
+	first, _ := d.resolve(decode[0]).(int64)
+	return first == 1
+}
+
+// colorSpace resolves an image colour space to its component count; a palette is returned
+// for indexed images
+func (d *pdfDoc) colorSpace(v interface{}) (components int, palette []color.Color, err error) {
+	switch cs := d.resolve(v).(type) {
+	case pdfName:
+		switch cs {
+		case "DeviceGray", "CalGray", "G":
+			return 1, nil, nil
+		case "DeviceRGB", "CalRGB", "RGB":
+			return 3, nil, nil
+		case "DeviceCMYK", "CMYK":
+			return 4, nil, nil
+		}
+	case pdfArray:
+		if len(cs) == 0 {
+			break
+		}
+		switch d.resolve(cs[0]) {
+		case pdfName("CalGray"):
+			return 1, nil, nil
+		case pdfName("CalRGB"), pdfName("Lab"):
+			return 3, nil, nil
+		case pdfName("ICCBased"):
+			if len(cs) > 1 {
+				if profile, ok := d.resolve(cs[1]).(*pdfStream); ok {
+					if n := d.intParam(profile.dict, "N", 0); n == 1 || n == 3 || n == 4 {
+						return n, nil, nil
+					}
+				}
+			}
+		case pdfName("Indexed"), pdfName("I"):
+			if len(cs) < 4 {
+				break
+			}
+			base, _, err := d.colorSpace(cs[1])
+			if err != nil {
+				return 0, nil, err
+			}
+			var lookup []byte
+			switch l := d.resolve(cs[3]).(type) {
+			case pdfString:
+				lookup = []byte(l)
+			case *pdfStream:
+				if lookup, err = d.decodeStream(l, 256*4); err != nil {
+					return 0, nil, err
+				}
+			}
+			hival, _ := d.resolve(cs[2]).(int64)
+			for i := 0; i <= int(hival) && (i+1)*base <= len(lookup); i++ {
+				entry := lookup[i*base : (i+1)*base]
+				palette = append(palette, sampleColor(entry, base))
+			}
+			if len(palette) == 0 {
+				break
+			}
+			return 1, palette, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("unsupported colour space")
+}
+
+func sampleColor(sample []byte, components int) color.Color {
+	switch components {
+	case 1:
+		return color.Gray{Y: sample[0]}
+	case 3:
+		return color.RGBA{R: sample[0], G: sample[1], B: sample[2], A: 255}
+	}
+	return color.CMYK{C: sample[0], M: sample[1], Y: sample[2], K: sample[3]}
+}
+
+// rasterImage builds an image from uncompressed samples
+func (d *pdfDoc) rasterImage(img pdfImage, data []byte) (image.Image, error) {
+	components, palette, err := d.colorSpace(img.stream.dict["ColorSpace"])
+	if err != nil {
+		return nil, err
+	}
+	bpc := d.intParam(img.stream.dict, "BitsPerComponent", 8)
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("unsupported bit depth %d", bpc)
+	}
+
+	rowLen := (img.width*components*bpc + 7) / 8
+	if len(data) < rowLen*img.height {
+		return nil, fmt.Errorf("image data is truncated")
+	}
+	invert := d.invertedDecode(img.stream) && palette == nil
+
+	// sample returns component c of pixel x in row, scaled to 8 bits
+	maxValue := 1<<bpc - 1
+	sample := func(row []byte, x, c int) int {
+		i := x*components + c
+		switch bpc {
+		case 8:
+			return int(row[i])
+		case 16:
+			return int(row[2*i])
+		}
+		bit := i * bpc
+		v := int(row[bit/8]>>(8-bpc-bit%8)) & maxValue
+		if palette != nil {
+			return v
+		}
+		return v * 255 / maxValue
+	}
+
+	bounds := image.Rect(0, 0, img.width, img.height)
+	var out image.Image
+	switch {
+	case palette != nil:
+		paletted := image.NewPaletted(bounds, palette)
+		for y := 0; y < img.height; y++ {
+			row := data[y*rowLen:]
+			for x := 0; x < img.width; x++ {
+				paletted.SetColorIndex(x, y, uint8(min(sample(row, x, 0), len(palette)-1)))
+			}
+		}
+		out = paletted
+	case components == 1:
+		gray := image.NewGray(bounds)
+		for y := 0; y < img.height; y++ {
+			row := data[y*rowLen:]
+			for x := 0; x < img.width; x++ {
+				v := sample(row, x, 0)
+				if invert {
+					v = 255 - v
+				}
+				gray.Pix[y*gray.Stride+x] = uint8(v)
+			}
+		}
+		out = gray
+	case components == 3:
+		rgba := image.NewRGBA(bounds)
+		for y := 0; y < img.height; y++ {
+			row := data[y*rowLen:]
+			for x := 0; x < img.width; x++ {
+				i := y*rgba.Stride + x*4
+				rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2], rgba.Pix[i+3] = uint8(sample(row, x, 0)), uint8(sample(row, x, 1)), uint8(sample(row, x, 2)), 255
+			}
+		}
+		out = rgba
+	default:
+		cmyk := image.NewCMYK(bounds)
+		for y := 0; y < img.height; y++ {
+			row := data[y*rowLen:]
+			for x := 0; x < img.width; x++ {
+				i := y*cmyk.Stride + x*4
+				cmyk.Pix[i], cmyk.Pix[i+1], cmyk.Pix[i+2], cmyk.Pix[i+3] = uint8(sample(row, x, 0)), uint8(sample(row, x, 1)), uint8(sample(row, x, 2)), uint8(sample(row, x, 3))
+			}
+		}
+		out = cmyk
+	}
+	return out, nil
+}
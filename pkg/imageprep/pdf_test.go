@@ -0,0 +1,236 @@
+// pkg/imageprep/pdf_test.go
+package imageprep
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+var testLimits = Limits{MaxDimension: 4096, MaxPixels: 1 << 24}
+
+// pdfObject is one indirect object of a test document; data follows "N 0 obj"
+type pdfObject struct {
+	num  int
+	data string
+}
+
+// buildPDF writes objects in order with a trailer pointing at object 1. There is no
+// cross-reference table, since the reader finds objects by scanning.
+func buildPDF(objects ...pdfObject) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for _, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", obj.num, obj.data)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func streamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		t.Fatalf("zlib: %v", err)
+	}
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// imageObject is a 2x2 grey image XObject
+func imageObject() string {
+	return streamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8", []byte{0, 255, 255, 0})
+}
+
+const pageContent = "q 2 0 0 2 0 0 cm /Im0 Do Q"
+
+// simplePDF has pages pages, each drawing the same image
+func simplePDF(pages int) []byte {
+	var kids []string
+	objects := []pdfObject{{num: 1, data: "<< /Type /Catalog /Pages 2 0 R >>"}}
+	for i := 0; i < pages; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 10+i))
+		objects = append(objects, pdfObject{num: 10 + i, data: "<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"})
+	}
+	objects = append(objects,
+		pdfObject{num: 2, data: fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /XObject << /Im0 5 0 R >> >> >>", strings.Join(kids, " "), pages)},
+		pdfObject{num: 4, data: streamObject("", []byte(pageContent))},
+		pdfObject{num: 5, data: imageObject()},
+	)
+	return buildPDF(objects...)
+}
+
+// objectStreamPDF packs the catalog, page tree and page into an object stream, the way
+// PDF 1.5 writers do. extra is appended as further objects.
+func objectStreamPDF(t testing.TB, extra ...pdfObject) []byte {
+	packed := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im0 5 0 R >> >> >>",
+	}
+	var header, body strings.Builder
+	for i, obj := range packed {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	data := header.String() + body.String()
+
+	objects := []pdfObject{
+		{num: 20, data: streamObject(
+			fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(packed), header.Len()),
+			deflate(t, []byte(data)),
+		)},
+		{num: 4, data: streamObject("", []byte(pageContent))},
+		{num: 5, data: imageObject()},
+	}
+	return buildPDF(append(objects, extra...)...)
+}
+
+func TestExtractPages(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		pages int
+	}{
+		{name: "page tree", data: simplePDF(3), pages: 3},
+		{name: "object stream", data: objectStreamPDF(t), pages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := OpenPDF(tt.data)
+			if err != nil {
+				t.Fatalf("OpenPDF: %v", err)
+			}
+			if pdf.PageCount() != tt.pages {
+				t.Fatalf("PageCount = %d, want %d", pdf.PageCount(), tt.pages)
+			}
+
+			pages, err := pdf.ExtractPages([]int{tt.pages}, testLimits)
+			if err != nil {
+				t.Fatalf("ExtractPages: %v", err)
+			}
+			if len(pages) != 1 || pages[0].Number != tt.pages {
+				t.Fatalf("extracted %+v, want page %d", pages, tt.pages)
+			}
+			img, err := png.Decode(bytes.NewReader(pages[0].Data))
+			if err != nil {
+				t.Fatalf("page is not a PNG: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != 2 || size.Y != 2 {
+				t.Errorf("page image is %v, want 2x2", size)
+			}
+
+			if _, err := pdf.ExtractPages([]int{tt.pages + 1}, testLimits); !hasReason(err, ReasonPageSelection) {
+				t.Errorf("ExtractPages of a missing page = %v, want %q", err, ReasonPageSelection)
+			}
+		})
+	}
+}
+
+// TestExtractPagesDecodeBudget draws one small compressed stream many times, which decodes
+// to more than the document may
+func TestExtractPagesDecodeBudget(t *testing.T) {
+	bomb := deflate(t, make([]byte, maxContentSize))
+	repeats := maxDecodedSize/maxContentSize + 1
+	contents := strings.TrimSpace(strings.Repeat("4 0 R ", repeats))
+
+	data := buildPDF(
+		pdfObject{num: 1, data: "<< /Type /Catalog /Pages 2 0 R >>"},
+		pdfObject{num: 2, data: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"},
+		pdfObject{num: 3, data: fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents [%s] /Resources << /XObject << /Im0 5 0 R >> >> >>", contents)},
+		pdfObject{num: 4, data: streamObject("/Filter /FlateDecode", bomb)},
+		pdfObject{num: 5, data: imageObject()},
+	)
+
+	pdf, err := OpenPDF(data)
+	if err != nil {
+		t.Fatalf("OpenPDF: %v", err)
+	}
+	_, err = pdf.ExtractPages([]int{1}, testLimits)
+	if !hasReason(err, ReasonPDFUnreadable) || !strings.Contains(err.Error(), "decodes to more than") {
+		t.Fatalf("ExtractPages = %v, want the decode budget error", err)
+	}
+}
+
+// TestObjectStreamsUnpackedLazily checks that an object stream holding nothing the pages
+// need is never decoded
+func TestObjectStreamsUnpackedLazily(t *testing.T) {
+	unused := deflate(t, append([]byte("30 0 "), make([]byte, 32<<20)...))
+	data := objectStreamPDF(t, pdfObject{
+		num:  21,
+		data: streamObject("/Type /ObjStm /N 1 /First 5 /Filter /FlateDecode", unused),
+	})
+
+	pdf, err := OpenPDF(data)
+	if err != nil {
+		t.Fatalf("OpenPDF: %v", err)
+	}
+	if _, err := pdf.ExtractPages([]int{1}, testLimits); err != nil {
+		t.Fatalf("ExtractPages: %v", err)
+	}
+	if decoded := maxDecodedSize - pdf.doc.budget; decoded > 1<<20 {
+		t.Errorf("decoded %d bytes, want the unused object stream left alone", decoded)
+	}
+}
+
+func TestOpenPDFRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		reason string
+	}{
+		{name: "not a pdf", data: []byte("GIF89a"), reason: ReasonPDFUnreadable},
+		{name: "no objects", data: []byte("%PDF-1.7\n%%EOF\n"), reason: ReasonPDFUnreadable},
+		{name: "no catalog", data: buildPDF(pdfObject{num: 1, data: "(not a dictionary)"}), reason: ReasonPDFUnreadable},
+		{
+			name:   "encrypted",
+			data:   bytes.Replace(simplePDF(1), []byte("<< /Root 1 0 R >>"), []byte("<< /Root 1 0 R /Encrypt << /Filter /Standard >> >>"), 1),
+			reason: ReasonPDFEncrypted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenPDF(tt.data); !hasReason(err, tt.reason) {
+				t.Errorf("OpenPDF = %v, want %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+func FuzzExtractPDFPages(f *testing.F) {
+	f.Add(simplePDF(1))
+	f.Add(simplePDF(3))
+	f.Add(objectStreamPDF(f))
+	f.Add(buildPDF(
+		pdfObject{num: 1, data: "<< /Type /Catalog /Pages 1 0 R /Kids [1 0 R] >>"},
+	))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pdf, err := OpenPDF(data)
+		if err != nil {
+			return
+		}
+		var pages []int
+		for i := 1; i <= min(pdf.PageCount(), 3); i++ {
+			pages = append(pages, i)
+		}
+		pdf.ExtractPages(pages, testLimits)
+	})
+}
+
+func hasReason(err error, reason string) bool {
+	var prepErr *Error
+	return errors.As(err, &prepErr) && prepErr.Reason == reason
+}
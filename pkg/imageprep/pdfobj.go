@@ -0,0 +1,775 @@
+// pkg/imageprep/pdfobj.go
+package imageprep
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// A minimal reader for the PDF object syntax: enough to walk the page tree and pull images
+// out of streams. Objects are found by scanning for "N G obj" rather than by trusting the
+// cross-reference table, which scanners and converters often get wrong.
+
+type (
+	pdfName   string
+	pdfString string
+	pdfArray  []interface{}
+	pdfDict   map[pdfName]interface{}
+	pdfRef    struct{ num, gen int }
+	pdfStream struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// maxDecodedSize bounds everything decoded from one document, across all of its streams,
+// so a small PDF cannot inflate without limit
+const maxDecodedSize = 256 << 20
+
+var (
+	errPDFSyntax      = errors.New("malformed pdf")
+	errStreamTooLarge = errors.New("stream too large")
+	// errDecodeBudget is returned once a document has decoded maxDecodedSize bytes
+	errDecodeBudget = errors.New("pdf decodes to too much data")
+)
+
+var objectHeader = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj`)
+
+type pdfDoc struct {
+	data    []byte
+	offsets map[int]int
+	objects map[int]interface{}
+	trailer pdfDict
+	// loading guards against reference cycles while an object is being parsed
+	loading map[int]bool
+	// budget is how many more bytes the document's streams may decode to
+	budget int
+	// objectStreams lists the object streams not yet unpacked; nil until the first object
+	// that is not stored directly is looked up
+	objectStreams []int
+	unpacking     bool
+}
+
+func openPDF(data []byte) (*pdfDoc, error) {
+	doc := &pdfDoc{
+		data:    data,
+		offsets: make(map[int]int),
+		objects: make(map[int]interface{}),
+		loading: make(map[int]bool),
+		budget:  maxDecodedSize,
+	}
+
+	// Later definitions win, as in an incrementally updated file
+	for _, m := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] > 0 && isDigit(data[m[0]-1]) {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		doc.offsets[num] = m[0]
+	}
+	if len(doc.offsets) == 0 {
+		return nil, errPDFSyntax
+	}
+
+	doc.trailer = doc.findTrailer()
+	return doc, nil
+}
+
+// packedObject looks num up in the object streams (PDF 1.5+). Streams are unpacked one at
+// a time until the object turns up, so those holding only objects that are never needed
+// are never decoded.
+func (d *pdfDoc) packedObject(num int) (interface{}, bool) {
+	if d.unpacking {
+		return nil, false
+	}
+	d.unpacking = true
+	defer func() { d.unpacking = false }()
+
+	if d.objectStreams == nil {
+		d.objectStreams = []int{}
+		for streamNum := range d.offsets {
+			if stream, ok := d.object(streamNum).(*pdfStream); ok && stream.dict["Type"] == pdfName("ObjStm") {
+				d.objectStreams = append(d.objectStreams, streamNum)
+			}
+		}
+		// In file order, so the same document always resolves the same way
+		sort.Slice(d.objectStreams, func(i, j int) bool {
+			return d.offsets[d.objectStreams[i]] < d.offsets[d.objectStreams[j]]
+		})
+	}
+
+	for len(d.objectStreams) > 0 {
+		streamNum := d.objectStreams[0]
+		d.objectStreams = d.objectStreams[1:]
+		if stream, ok := d.object(streamNum).(*pdfStream); ok {
+			d.unpackObjectStream(stream)
+		}
+		if obj, ok := d.objects[num]; ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// unpackObjectStream stores the objects of an object stream that are not defined directly
+func (d *pdfDoc) unpackObjectStream(stream *pdfStream) {
+	data, err := d.decodeStream(stream, d.budget)
+	if err != nil {
+		return
+	}
+
+	count, _ := d.resolve(stream.dict["N"]).(int64)
+	first, _ := d.resolve(stream.dict["First"]).(int64)
+	if first <= 0 || int(first) > len(data) {
+		return
+	}
+	header := &pdfParser{data: data[:first]}
+	for i := int64(0); i < count; i++ {
+		objNum, err1 := header.parseObject()
+		offset, err2 := header.parseObject()
+		n, ok1 := objNum.(int64)
+		o, ok2 := offset.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 || o < 0 {
+			break
+		}
+		if _, direct := d.offsets[int(n)]; direct {
+			continue
+		}
+		if _, seen := d.objects[int(n)]; seen {
+			continue
+		}
+		p := &pdfParser{data: data, pos: int(first + o)}
+		if obj, err := p.parseObject(); err == nil {
+			d.objects[int(n)] = obj
+		}
+	}
+}
+
+// findTrailer returns the last trailer dictionary, or the newest cross-reference stream's
+func (d *pdfDoc) findTrailer() pdfDict {
+	if i := bytes.LastIndex(d.data, []byte("trailer")); i >= 0 {
+		p := &pdfParser{data: d.data, pos: i + len("trailer")}
+		if obj, err := p.parseObject(); err == nil {
+			if dict, ok := obj.(pdfDict); ok && dict["Root"] != nil {
+				return dict
+			}
+		}
+	}
+
+	var trailer pdfDict
+	newest := -1
+	for num, offset := range d.offsets {
+		if stream, ok := d.object(num).(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") && offset > newest {
+			trailer, newest = stream.dict, offset
+		}
+	}
+	return trailer
+}
+
+// catalog returns the document catalog, looking for it by type when the trailer is unusable
+func (d *pdfDoc) catalog() pdfDict {
+	if root, ok := d.resolve(d.trailer["Root"]).(pdfDict); ok {
+		return root
+	}
+	for num := range d.offsets {
+		if dict, ok := d.object(num).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			return dict
+		}
+	}
+	return nil
+}
+
+func (d *pdfDoc) object(num int) interface{} {
+	if obj, ok := d.objects[num]; ok {
+		return obj
+	}
+	offset, ok := d.offsets[num]
+	if !ok {
+		obj, _ := d.packedObject(num)
+		return obj
+	}
+	if d.loading[num] {
+		return nil
+	}
+
+	d.loading[num] = true
+	obj, err := d.parseIndirect(offset)
+	delete(d.loading, num)
+	if err != nil {
+		obj = nil
+	}
+	d.objects[num] = obj
+	return obj
+}
+
+// resolve follows references; a missing object resolves to nil, as the spec asks
+func (d *pdfDoc) resolve(v interface{}) interface{} {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.object(ref.num)
+	}
+	return nil
+}
+
+func (d *pdfDoc) parseIndirect(offset int) (interface{}, error) {
+	p := &pdfParser{data: d.data, pos: offset}
+	for i := 0; i < 2; i++ {
+		if _, err := p.parseObject(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.keyword("obj") {
+		return nil, errPDFSyntax
+	}
+
+	obj, err := p.parseObject()
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := obj.(pdfDict)
+	if !ok || !p.keyword("stream") {
+		return obj, nil
+	}
+
+	// The stream keyword ends with CRLF or LF
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	if length, ok := d.resolve(dict["Length"]).(int64); ok && length >= 0 && start+int(length) <= len(d.data) {
+		end := start + int(length)
+		after := &pdfParser{data: d.data, pos: end}
+		if after.keyword("endstream") {
+			return &pdfStream{dict: dict, raw: d.data[start:end]}, nil
+		}
+	}
+
+	// Wrong or missing length: the data runs up to endstream
+	end := bytes.Index(d.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, errPDFSyntax
+	}
+	raw := d.data[start : start+end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\r"))
+	return &pdfStream{dict: dict, raw: raw}, nil
+}
+
+// filters returns the stream's filter names and their parameters, in decoding order
+func (d *pdfDoc) filters(s *pdfStream) ([]pdfName, []pdfDict) {
+	var names []pdfName
+	var params []pdfDict
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		names = []pdfName{f}
+	case pdfArray:
+		for _, v := range f {
+			if name, ok := d.resolve(v).(pdfName); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	switch p := d.resolve(s.dict["DecodeParms"]).(type) {
+	case pdfDict:
+		params = []pdfDict{p}
+	case pdfArray:
+		for _, v := range p {
+			dict, _ := d.resolve(v).(pdfDict)
+			params = append(params, dict)
+		}
+	}
+	for len(params) < len(names) {
+		params = append(params, nil)
+	}
+	return names, params
+}
+
+// decodeStream applies every filter of a stream. Image filters cannot be applied here;
+// see decodeStreamUntil.
+func (d *pdfDoc) decodeStream(s *pdfStream, limit int) ([]byte, error) {
+	data, rest, _, err := d.decodeStreamUntil(s, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unsupported filter %s", rest[0])
+	}
+	return data, nil
+}
+
+// decodeStreamUntil applies the general-purpose filters of a stream and stops at the first
+// image filter, which it returns with its parameters for the caller to handle. Whatever it
+// decodes is taken from the document's budget.
+func (d *pdfDoc) decodeStreamUntil(s *pdfStream, limit int) ([]byte, []pdfName, []pdfDict, error) {
+	names, params := d.filters(s)
+	data := s.raw
+	for i, name := range names {
+		if d.budget <= 0 {
+			return nil, nil, nil, errDecodeBudget
+		}
+		// The budget may be tighter than the caller's limit
+		stepLimit := min(limit, d.budget)
+
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data, stepLimit)
+			if err == nil {
+				data, err = d.unpredict(data, params[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		case "RunLengthDecode", "RL":
+			data, err = decodeRunLength(data, stepLimit)
+		default:
+			return data, names[i:], params[i:], nil
+		}
+		if errors.Is(err, errStreamTooLarge) {
+			// The output is dropped, but it was decoded all the same
+			d.budget -= stepLimit
+			if d.budget <= 0 {
+				d.budget = 0
+				return nil, nil, nil, errDecodeBudget
+			}
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(data) > d.budget {
+			d.budget = 0
+			return nil, nil, nil, errDecodeBudget
+		}
+		d.budget -= len(data)
+	}
+	return data, nil, nil, nil
+}
+
+func inflate(data []byte, limit int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, int64(limit)+1))
+	if len(out) > limit {
+		return nil, errStreamTooLarge
+	}
+	// Truncated or badly terminated streams are common; keep what was decoded
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG or TIFF predictor of a Flate stream
+func (d *pdfDoc) unpredict(data []byte, params pdfDict) ([]byte, error) {
+	predictor := d.intParam(params, "Predictor", 1)
+	if predictor <= 1 {
+		return data, nil
+	}
+	colors := d.intParam(params, "Colors", 1)
+	bpc := d.intParam(params, "BitsPerComponent", 8)
+	columns := d.intParam(params, "Columns", 1)
+	if colors <= 0 || bpc <= 0 || columns <= 0 {
+		return nil, errPDFSyntax
+	}
+
+	bpp := max(1, (colors*bpc+7)/8)
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	if predictor == 2 {
+		if bpc != 8 {
+			return nil, errors.New("unsupported TIFF predictor depth")
+		}
+		for row := 0; row+rowLen <= len(data); row += rowLen {
+			for i := row + bpp; i < row+rowLen; i++ {
+				data[i] += data[i-bpp]
+			}
+		}
+		return data, nil
+	}
+
+	// PNG predictors: every row starts with its own filter type
+	out := make([]byte, 0, len(data)/(rowLen+1)*rowLen)
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+rowLen+1 <= len(data); pos += rowLen + 1 {
+		filter, row := data[pos], data[pos+1:pos+1+rowLen]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (d *pdfDoc) intParam(params pdfDict, key pdfName, def int) int {
+	if v, ok := d.resolve(params[key]).(int64); ok {
+		return int(v)
+	}
+	return def
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	if i := bytes.IndexByte(data, '>'); i >= 0 {
+		data = data[:i]
+	}
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	return hex.DecodeString(string(digits))
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+func decodeRunLength(data []byte, limit int) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out, nil
+		case n < 128:
+			if i+n+1 > len(data) {
+				return nil, errPDFSyntax
+			}
+			out = append(out, data[i:i+n+1]...)
+			i += n + 1
+		default:
+			if i >= len(data) {
+				return nil, errPDFSyntax
+			}
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+		if len(out) > limit {
+			return nil, errStreamTooLarge
+		}
+	}
+	return out, nil
+}
+
+// pdfParser reads PDF objects from data starting at pos
+type pdfParser struct {
+	data []byte
+	pos  int
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword consumes word when it comes next as a whole token
+func (p *pdfParser) keyword(word string) bool {
+	p.skipSpace()
+	end := p.pos + len(word)
+	if end > len(p.data) || string(p.data[p.pos:end]) != word {
+		return false
+	}
+	if end < len(p.data) && !isSpace(p.data[end]) && !isDelimiter(p.data[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *pdfParser) regularToken() []byte {
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+func (p *pdfParser) parseObject() (interface{}, error) {
+	return p.parseDepth(0)
+}
+
+func (p *pdfParser) parseDepth(depth int) (interface{}, error) {
+	if depth > 64 {
+		return nil, errPDFSyntax
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.pos += 2
+		dict := pdfDict{}
+		for {
+			p.skipSpace()
+			if p.pos+1 < len(p.data) && p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+				p.pos += 2
+				return dict, nil
+			}
+			key, err := p.parseDepth(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				return nil, errPDFSyntax
+			}
+			value, err := p.parseDepth(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = value
+		}
+
+	case c == '<':
+		end := bytes.IndexByte(p.data[p.pos:], '>')
+		if end < 0 {
+			return nil, errPDFSyntax
+		}
+		decoded, err := decodeASCIIHex(p.data[p.pos+1 : p.pos+end])
+		p.pos += end + 1
+		return pdfString(decoded), err
+
+	case c == '[':
+		p.pos++
+		array := pdfArray{}
+		for {
+			p.skipSpace()
+			if p.pos < len(p.data) && p.data[p.pos] == ']' {
+				p.pos++
+				return array, nil
+			}
+			value, err := p.parseDepth(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+
+	case c == '(':
+		return p.parseLiteralString()
+
+	case c == '/':
+		p.pos++
+		return pdfName(decodeName(p.regularToken())), nil
+
+	case isDigit(c) || c == '-' || c == '+' || c == '.':
+		return p.parseNumberOrRef()
+	}
+
+	token := p.regularToken()
+	switch string(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if len(token) == 0 {
+		p.pos++
+	}
+	return nil, errPDFSyntax
+}
+
+func (p *pdfParser) parseNumberOrRef() (interface{}, error) {
+	token := string(p.regularToken())
+	n, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		f, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, errPDFSyntax
+		}
+		return f, nil
+	}
+
+	// "num gen R" is a reference
+	save := p.pos
+	p.skipSpace()
+	if p.pos < len(p.data) && isDigit(p.data[p.pos]) {
+		gen, err := strconv.Atoi(string(p.regularToken()))
+		if err == nil && n >= 0 && p.keyword("R") {
+			return pdfRef{num: int(n), gen: gen}, nil
+		}
+	}
+	p.pos = save
+	return n, nil
+}
+
+func (p *pdfParser) parseLiteralString() (interface{}, error) {
+	p.pos++ // (
+	var out []byte
+	nesting := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			nesting++
+		case ')':
+			if nesting--; nesting == 0 {
+				return pdfString(out), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, errPDFSyntax
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, errPDFSyntax
+}
+
+// decodeName expands #xx escapes in a name
+func decodeName(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+	out := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if b, err := hex.DecodeString(string(raw[i+1 : i+3])); err == nil {
+				out = append(out, b[0])
+				i += 2
+				continue
+			}
+		}
+		out = append(out, raw[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,193 @@
+// pkg/pagination/pagination.go
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "chi-mongo-backend/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// List endpoints return newest first and seek past the last item of the previous page
+// instead of skipping, so every page costs the same however deep the client goes.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+// Cursor marks the last item of a page. Time or Value holds the sort key when the list is
+// ordered by a timestamp or a number; _id breaks ties between items with the same key.
+type Cursor struct {
+	ID    primitive.ObjectID `json:"id"`
+	Time  *time.Time         `json:"t,omitempty"`
+	Value *int64             `json:"v,omitempty"`
+}
+
+// Params is a page request parsed from the query string
+type Params struct {
+	Limit int
+	After *Cursor
+}
+
+// Info is returned with every page; NextCursor is empty on the last page
+type Info struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// cursorPayload is the wire form of a Cursor; times are kept in milliseconds like Mongo
+type cursorPayload struct {
+	ID    string `json:"i"`
+	Time  *int64 `json:"t,omitempty"`
+	Value *int64 `json:"v,omitempty"`
+}
+
+// ParseParams reads "limit" and "cursor" from the query string. Limits outside 1..MaxLimit
+// are clamped; a cursor that does not decode is rejected.
+func ParseParams(r *http.Request) (*Params, error) {
+	params := &Params{Limit: DefaultLimit}
+
+	if str := r.URL.Query().Get("limit"); str != "" {
+		if limit, err := strconv.Atoi(str); err == nil && limit > 0 {
+			params.Limit = limit
+		}
+	}
+	if params.Limit > MaxLimit {
+		params.Limit = MaxLimit
+	}
+
+	if str := r.URL.Query().Get("cursor"); str != "" {
+		cursor, err := Decode(str)
+		if err != nil {
+			return nil, apperrors.NewAppError(apperrors.ErrValidation, http.StatusBadRequest, "invalid cursor")
+		}
+		params.After = cursor
+	}
+
+	return params, nil
+}
+
+// Encode turns a cursor into an opaque URL-safe string
+func Encode(c Cursor) string {
+	payload := cursorPayload{ID: c.ID.Hex(), Value: c.Value}
+	if c.Time != nil {
+		ms := c.Time.UnixMilli()
+		payload.Time = &ms
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a string produced by Encode
+func Decode(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(payload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &Cursor{ID: id, Value: payload.Value}
+	if payload.Time != nil {
+		t := time.UnixMilli(*payload.Time).UTC()
+		cursor.Time = &t
+	}
+	return cursor, nil
+}
+
+// Filter narrows filter to the items after the cursor in a newest-first list. timeField
+// names the timestamp the list is ordered by, or is empty when it is ordered by _id alone.
+func (p *Params) Filter(filter bson.M, timeField string) bson.M {
+	return p.SeekFilter(filter, timeField, false)
+}
+
+// SeekFilter narrows filter to the items after the cursor in a list ordered by (field, _id)
+// in the given direction. The cursor's Time or Value is compared against field.
+func (p *Params) SeekFilter(filter bson.M, field string, ascending bool) bson.M {
+	if p == nil || p.After == nil {
+		return filter
+	}
+
+	op := "$lt"
+	if ascending {
+		op = "$gt"
+	}
+
+	var key interface{}
+	switch {
+	case p.After.Time != nil:
+		key = *p.After.Time
+	case p.After.Value != nil:
+		key = *p.After.Value
+	}
+
+	var seek bson.M
+	if field == "" || key == nil {
+		seek = bson.M{"_id": bson.M{op: p.After.ID}}
+	} else {
+		seek = bson.M{"$or": []bson.M{
+			{field: bson.M{op: key}},
+			{field: key, "_id": bson.M{op: p.After.ID}},
+		}}
+	}
+
+	if len(filter) == 0 {
+		return seek
+	}
+	return bson.M{"$and": []bson.M{filter, seek}}
+}
+
+// Sort orders newest first by timeField (when set) and then by _id
+func Sort(timeField string) bson.D {
+	return SortBy(timeField, false)
+}
+
+// SortBy orders by field (when set) and then by _id, in the given direction
+func SortBy(field string, ascending bool) bson.D {
+	dir := -1
+	if ascending {
+		dir = 1
+	}
+	if field == "" {
+		return bson.D{{Key: "_id", Value: dir}}
+	}
+	return bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// FindOptions sorts newest first and fetches one extra item to tell whether a next page exists
+func (p *Params) FindOptions(timeField string) *options.FindOptions {
+	return options.Find().
+		SetSort(Sort(timeField)).
+		SetLimit(int64(p.Limit + 1))
+}
+
+// Page trims items fetched with FindOptions to the requested limit and builds the page info.
+// key returns the cursor of an item.
+func Page[T any](items []T, p *Params, key func(T) Cursor) ([]T, Info) {
+	info := Info{Limit: p.Limit}
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) > p.Limit {
+		items = items[:p.Limit]
+		info.HasMore = true
+		info.NextCursor = Encode(key(items[len(items)-1]))
+	}
+	return items, info
+}
@@ -0,0 +1,117 @@
+// pkg/utils/decode.go
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/validation"
+)
+
+type strictJSONKey struct{}
+
+// unknownFieldPrefix starts the error encoding/json returns for a field rejected by
+// DisallowUnknownFields, which has no error type of its own
+const unknownFieldPrefix = "json: unknown field "
+
+var errTrailingData = errors.New("request body must contain a single JSON value")
+
+// WithStrictJSON makes DecodeJSONBody reject fields the destination does not declare
+func WithStrictJSON(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictJSONKey{}, true)
+}
+
+// DecodeJSONBody decodes the request body into dst. A value of the wrong type is reported
+// as a field error with its path, and so is an unknown field on routes that use strict JSON.
+// Those routes also reject anything after the first JSON value.
+func DecodeJSONBody(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	strict, _ := r.Context().Value(strictJSONKey{}).(bool)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if strict {
+		var extra json.RawMessage
+		if err := decoder.Decode(&extra); !errors.Is(err, io.EOF) {
+			if _, ok := apperrors.IsPayloadTooLarge(err); !ok {
+				err = errTrailingData
+			}
+			return decodeError(err)
+		}
+	}
+	return nil
+}
+
+// unknownField returns the field named by an unknown field error
+func unknownField(err error) (string, bool) {
+	if !strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`), true
+}
+
+func decodeError(err error) error {
+	if limit, ok := apperrors.IsPayloadTooLarge(err); ok {
+		return apperrors.NewPayloadTooLargeError(limit)
+	}
+
+	var fields apperrors.FieldErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		fields.Add(typeErr.Field, validation.CodeInvalidType,
+			fmt.Sprintf("%s must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value))
+		return apperrors.NewValidationError(fields)
+	}
+	if field, ok := unknownField(err); ok {
+		fields.Add(field, validation.CodeUnknownField, field+" is not a known field")
+		return apperrors.NewValidationError(fields)
+	}
+
+	detail := err.Error()
+	switch {
+	case errors.Is(err, io.EOF):
+		detail = "request body is empty"
+	case errors.As(err, &syntaxErr):
+		detail = fmt.Sprintf("%s at offset %d", syntaxErr.Error(), syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		detail = "request body must be a JSON object"
+	}
+	appErr := apperrors.NewAppError(apperrors.ErrBadRequest, http.StatusBadRequest, "invalid JSON format", detail)
+	appErr.TechnicalMessage = detail
+	return appErr
+}
+
+// jsonTypeName names the JSON type a Go type decodes from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,85 @@
+// pkg/utils/decode_test.go
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "chi-mongo-backend/pkg/errors"
+	"chi-mongo-backend/pkg/validation"
+)
+
+type decodeTarget struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TestUnknownFieldMessage pins the encoding/json error for an unknown field, which
+// unknownField recognises by its text
+func TestUnknownFieldMessage(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`{"name":"a","extra":1}`))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&decodeTarget{})
+	if err == nil {
+		t.Fatal("Decode accepted an unknown field")
+	}
+
+	field, ok := unknownField(err)
+	if !ok || field != "extra" {
+		t.Fatalf("unknownField(%q) = %q, %v; want \"extra\", true", err, field, ok)
+	}
+	if _, ok := unknownField(errors.New("json: cannot unmarshal string")); ok {
+		t.Error("unknownField matched an unrelated error")
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		strict    bool
+		body      string
+		errType   string
+		fieldCode string
+	}{
+		{name: "object", strict: true, body: `{"name":"a","count":1}`},
+		{name: "trailing whitespace", strict: true, body: "{\"name\":\"a\"}\n \t"},
+		{name: "unknown field", strict: true, body: `{"name":"a","extra":1}`, errType: apperrors.ErrValidation, fieldCode: validation.CodeUnknownField},
+		{name: "wrong type", strict: true, body: `{"count":"1"}`, errType: apperrors.ErrValidation, fieldCode: validation.CodeInvalidType},
+		{name: "second value", strict: true, body: `{"name":"a"}{"name":"b"}`, errType: apperrors.ErrBadRequest},
+		{name: "trailing garbage", strict: true, body: `{"name":"a"} x`, errType: apperrors.ErrBadRequest},
+		{name: "trailing brace", strict: true, body: `{"name":"a"}}`, errType: apperrors.ErrBadRequest},
+		{name: "empty", strict: true, body: ``, errType: apperrors.ErrBadRequest},
+		{name: "unknown field when not strict", body: `{"name":"a","extra":1}`},
+		{name: "second value when not strict", body: `{"name":"a"}{"name":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(tt.body)))
+			if tt.strict {
+				r = r.WithContext(WithStrictJSON(r.Context()))
+			}
+
+			err := DecodeJSONBody(r, &decodeTarget{})
+			if tt.errType == "" {
+				if err != nil {
+					t.Fatalf("DecodeJSONBody = %v, want no error", err)
+				}
+				return
+			}
+
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) || appErr.Type != tt.errType {
+				t.Fatalf("DecodeJSONBody = %v, want a %s error", err, tt.errType)
+			}
+			if tt.fieldCode != "" && (len(appErr.Fields) != 1 || appErr.Fields[0].Code != tt.fieldCode) {
+				t.Errorf("fields = %+v, want one %s field error", appErr.Fields, tt.fieldCode)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ type EnhancedErrorResponse struct {
 	ErrorCode        string      `json:"error_code,omitempty"`
 	RequestID        string      `json:"request_id,omitempty"`
 	OriginalResponse interface{} `json:"original_response,omitempty"` // Include original backend response
+	Fields           apperrors.FieldErrors `json:"fields,omitempty"`  // Every failing field of a validation error
 }
 
 // SendJSONResponse sends a JSON response with proper error handling
@@ -73,6 +74,7 @@ func SendErrorResponse(w http.ResponseWriter, err error) {
 			Suggestion:       appErr.Suggestion,
 			ErrorCode:        appErr.ErrorCode,
 			OriginalResponse: cleanedOriginalResponse,
+			Fields:           appErr.Fields,
 		}
 		
 		// Log the response being sent (with truncated original response for readability)
@@ -158,10 +160,3 @@ func SendUserFriendlyErrorResponseWithOriginal(w http.ResponseWriter, userMessag
 	}
 	SendJSONResponse(w, statusCode, response)
 }
-
-func DecodeJSONBody(r *http.Request, dst interface{}) error {
-	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
-		return apperrors.NewAppError(apperrors.ErrBadRequest, http.StatusBadRequest, "invalid JSON format")
-	}
-	return nil
-}
\ No newline at end of file
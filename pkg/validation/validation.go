@@ -0,0 +1,184 @@
+// pkg/validation/validation.go
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "chi-mongo-backend/pkg/errors"
+)
+
+// Codes of the failures reported by Struct; JSON decoding adds the ones below them
+const (
+	CodeRequired        = "required"
+	CodeRequiredWithout = "required_without"
+	CodeMin             = "min"
+	CodeMax             = "max"
+	CodeEmail           = "email"
+	CodeURL             = "url"
+	CodeAlphanum        = "alphanum"
+	CodeInvalid         = "invalid"
+
+	CodeInvalidType  = "invalid_type"
+	CodeUnknownField = "unknown_field"
+)
+
+// Struct checks v, a pointer to a struct, against the validate tags of its fields and
+// returns every failing field. Fields are named by their json tag, or by their form tag
+// when they are not part of the JSON body, as with uploads. The supported rules are
+// required, required_without=Field, omitempty, min=n, max=n, email, url and alphanum;
+// min and max bound the length of strings and slices and the value of numbers.
+// A field reports only its first failing rule.
+func Struct(v interface{}) apperrors.FieldErrors {
+	var errs apperrors.FieldErrors
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+	checkStruct(value, "", &errs)
+	return errs
+}
+
+func checkStruct(value reflect.Value, prefix string, errs *apperrors.FieldErrors) {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + FieldName(field)
+		fieldValue := value.Field(i)
+
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			if !checkField(value, fieldValue, name, tag, errs) {
+				continue
+			}
+		}
+
+		// Nested request structs are checked with their path, e.g. "alert.threshold"
+		if nested := reflect.Indirect(fieldValue); nested.Kind() == reflect.Struct && nested.Type().PkgPath() != "time" {
+			checkStruct(nested, name+".", errs)
+		}
+	}
+}
+
+// checkField applies the rules of one field and reports whether it passed
+func checkField(parent, value reflect.Value, name, tag string, errs *apperrors.FieldErrors) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "omitempty":
+			if isZero(value) {
+				return true
+			}
+		case "required":
+			if isZero(value) {
+				errs.Add(name, CodeRequired, name+" is required")
+				return false
+			}
+		case "required_without":
+			other, ok := parent.Type().FieldByName(param)
+			if !ok {
+				panic(fmt.Sprintf("validation: required_without names unknown field %q", param))
+			}
+			if isZero(value) && isZero(parent.FieldByIndex(other.Index)) {
+				errs.Add(name, CodeRequiredWithout, fmt.Sprintf("%s or %s is required", name, FieldName(other)))
+				return false
+			}
+		case "min", "max":
+			if !checkBound(value, name, rule, param, errs) {
+				return false
+			}
+		case "email":
+			if address, err := mail.ParseAddress(value.String()); err != nil || address.Address != value.String() {
+				errs.Add(name, CodeEmail, name+" must be a valid email address")
+				return false
+			}
+		case "url":
+			parsed, err := url.Parse(value.String())
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				errs.Add(name, CodeURL, name+" must be a valid http or https URL")
+				return false
+			}
+		case "alphanum":
+			for _, c := range value.String() {
+				if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+					errs.Add(name, CodeAlphanum, name+" may only contain letters and digits")
+					return false
+				}
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", rule, name))
+		}
+	}
+	return true
+}
+
+func checkBound(value reflect.Value, name, rule, param string, errs *apperrors.FieldErrors) bool {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: %s on %s needs a number, got %q", rule, name, param))
+	}
+
+	var actual float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		actual, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	default:
+		panic(fmt.Sprintf("validation: %s does not apply to %s", rule, name))
+	}
+
+	if rule == "min" && actual < bound {
+		if unit == "" {
+			errs.Add(name, CodeMin, fmt.Sprintf("%s must be at least %s", name, param))
+		} else {
+			errs.Add(name, CodeMin, fmt.Sprintf("%s must have at least %s%s", name, param, unit))
+		}
+		return false
+	}
+	if rule == "max" && actual > bound {
+		if unit == "" {
+			errs.Add(name, CodeMax, fmt.Sprintf("%s must be at most %s", name, param))
+		} else {
+			errs.Add(name, CodeMax, fmt.Sprintf("%s must have at most %s%s", name, param, unit))
+		}
+		return false
+	}
+	return true
+}
+
+// isZero treats blank strings as empty, since the requests trim their fields
+func isZero(value reflect.Value) bool {
+	if value.Kind() == reflect.String {
+		return strings.TrimSpace(value.String()) == ""
+	}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Map {
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// FieldName is the name a request field is reported under: its json name, its form name
+// for fields sent only as multipart/form-data, or else its Go name
+func FieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}